@@ -0,0 +1,82 @@
+package main
+
+import (
+	"MessagingSystemBackend/internal/importer"
+	"MessagingSystemBackend/internal/initializers"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Command-line importer for Slack and WhatsApp exports.
+//
+//	go run ./cmd/import -source slack -file export.zip
+//	go run ./cmd/import -source whatsapp -file chat.txt -group "Project X" -tz Europe/Berlin
+//
+// -users names a JSON file mapping people in the export to existing usernames,
+// e.g. {"U024BE7LH": "alice"}; everyone else gets a placeholder account.
+func main() {
+	source := flag.String("source", "", "export type: slack or whatsapp")
+	file := flag.String("file", "", "path to the export file")
+	group := flag.String("group", "", "WhatsApp: group to import a group chat into")
+	dateOrder := flag.String("date-order", "", "WhatsApp: dmy or mdy (detected when empty)")
+	tz := flag.String("tz", "UTC", "WhatsApp: time zone of the export's timestamps")
+	userMap := flag.String("users", "", "JSON file mapping Slack user IDs or WhatsApp names to existing usernames")
+	flag.Parse()
+
+	if *source == "" || *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	location, err := time.LoadLocation(*tz)
+	if err != nil {
+		log.Fatalln("Invalid time zone:", err)
+	}
+
+	var users map[string]string
+	if *userMap != "" {
+		data, err := os.ReadFile(*userMap)
+		if err != nil {
+			log.Fatalln("Could not read user map:", err)
+		}
+		if err := json.Unmarshal(data, &users); err != nil {
+			log.Fatalln("Invalid user map:", err)
+		}
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalln("Could not open export:", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		log.Fatalln("Could not read export:", err)
+	}
+
+	initializers.LoadEnv()
+	initializers.ConnectToDb()
+	initializers.SyncDatabase()
+
+	report, err := importer.Import(f, info.Size(), importer.Options{
+		Source:    *source,
+		FileName:  filepath.Base(*file),
+		GroupName: *group,
+		DateOrder: *dateOrder,
+		Location:  location,
+		Users:     users,
+	})
+
+	out, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(out))
+
+	if err != nil {
+		log.Fatalln("Import failed (run again to resume):", err)
+	}
+}
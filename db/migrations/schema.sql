@@ -3,28 +3,81 @@ CREATE TABLE users (
     id SERIAL PRIMARY KEY,
     username VARCHAR(255) NOT NULL UNIQUE,
     password VARCHAR(255) NOT NULL,
-    created_at TIMESTAMP
+    created_at TIMESTAMP,
+    placeholder BOOLEAN DEFAULT false NOT NULL,
+    group_add_policy VARCHAR(16) DEFAULT 'anyone' NOT NULL,  -- anyone, contacts or invitation
+    broadcast_policy VARCHAR(16) DEFAULT 'anyone' NOT NULL   -- anyone, contacts or nobody
 );
 
+-- COMMUNITIES
+CREATE TABLE communities (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT,
+    created_by INTEGER NOT NULL,
+    created_at TIMESTAMP,
+    CONSTRAINT fk_communities_creator FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_communities_created_by ON communities(created_by);
+
 -- GROUPS
 CREATE TABLE groups (
     id SERIAL PRIMARY KEY,
-    name VARCHAR(255) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
     created_by INTEGER NOT NULL,
     created_at TIMESTAMP,
-    CONSTRAINT fk_groups_creator FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+    community_id INTEGER,                           -- Set for channels of a community
+    private_channel BOOLEAN DEFAULT false NOT NULL, -- Channel holds only the members added to it
+    max_members INTEGER,          -- NULL uses GROUP_MAX_MEMBERS
+    max_admins INTEGER,           -- NULL uses GROUP_MAX_ADMINS
+    edit_window_minutes INTEGER,  -- NULL uses EDIT_WINDOW_MINUTES
+    allow_join_requests BOOLEAN DEFAULT false NOT NULL,
+    posting_policy VARCHAR(16) DEFAULT 'everyone' NOT NULL,  -- everyone, admins or roles
+    posting_roles TEXT,                                      -- Comma-separated roles allowed to post under 'roles'
+    slow_mode_seconds INTEGER DEFAULT 0 NOT NULL,            -- Minimum interval between a member's messages; 0 is off
+    description TEXT,
+    topic TEXT,
+    visibility VARCHAR(16) DEFAULT 'private' NOT NULL,  -- private or public
+    color VARCHAR(7),                                   -- #rrggbb
+    avatar_updated_at TIMESTAMP,                        -- NULL when there is no avatar
+    archived_at TIMESTAMP,                              -- Set while the group is archived (read-only)
+    archived_by INTEGER,
+    delete_requested_by INTEGER,
+    purge_at TIMESTAMP,                                 -- Set once deleted; history is erased after this
+    CONSTRAINT fk_groups_creator FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_groups_community FOREIGN KEY (community_id) REFERENCES communities(id) ON DELETE CASCADE
 );
 
+-- Names are unique among standalone groups, and among the channels of a community
+CREATE UNIQUE INDEX idx_groups_name ON groups(name) WHERE community_id IS NULL;
+CREATE UNIQUE INDEX idx_groups_community_name ON groups(community_id, name) WHERE community_id IS NOT NULL;
+
 -- Index to filter/sort recent groups
 CREATE INDEX idx_groups_created_at ON groups(created_at);
 CREATE INDEX idx_groups_created_by ON groups(created_by);
+CREATE INDEX idx_groups_purge_at ON groups(purge_at);
+
+-- Full-text search over public group profiles in the directory
+ALTER TABLE groups ADD COLUMN profile_tsv tsvector
+    GENERATED ALWAYS AS (to_tsvector('english', name || ' ' || coalesce(description, '') || ' ' || coalesce(topic, ''))) STORED;
+CREATE INDEX idx_groups_profile_tsv ON groups USING GIN (profile_tsv);
+
+-- GROUP AVATARS (kept apart so group queries do not load the image)
+CREATE TABLE group_avatars (
+    group_id INTEGER PRIMARY KEY,
+    content_type VARCHAR(32) NOT NULL,
+    data BYTEA NOT NULL,
+    updated_at TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
 
 -- GROUP MEMBERS
 CREATE TABLE group_members (
     id SERIAL PRIMARY KEY,
     user_id INTEGER NOT NULL,
     group_id INTEGER NOT NULL,
-    is_admin BOOLEAN DEFAULT false NOT NULL,
+    role VARCHAR(16) DEFAULT 'member' NOT NULL,  -- owner, admin, moderator, member, read_only; one owner per group
     joined_at TIMESTAMP,
     CONSTRAINT fk_group_member_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
     CONSTRAINT fk_group_member_group FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
@@ -34,39 +87,372 @@ CREATE TABLE group_members (
 -- Indexes for group membership filtering
 CREATE INDEX idx_group_members_user_id ON group_members(user_id);
 CREATE INDEX idx_group_members_group_id ON group_members(group_id);
-CREATE INDEX idx_group_members_is_admin ON group_members(group_id, is_admin);
+CREATE INDEX idx_group_members_role ON group_members(role);
 CREATE INDEX idx_group_members_joined_at ON group_members(joined_at);
 
--- GROUP MESSAGES
-CREATE TABLE group_messages (
+-- COMMUNITY MEMBERS (roles are copied to the member's channel memberships)
+CREATE TABLE community_members (
     id SERIAL PRIMARY KEY,
-    group_id INTEGER NOT NULL,
-    sender_id INTEGER NOT NULL,
-    content TEXT NOT NULL,
+    community_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    role VARCHAR(16) DEFAULT 'member' NOT NULL,  -- owner, admin or member; one owner per community
+    joined_at TIMESTAMP,
+    CONSTRAINT fk_community_member_community FOREIGN KEY (community_id) REFERENCES communities(id) ON DELETE CASCADE,
+    CONSTRAINT fk_community_member_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (community_id, user_id)
+);
+
+CREATE INDEX idx_community_members_user_id ON community_members(user_id);
+
+-- CONVERSATIONS (one per group, one per set of DM participants)
+CREATE TABLE conversations (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(16) NOT NULL,
+    group_id INTEGER UNIQUE,
+    direct_key TEXT UNIQUE,
     created_at TIMESTAMP,
-    updated_at TIMESTAMP,
-    CONSTRAINT fk_group_msg_group FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
-    CONSTRAINT fk_group_msg_sender FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE
+    CONSTRAINT fk_conversations_group FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_conversations_kind ON conversations(kind);
+
+-- CONVERSATION MEMBERS
+CREATE TABLE conversation_members (
+    id SERIAL PRIMARY KEY,
+    conversation_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    joined_at TIMESTAMP,
+    FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (conversation_id, user_id)
 );
 
--- Indexes for fast retrieval and sorting
-CREATE INDEX idx_group_messages_group_id ON group_messages(group_id);
-CREATE INDEX idx_group_messages_sender_id ON group_messages(sender_id);
-CREATE INDEX idx_group_messages_created_at ON group_messages(created_at);
+CREATE INDEX idx_conversation_members_user_id ON conversation_members(user_id);
 
--- DIRECT MESSAGES
-CREATE TABLE direct_messages (
+-- MESSAGES (direct and group)
+CREATE TABLE messages (
     id SERIAL PRIMARY KEY,
+    conversation_id INTEGER NOT NULL,
     sender_id INTEGER NOT NULL,
-    receiver_id INTEGER NOT NULL,
+    kind VARCHAR(16) DEFAULT 'user' NOT NULL,  -- 'user' or 'system'
     content TEXT NOT NULL,
     created_at TIMESTAMP,
     updated_at TIMESTAMP,
-    CONSTRAINT fk_dm_sender FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE,
-    CONSTRAINT fk_dm_receiver FOREIGN KEY (receiver_id) REFERENCES users(id) ON DELETE CASCADE
+    pinned_at TIMESTAMP,  -- Set while pinned in its group
+    pinned_by INTEGER,
+    event VARCHAR(32),    -- System messages: member_added, role_changed, profile_changed, ...
+    event_data JSONB,     -- System messages: details of the event for clients to render
+    CONSTRAINT fk_messages_conversation FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+    CONSTRAINT fk_messages_sender FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Indexes for chat history and sorting
+CREATE INDEX idx_messages_conversation_created ON messages(conversation_id, created_at);
+CREATE INDEX idx_messages_sender_id ON messages(sender_id);
+CREATE INDEX idx_messages_created_at ON messages(created_at);
+
+-- Full-text search over messages
+ALTER TABLE messages ADD COLUMN content_tsv tsvector
+    GENERATED ALWAYS AS (to_tsvector('english', content)) STORED;
+CREATE INDEX idx_messages_content_tsv ON messages USING GIN (content_tsv);
+
+-- The former group_messages and direct_messages tables are copied into the
+-- tables above on startup and kept as legacy_group_messages and
+-- legacy_direct_messages. The old ID of every copied message is kept below.
+
+-- LEGACY MESSAGE IDS (resolved by PUT /groups/message/:id, PUT /dm/message/:id and GET /groups/:id)
+CREATE TABLE legacy_message_ids (
+    kind VARCHAR(16) NOT NULL,  -- 'group' or 'direct'
+    legacy_id INTEGER NOT NULL,
+    message_id INTEGER NOT NULL,
+    PRIMARY KEY (kind, legacy_id),
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_legacy_message_ids_message_id ON legacy_message_ids(message_id);
+
+-- GROUP MEMBERSHIP HISTORY
+CREATE TABLE group_membership_events (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    actor_id INTEGER NOT NULL,
+    action VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_membership_group_created ON group_membership_events(group_id, created_at);
+CREATE INDEX idx_group_membership_events_user_id ON group_membership_events(user_id);
+
+-- GROUP AUDIT LOG (administrative actions, with values before and after)
+CREATE TABLE group_audit_entries (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL,
+    actor_id INTEGER NOT NULL,
+    action VARCHAR(64) NOT NULL,
+    target_user_id INTEGER,
+    target_message_id INTEGER,  -- No foreign key: deleted messages stay in the log
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_audit_group_created ON group_audit_entries(group_id, created_at);
+CREATE INDEX idx_group_audit_entries_actor_id ON group_audit_entries(actor_id);
+CREATE INDEX idx_group_audit_entries_action ON group_audit_entries(action);
+CREATE INDEX idx_group_audit_entries_target_user_id ON group_audit_entries(target_user_id);
+
+-- GROUP INVITE LINKS
+CREATE TABLE group_invites (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    created_by INTEGER NOT NULL,
+    role VARCHAR(16) DEFAULT 'member' NOT NULL,  -- Role given to people who join
+    max_uses INTEGER,                             -- NULL for unlimited
+    uses INTEGER DEFAULT 0 NOT NULL,
+    expires_at TIMESTAMP,                         -- NULL for never
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_group_invites_group_id ON group_invites(group_id);
+
+-- GROUP INVITE USES (who joined through which link)
+CREATE TABLE group_invite_uses (
+    id SERIAL PRIMARY KEY,
+    invite_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    joined_at TIMESTAMP,
+    FOREIGN KEY (invite_id) REFERENCES group_invites(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_group_invite_uses_invite_id ON group_invite_uses(invite_id);
+
+-- GROUP INVITATIONS (users join only once they accept)
+CREATE TABLE group_invitations (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    invited_by INTEGER NOT NULL,
+    role VARCHAR(16) DEFAULT 'member' NOT NULL,     -- Role given on acceptance
+    status VARCHAR(16) DEFAULT 'pending' NOT NULL,  -- pending, accepted, declined or cancelled
+    created_at TIMESTAMP,
+    responded_at TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (invited_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_group_invitations_group_id ON group_invitations(group_id);
+CREATE INDEX idx_group_invitations_user_id ON group_invitations(user_id);
+-- At most one pending invitation per user and group
+CREATE UNIQUE INDEX idx_group_invitation_pending ON group_invitations(group_id, user_id) WHERE status = 'pending';
+
+-- GROUP JOIN REQUESTS
+CREATE TABLE group_join_requests (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    message TEXT,
+    status VARCHAR(16) DEFAULT 'pending' NOT NULL,  -- pending, approved, rejected or cancelled
+    reviewed_by INTEGER,
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_group_join_requests_group_id ON group_join_requests(group_id);
+CREATE INDEX idx_group_join_requests_user_id ON group_join_requests(user_id);
+-- At most one pending request per user and group
+CREATE UNIQUE INDEX idx_join_request_pending ON group_join_requests(group_id, user_id) WHERE status = 'pending';
+
+-- GROUP MUTES (members who may read but not post until muted_until)
+CREATE TABLE group_mutes (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    muted_by INTEGER NOT NULL,
+    reason TEXT,
+    muted_until TIMESTAMP NOT NULL,  -- Expired rows are ignored and replaced by the next mute
+    created_at TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (group_id, user_id)
+);
+
+-- GROUP BANS (users who cannot be added back until unbanned)
+CREATE TABLE group_bans (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    banned_by INTEGER NOT NULL,
+    reason TEXT,
+    created_at TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (group_id, user_id)
+);
+
+-- OWNERSHIP TRANSFERS
+CREATE TABLE ownership_transfers (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL,
+    from_user_id INTEGER NOT NULL,
+    to_user_id INTEGER NOT NULL,
+    status VARCHAR(16) NOT NULL,  -- pending, accepted, declined, cancelled
+    created_at TIMESTAMP,
+    responded_at TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (to_user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_ownership_transfers_group_id ON ownership_transfers(group_id);
+CREATE INDEX idx_ownership_transfers_to_user_id ON ownership_transfers(to_user_id);
+
+-- GROUP PERMISSION OVERRIDES (per-group changes to a role's default capabilities)
+CREATE TABLE group_permission_overrides (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    capability VARCHAR(32) NOT NULL,  -- post, add_members, remove_members, pin, edit_settings, delete_messages
+    allowed BOOLEAN NOT NULL,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    UNIQUE (group_id, role, capability)
+);
+
+-- BROADCAST LISTS
+CREATE TABLE broadcast_lists (
+    id SERIAL PRIMARY KEY,
+    owner_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP,
+    FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (owner_id, name)
+);
+
+CREATE TABLE broadcast_list_members (
+    id SERIAL PRIMARY KEY,
+    list_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    added_at TIMESTAMP,
+    FOREIGN KEY (list_id) REFERENCES broadcast_lists(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (list_id, user_id)
+);
+
+CREATE INDEX idx_broadcast_list_members_user_id ON broadcast_list_members(user_id);
+
+-- POLLS
+CREATE TABLE polls (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL,
+    message_id INTEGER NOT NULL UNIQUE,
+    created_by INTEGER NOT NULL,
+    question TEXT NOT NULL,
+    multiple_choice BOOLEAN DEFAULT false NOT NULL,
+    anonymous BOOLEAN DEFAULT false NOT NULL,
+    closes_at TIMESTAMP,
+    closed_at TIMESTAMP,
+    closed_by INTEGER,
+    created_at TIMESTAMP,
+    CONSTRAINT fk_poll_group FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    CONSTRAINT fk_poll_message FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+    CONSTRAINT fk_poll_creator FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_polls_group_id ON polls(group_id);
+CREATE INDEX idx_polls_closes_at ON polls(closes_at);
+
+CREATE TABLE poll_options (
+    id SERIAL PRIMARY KEY,
+    poll_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    CONSTRAINT fk_poll_option_poll FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_poll_options_poll_id ON poll_options(poll_id);
+
+CREATE TABLE poll_votes (
+    id SERIAL PRIMARY KEY,
+    poll_id INTEGER NOT NULL,
+    option_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    created_at TIMESTAMP,
+    CONSTRAINT fk_poll_vote_poll FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE,
+    CONSTRAINT fk_poll_vote_option FOREIGN KEY (option_id) REFERENCES poll_options(id) ON DELETE CASCADE,
+    CONSTRAINT fk_poll_vote_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (poll_id, option_id, user_id)
+);
+
+CREATE INDEX idx_poll_votes_poll_id ON poll_votes(poll_id);
+CREATE INDEX idx_poll_votes_user_id ON poll_votes(user_id);
+
+-- POLL VOTE HISTORY
+CREATE TABLE poll_vote_histories (
+    id SERIAL PRIMARY KEY,
+    poll_id INTEGER NOT NULL,
+    option_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP,
+    CONSTRAINT fk_poll_history_poll FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE,
+    CONSTRAINT fk_poll_history_option FOREIGN KEY (option_id) REFERENCES poll_options(id) ON DELETE CASCADE,
+    CONSTRAINT fk_poll_history_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_poll_vote_histories_poll_id ON poll_vote_histories(poll_id);
+CREATE INDEX idx_poll_vote_histories_created_at ON poll_vote_histories(created_at);
+
+-- MESSAGE EDIT HISTORY
+CREATE TABLE message_edits (
+    id SERIAL PRIMARY KEY,
+    message_id INTEGER NOT NULL,
+    editor_id INTEGER NOT NULL,
+    previous_content TEXT NOT NULL,
+    edited_at TIMESTAMP,
+    CONSTRAINT fk_message_edits_message FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+    CONSTRAINT fk_message_edit_editor FOREIGN KEY (editor_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_message_edits_message_id ON message_edits(message_id);
+CREATE INDEX idx_message_edits_edited_at ON message_edits(edited_at);
+
+-- IMPORT JOBS
+CREATE TABLE import_jobs (
+    id SERIAL PRIMARY KEY,
+    source VARCHAR(16) NOT NULL,
+    checksum VARCHAR(64) NOT NULL,
+    file_name TEXT,
+    status VARCHAR(16) NOT NULL,
+    error TEXT,
+    messages_imported INTEGER DEFAULT 0 NOT NULL,
+    messages_skipped INTEGER DEFAULT 0 NOT NULL,
+    started_by INTEGER,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_import_job_file ON import_jobs(source, checksum);
+CREATE INDEX idx_import_jobs_status ON import_jobs(status);
+
+-- IMPORT RECORDS (external entity -> imported row)
+CREATE TABLE import_records (
+    id SERIAL PRIMARY KEY,
+    source VARCHAR(16) NOT NULL,
+    entity_type VARCHAR(32) NOT NULL,
+    external_id TEXT NOT NULL,
+    entity_id INTEGER NOT NULL,
+    job_id INTEGER,
+    created_at TIMESTAMP,
+    UNIQUE (source, entity_type, external_id)
 );
 
--- Indexes for DM queries
-CREATE INDEX idx_direct_messages_sender_id ON direct_messages(sender_id);
-CREATE INDEX idx_direct_messages_receiver_id ON direct_messages(receiver_id);
-CREATE INDEX idx_direct_messages_created_at ON direct_messages(created_at);
+CREATE INDEX idx_import_records_job_id ON import_records(job_id);
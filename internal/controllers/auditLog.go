@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetAuditLog returns the administrative actions taken in a group, newest
+// first. Only the owner and admins can read it.
+// Query params: action, actor_id, target_user_id, since and until (RFC 3339),
+// page and limit.
+func GetAuditLog(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	// Archived groups keep their log readable
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapViewAuditLog, "Only admins can see the audit log", evenIfReadOnly) {
+		return
+	}
+
+	query := initializers.DB.Table("group_audit_entries e").
+		Select("e.id, e.action, e.actor_id, a.username AS actor_username, e.target_user_id, t.username AS target_username, "+
+			"e.target_message_id, e.before, e.after, e.created_at").
+		Joins("LEFT JOIN users a ON a.id = e.actor_id").
+		Joins("LEFT JOIN users t ON t.id = e.target_user_id").
+		Where("e.group_id = ?", group.ID)
+
+	if action := c.Query("action"); action != "" {
+		query = query.Where("e.action = ?", action)
+	}
+	for param, column := range map[string]string{"actor_id": "e.actor_id", "target_user_id": "e.target_user_id"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param})
+			return
+		}
+		query = query.Where(column+" = ?", id)
+	}
+	for param, op := range map[string]string{"since": ">=", "until": "<"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be an RFC 3339 time"})
+			return
+		}
+		query = query.Where("e.created_at "+op+" ?", t)
+	}
+
+	page, limit := parsePagination(c, 50, 200)
+
+	var results []struct {
+		ID              uint
+		Action          string
+		ActorID         uint
+		ActorUsername   string
+		TargetUserID    *uint
+		TargetUsername  *string
+		TargetMessageID *uint
+		Before          *string
+		After           *string
+		CreatedAt       time.Time
+	}
+	// SQL: SELECT e.*, a.username, t.username FROM group_audit_entries e
+	//      LEFT JOIN users a ON a.id = e.actor_id LEFT JOIN users t ON t.id = e.target_user_id
+	//      WHERE e.group_id = ? [AND e.action = ?] [AND e.actor_id = ?] [AND e.target_user_id = ?]
+	//        [AND e.created_at >= ?] [AND e.created_at < ?]
+	//      ORDER BY e.created_at DESC, e.id DESC LIMIT ? OFFSET ?;
+	err := query.Order("e.created_at DESC, e.id DESC").Limit(limit).Offset((page - 1) * limit).Scan(&results).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load the audit log"})
+		return
+	}
+
+	resp := []gin.H{}
+	for _, r := range results {
+		resp = append(resp, gin.H{
+			"id":                r.ID,
+			"action":            r.Action,
+			"actor_id":          r.ActorID,
+			"actor_username":    r.ActorUsername,
+			"target_user_id":    r.TargetUserID,
+			"target_username":   r.TargetUsername,
+			"target_message_id": r.TargetMessageID,
+			"before":            rawJSON(r.Before),
+			"after":             rawJSON(r.After),
+			"created_at":        r.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": resp, "page": page, "limit": limit})
+}
+
+// auditTarget says who or what an audited action applied to.
+type auditTarget struct {
+	UserID    *uint
+	MessageID *uint
+}
+
+// auditUser is the auditTarget of an action on a member.
+func auditUser(userID uint) auditTarget {
+	return auditTarget{UserID: &userID}
+}
+
+// auditMessage is the auditTarget of an action on a message by senderID.
+func auditMessage(messageID uint, senderID uint) auditTarget {
+	return auditTarget{UserID: &senderID, MessageID: &messageID}
+}
+
+// recordAudit appends an administrative action to a group's audit log.
+// before and after are stored as JSON; nil leaves them empty.
+func recordAudit(tx *gorm.DB, groupID uint, actorID uint, action string, target auditTarget, before any, after any) error {
+	entry := models.GroupAuditEntry{
+		GroupID:         groupID,
+		ActorID:         actorID,
+		Action:          action,
+		TargetUserID:    target.UserID,
+		TargetMessageID: target.MessageID,
+		CreatedAt:       time.Now(),
+	}
+	var err error
+	if entry.Before, err = jsonColumn(before); err != nil {
+		return err
+	}
+	if entry.After, err = jsonColumn(after); err != nil {
+		return err
+	}
+	// SQL: INSERT INTO group_audit_entries (group_id, actor_id, action, target_user_id, target_message_id, before, after, created_at)
+	//      VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+	return tx.Create(&entry).Error
+}
+
+// groupAuditFields returns the values of the group columns in updates, as
+// recorded before and after a profile or settings change.
+func groupAuditFields(group models.Group, updates map[string]any) gin.H {
+	all := gin.H{
+		"name":                group.Name,
+		"description":         group.Description,
+		"topic":               group.Topic,
+		"visibility":          group.Visibility,
+		"color":               group.Color,
+		"max_members":         group.MaxMembers,
+		"max_admins":          group.MaxAdmins,
+		"edit_window_minutes": group.EditWindowMinutes,
+		"allow_join_requests": group.AllowJoinRequests,
+		"posting_policy":      group.PostingPolicy,
+		"posting_roles":       group.PostingRoles,
+		"slow_mode_seconds":   group.SlowModeSeconds,
+	}
+	fields := gin.H{}
+	for column := range updates {
+		fields[column] = all[column]
+	}
+	return fields
+}
+
+// jsonColumn encodes a value for a jsonb column, or returns nil for a nil value.
+func jsonColumn(value any) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	s := string(data)
+	return &s, nil
+}
+
+// rawJSON returns stored JSON for embedding in a response as is.
+func rawJSON(s *string) any {
+	if s == nil {
+		return nil
+	}
+	return json.RawMessage(*s)
+}
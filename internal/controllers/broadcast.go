@@ -0,0 +1,355 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Largest number of recipients on one broadcast list
+const maxBroadcastRecipients = 256
+
+var (
+	errBroadcastSelf    = errors.New("you cannot add yourself to a broadcast list")
+	errBroadcastNoUser  = errors.New("user not found")
+	errBroadcastTooMany = fmt.Errorf("a broadcast list can have at most %d members", maxBroadcastRecipients)
+)
+
+// CreateBroadcastList saves a named list of recipients for the current user.
+func CreateBroadcastList(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var body struct {
+		Name    string `json:"name"`
+		UserIDs []uint `json:"user_ids"`
+	}
+	if err := c.Bind(&body); err != nil || strings.TrimSpace(body.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid list name"})
+		return
+	}
+
+	list := models.BroadcastList{OwnerID: user.Id, Name: strings.TrimSpace(body.Name), CreatedAt: time.Now()}
+
+	// SQL: SELECT COUNT(*) FROM broadcast_lists WHERE owner_id = ? AND name = ?;
+	var existing int64
+	initializers.DB.Model(&models.BroadcastList{}).Where("owner_id = ? AND name = ?", user.Id, list.Name).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You already have a list with this name"})
+		return
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// SQL: INSERT INTO broadcast_lists (owner_id, name, created_at) VALUES (?, ?, ?);
+		if err := tx.Create(&list).Error; err != nil {
+			return err
+		}
+		return addBroadcastMembers(tx, list, body.UserIDs)
+	})
+	if err != nil {
+		broadcastMembersError(c, err, "Could not create list")
+		return
+	}
+
+	c.JSON(http.StatusOK, broadcastListResponse(list.ID))
+}
+
+// ListBroadcastLists returns the current user's broadcast lists.
+func ListBroadcastLists(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var results []struct {
+		ID          uint
+		Name        string
+		MemberCount int64
+		CreatedAt   time.Time
+	}
+
+	// SQL: SELECT l.id, l.name, COUNT(m.id), l.created_at FROM broadcast_lists l
+	//      LEFT JOIN broadcast_list_members m ON m.list_id = l.id
+	//      WHERE l.owner_id = ? GROUP BY l.id ORDER BY l.name;
+	initializers.DB.Raw(`
+		SELECT l.id, l.name, COUNT(m.id) AS member_count, l.created_at
+		FROM broadcast_lists l
+		LEFT JOIN broadcast_list_members m ON m.list_id = l.id
+		WHERE l.owner_id = ?
+		GROUP BY l.id
+		ORDER BY l.name
+	`, user.Id).Scan(&results)
+
+	resp := []gin.H{}
+	for _, r := range results {
+		resp = append(resp, gin.H{
+			"id":           r.ID,
+			"name":         r.Name,
+			"member_count": r.MemberCount,
+			"created_at":   r.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetBroadcastList returns a broadcast list with its members.
+func GetBroadcastList(c *gin.Context) {
+	list, ok := findOwnBroadcastList(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, broadcastListResponse(list.ID))
+}
+
+// AddBroadcastMembers adds users to a broadcast list.
+func AddBroadcastMembers(c *gin.Context) {
+	list, ok := findOwnBroadcastList(c)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		UserIDs []uint `json:"user_ids"`
+	}
+	if err := c.Bind(&body); err != nil || len(body.UserIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one user required"})
+		return
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		return addBroadcastMembers(tx, list, body.UserIDs)
+	})
+	if err != nil {
+		broadcastMembersError(c, err, "Could not add members")
+		return
+	}
+
+	c.JSON(http.StatusOK, broadcastListResponse(list.ID))
+}
+
+// RemoveBroadcastMember removes a user from a broadcast list.
+func RemoveBroadcastMember(c *gin.Context) {
+	list, ok := findOwnBroadcastList(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// SQL: DELETE FROM broadcast_list_members WHERE list_id = ? AND user_id = ?;
+	result := initializers.DB.Where("list_id = ? AND user_id = ?", list.ID, userID).Delete(&models.BroadcastListMember{})
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not on this list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User removed from list"})
+}
+
+// DeleteBroadcastList deletes a broadcast list. Messages already sent stay in
+// the recipients' direct conversations.
+func DeleteBroadcastList(c *gin.Context) {
+	list, ok := findOwnBroadcastList(c)
+	if !ok {
+		return
+	}
+
+	// SQL: DELETE FROM broadcast_lists WHERE id = ?;
+	if err := initializers.DB.Delete(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "List deleted"})
+}
+
+// SendBroadcast sends a message privately to every member of a broadcast list.
+// Each recipient gets it in their direct conversation with the sender. All
+// deliveries happen in one transaction; a recipient that cannot receive the
+// message is reported back instead of aborting the others.
+func SendBroadcast(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	list, ok := findOwnBroadcastList(c)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Content string `json:"content"`
+	}
+	if err := c.Bind(&body); err != nil || body.Content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message content"})
+		return
+	}
+
+	// SQL: SELECT * FROM broadcast_list_members WHERE list_id = ? ORDER BY id;
+	var members []models.BroadcastListMember
+	initializers.DB.Preload("User").Where("list_id = ?", list.ID).Order("id").Find(&members)
+	if len(members) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The list has no members"})
+		return
+	}
+
+	sent := []gin.H{}
+	failed := []gin.H{}
+	now := time.Now()
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		for _, member := range members {
+			if reason := broadcastRecipientError(user, member.User); reason != "" {
+				failed = append(failed, gin.H{"user_id": member.UserID, "error": reason})
+				continue
+			}
+
+			// Each delivery runs in a savepoint so one failure only rolls back that recipient
+			var msg models.Message
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				conv, err := models.FindOrCreateDirectConversation(tx, []uint{user.Id, member.UserID})
+				if err != nil {
+					return err
+				}
+				msg = models.Message{ConversationID: conv.ID, SenderID: user.Id, Content: body.Content, CreatedAt: now}
+				// SQL: INSERT INTO messages (conversation_id, sender_id, content, created_at) VALUES (?, ?, ?, ?);
+				return tx.Create(&msg).Error
+			})
+			if err != nil {
+				failed = append(failed, gin.H{"user_id": member.UserID, "error": "Failed to deliver message"})
+				continue
+			}
+			sent = append(sent, gin.H{"user_id": member.UserID, "conversation_id": msg.ConversationID, "message_id": msg.ID})
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send broadcast"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sent":   sent,
+		"failed": failed,
+	})
+}
+
+// broadcastRecipientError returns why a user cannot receive a broadcast from
+// sender, or an empty string if they can.
+func broadcastRecipientError(sender models.User, recipient models.User) string {
+	if recipient.Placeholder {
+		return "User has no account"
+	}
+	switch recipient.BroadcastPolicy {
+	case models.BroadcastNobody:
+		return "User does not accept broadcasts"
+	case models.BroadcastContacts:
+		if !isContact(recipient.Id, sender.Id) {
+			return "User only accepts broadcasts from their contacts"
+		}
+	}
+	return ""
+}
+
+// broadcastMembersError writes the response for an error from
+// addBroadcastMembers.
+func broadcastMembersError(c *gin.Context, err error, failure string) {
+	switch {
+	case errors.Is(err, errBroadcastSelf), errors.Is(err, errBroadcastNoUser), errors.Is(err, errBroadcastTooMany):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": failure})
+	}
+}
+
+// addBroadcastMembers adds existing users to a list, ignoring those already on it.
+func addBroadcastMembers(tx *gorm.DB, list models.BroadcastList, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	for _, id := range userIDs {
+		if id == list.OwnerID {
+			return errBroadcastSelf
+		}
+	}
+
+	// SQL: SELECT COUNT(*) FROM users WHERE id IN (...);
+	unique := strings.Split(models.DirectKey(userIDs), ":")
+	var found int64
+	tx.Model(&models.User{}).Where("id IN ?", unique).Count(&found)
+	if int(found) != len(unique) {
+		return errBroadcastNoUser
+	}
+
+	now := time.Now()
+	for _, id := range userIDs {
+		member := models.BroadcastListMember{ListID: list.ID, UserID: id, AddedAt: now}
+		// SQL: INSERT INTO broadcast_list_members (list_id, user_id, added_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING;
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&member).Error; err != nil {
+			return err
+		}
+	}
+
+	// SQL: SELECT COUNT(*) FROM broadcast_list_members WHERE list_id = ?;
+	var count int64
+	tx.Model(&models.BroadcastListMember{}).Where("list_id = ?", list.ID).Count(&count)
+	if count > maxBroadcastRecipients {
+		return errBroadcastTooMany
+	}
+	return nil
+}
+
+// findOwnBroadcastList loads the list in the :id URL param, writing an error
+// response and returning false unless it belongs to the current user.
+func findOwnBroadcastList(c *gin.Context) (models.BroadcastList, bool) {
+	user := c.MustGet("user").(models.User)
+	var list models.BroadcastList
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid list ID"})
+		return list, false
+	}
+
+	// SQL: SELECT * FROM broadcast_lists WHERE id = ? AND owner_id = ? LIMIT 1;
+	if err := initializers.DB.Where("owner_id = ?", user.Id).First(&list, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return list, false
+	}
+
+	return list, true
+}
+
+// broadcastListResponse returns a list with its members.
+func broadcastListResponse(listID uint) gin.H {
+	var list models.BroadcastList
+	// SQL: SELECT * FROM broadcast_lists WHERE id = ?;
+	//      SELECT * FROM broadcast_list_members WHERE list_id = ?; SELECT * FROM users WHERE id IN (...);
+	initializers.DB.Preload("Members.User").First(&list, listID)
+
+	members := []gin.H{}
+	for _, m := range list.Members {
+		members = append(members, gin.H{
+			"user_id":  m.UserID,
+			"username": m.User.Username,
+			"added_at": m.AddedAt,
+		})
+	}
+
+	return gin.H{
+		"id":         list.ID,
+		"name":       list.Name,
+		"members":    members,
+		"created_at": list.CreatedAt,
+	}
+}
@@ -0,0 +1,853 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"MessagingSystemBackend/internal/realtime"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// errNotCommunityMember is returned when adding someone to a channel of a community they are not in.
+	errNotCommunityMember = errors.New("user is not a member of this community")
+	// errCommunityFull is returned when an addition would exceed the community member limit.
+	errCommunityFull = errors.New("community has reached its member limit")
+	// errAlreadyCommunityMember is returned when adding someone who is already in the community.
+	errAlreadyCommunityMember = errors.New("user already a member of this community")
+)
+
+// channelJoin is a channel a user was added to along with the community.
+type channelJoin struct {
+	ChannelID uint
+	Role      string // Role the user got in the channel
+}
+
+// channelLeave is a channel a user left along with the community.
+type channelLeave struct {
+	ChannelID uint
+	Result    departure
+}
+
+// channelRoleChange is a channel where a user's role followed their new
+// community role.
+type channelRoleChange struct {
+	ChannelID uint
+	Previous  string
+	Role      string
+}
+
+// CreateCommunity creates a community owned by the current user. Channels are
+// added to it afterwards.
+func CreateCommunity(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var body struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := c.Bind(&body); err != nil || strings.TrimSpace(body.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid community name"})
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if len(body.Name) > maxGroupNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Name can be at most %d characters", maxGroupNameLength)})
+		return
+	}
+	if len(body.Description) > maxGroupDescriptionLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Description can be at most %d characters", maxGroupDescriptionLength)})
+		return
+	}
+
+	community := models.Community{
+		Name:        body.Name,
+		Description: strings.TrimSpace(body.Description),
+		CreatedBy:   user.Id,
+		CreatedAt:   time.Now(),
+	}
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// SQL: INSERT INTO communities (name, description, created_by, created_at) VALUES (?, ?, ?, ?);
+		if err := tx.Create(&community).Error; err != nil {
+			return err
+		}
+		owner := models.CommunityMember{CommunityID: community.ID, UserID: user.Id, Role: models.RoleOwner, JoinedAt: community.CreatedAt}
+		// SQL: INSERT INTO community_members (community_id, user_id, role, joined_at) VALUES (?, ?, 'owner', ?);
+		return tx.Create(&owner).Error
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Community name already taken"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create community"})
+		return
+	}
+
+	c.JSON(http.StatusOK, communityResponse(community))
+}
+
+// ListCommunities returns the communities the current user belongs to, with
+// their role in each.
+func ListCommunities(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	// SQL: SELECT * FROM community_members WHERE user_id = ? ORDER BY joined_at; SELECT * FROM communities WHERE id IN (...);
+	var memberships []models.CommunityMember
+	initializers.DB.Preload("Community").Where("user_id = ?", user.Id).Order("joined_at").Find(&memberships)
+
+	resp := []gin.H{}
+	for _, m := range memberships {
+		entry := communityResponse(m.Community)
+		entry["role"] = m.Role
+		resp = append(resp, entry)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetCommunity returns a community and the channels the current user can see:
+// every public channel, and the private channels they are a member of.
+func GetCommunity(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	community, ok := findCommunity(c)
+	if !ok {
+		return
+	}
+	role, ok := communityRoleTx(initializers.DB, community.ID, user.Id)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this community"})
+		return
+	}
+
+	var channels []struct {
+		ID             uint
+		Name           string
+		Topic          string
+		PrivateChannel bool
+		ArchivedAt     *time.Time
+		MemberCount    int64
+		IsMember       bool
+	}
+	// SQL:
+	// SELECT g.id, g.name, g.topic, g.private_channel, g.archived_at,
+	//   (SELECT COUNT(*) FROM group_members WHERE group_id = g.id) AS member_count, me.id IS NOT NULL AS is_member
+	// FROM groups g
+	// LEFT JOIN group_members me ON me.group_id = g.id AND me.user_id = {userId}
+	// WHERE g.community_id = ? AND g.purge_at IS NULL AND (NOT g.private_channel OR me.id IS NOT NULL)
+	// ORDER BY g.name;
+	initializers.DB.Raw(`
+		SELECT g.id, g.name, g.topic, g.private_channel, g.archived_at,
+			(SELECT COUNT(*) FROM group_members gm WHERE gm.group_id = g.id) AS member_count,
+			me.id IS NOT NULL AS is_member
+		FROM groups g
+		LEFT JOIN group_members me ON me.group_id = g.id AND me.user_id = ?
+		WHERE g.community_id = ? AND g.purge_at IS NULL AND (NOT g.private_channel OR me.id IS NOT NULL)
+		ORDER BY g.name
+	`, user.Id, community.ID).Scan(&channels)
+
+	// SQL: SELECT COUNT(*) FROM community_members WHERE community_id = ?;
+	var memberCount int64
+	initializers.DB.Model(&models.CommunityMember{}).Where("community_id = ?", community.ID).Count(&memberCount)
+
+	channelList := []gin.H{}
+	for _, ch := range channels {
+		channelList = append(channelList, gin.H{
+			"group_id":     ch.ID,
+			"name":         ch.Name,
+			"topic":        ch.Topic,
+			"private":      ch.PrivateChannel,
+			"archived":     ch.ArchivedAt != nil,
+			"member_count": ch.MemberCount,
+			"is_member":    ch.IsMember,
+		})
+	}
+
+	resp := communityResponse(community)
+	resp["role"] = role
+	resp["member_count"] = memberCount
+	resp["channels"] = channelList
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListCommunityMembers returns the members of a community with their roles.
+func ListCommunityMembers(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	community, ok := findCommunity(c)
+	if !ok {
+		return
+	}
+	if _, ok := communityRoleTx(initializers.DB, community.ID, user.Id); !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this community"})
+		return
+	}
+
+	// SQL: SELECT * FROM community_members WHERE community_id = ? ORDER BY joined_at, id; SELECT * FROM users WHERE id IN (...);
+	var members []models.CommunityMember
+	initializers.DB.Preload("User").Where("community_id = ?", community.ID).Order("joined_at, id").Find(&members)
+
+	resp := []gin.H{}
+	for _, m := range members {
+		resp = append(resp, gin.H{
+			"user_id":   m.UserID,
+			"username":  m.User.Username,
+			"role":      m.Role,
+			"joined_at": m.JoinedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// AddCommunityMember adds a user to a community and to all of its public
+// channels. Only the owner and admins may add members, and adding an admin is
+// up to the owner. The user's group add preference applies as for groups.
+func AddCommunityMember(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	community, ok := findCommunity(c)
+	if !ok {
+		return
+	}
+	actorRole, ok := requireCommunityAdmin(c, community.ID, currentUser.Id, "You cannot add members to this community")
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Username string `json:"username"`
+		Role     string `json:"role"`
+	}
+	if err := c.Bind(&body); err != nil || body.Username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid username"})
+		return
+	}
+	if body.Role == "" {
+		body.Role = models.RoleMember
+	}
+	if !models.IsValidCommunityRole(body.Role) || body.Role == models.RoleOwner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be admin or member"})
+		return
+	}
+	if body.Role == models.RoleAdmin && actorRole != models.RoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the community owner can add admins"})
+		return
+	}
+
+	// SQL: SELECT * FROM users WHERE username = ?;
+	var user models.User
+	if err := initializers.DB.Where("username = ?", body.Username).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !allowsDirectAdd(currentUser.Id, user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This user does not allow being added to groups directly"})
+		return
+	}
+
+	var joined []channelJoin
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := addCommunityMemberTx(tx, community.ID, user.Id, body.Role); err != nil {
+			return err
+		}
+		var err error
+		joined, err = joinCommunityChannelsTx(tx, community.ID, user, currentUser)
+		return err
+	})
+	if errors.Is(err, errAlreadyCommunityMember) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User already a member of this community"})
+		return
+	}
+	if errors.Is(err, errCommunityFull) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Community has reached its member limit"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not add member"})
+		return
+	}
+
+	publishChannelJoins(joined, user.Id)
+	realtime.Publish(realtime.UserTopic(user.Id), realtime.Event{Type: "community.joined", Data: communityResponse(community)})
+	c.JSON(http.StatusOK, gin.H{"message": "User added to community", "channels_joined": len(joined)})
+}
+
+// SetCommunityRole makes a member an admin of the community or a regular
+// member again. Only the owner may change roles. The new role is applied to
+// every channel the member is in, except channels they own, and recorded and
+// announced there like any other role change.
+func SetCommunityRole(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	community, ok := findCommunity(c)
+	if !ok {
+		return
+	}
+	actorRole, ok := requireCommunityAdmin(c, community.ID, currentUser.Id, "Only the community owner can change roles")
+	if !ok {
+		return
+	}
+	if actorRole != models.RoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the community owner can change roles"})
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var body struct {
+		Role string `json:"role"`
+	}
+	if err := c.Bind(&body); err != nil || body.Role == models.RoleOwner || !models.IsValidCommunityRole(body.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be admin or member"})
+		return
+	}
+
+	// SQL: SELECT * FROM users WHERE id = ? LIMIT 1;
+	var target models.User
+	if err := initializers.DB.First(&target, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var changes []channelRoleChange
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// SQL: SELECT * FROM community_members WHERE community_id = ? AND user_id = ? LIMIT 1 FOR UPDATE;
+		var member models.CommunityMember
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("community_id = ? AND user_id = ?", community.ID, userID).First(&member).Error
+		if err != nil {
+			return err
+		}
+		if member.Role == models.RoleOwner {
+			return errOwnerRole
+		}
+		if member.Role == body.Role {
+			return errSameRole
+		}
+
+		// SQL: UPDATE community_members SET role = ? WHERE id = ?;
+		if err := tx.Model(&member).Update("role", body.Role).Error; err != nil {
+			return err
+		}
+
+		// Channel owners keep their channel; everywhere else the community role wins
+		// SQL: SELECT * FROM group_members WHERE user_id = ? AND role <> 'owner'
+		//        AND group_id IN (SELECT id FROM groups WHERE community_id = ?) ORDER BY group_id;
+		var memberships []models.GroupMember
+		err = tx.Where("user_id = ? AND role <> ? AND group_id IN (?)", target.Id, models.RoleOwner,
+			tx.Model(&models.Group{}).Select("id").Where("community_id = ?", community.ID)).
+			Order("group_id").Find(&memberships).Error
+		if err != nil {
+			return err
+		}
+
+		role := channelRole(body.Role)
+		for _, m := range memberships {
+			if m.Role == role {
+				continue
+			}
+			if _, err := lockGroup(tx, m.GroupID); err != nil {
+				return err
+			}
+			if role == models.RoleAdmin && !canAddAdminTx(tx, m.GroupID) {
+				return errAdminLimit
+			}
+			if err := setMemberRoleTx(tx, m, target, currentUser, role); err != nil {
+				return err
+			}
+			changes = append(changes, channelRoleChange{ChannelID: m.GroupID, Previous: m.Role, Role: role})
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of this community"})
+		return
+	}
+	if errors.Is(err, errOwnerRole) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The community owner's role cannot be changed"})
+		return
+	}
+	if errors.Is(err, errSameRole) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User already has this role"})
+		return
+	}
+	if errors.Is(err, errAdminLimit) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not change role"})
+		return
+	}
+
+	for _, change := range changes {
+		publishRoleChange(change.ChannelID, target.Id, change.Previous, change.Role)
+	}
+	realtime.Publish(realtime.UserTopic(target.Id), realtime.Event{Type: "community.role_changed", Data: gin.H{"community_id": community.ID, "role": body.Role}})
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated", "role": body.Role})
+}
+
+// RemoveCommunityMember removes a member from a community and from every one
+// of its channels. Admins can remove members, the owner can also remove admins;
+// the owner cannot be removed.
+func RemoveCommunityMember(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	community, ok := findCommunity(c)
+	if !ok {
+		return
+	}
+	actorRole, ok := requireCommunityAdmin(c, community.ID, currentUser.Id, "You cannot remove members from this community")
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if uint(userID) == currentUser.Id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use leave to remove yourself from a community"})
+		return
+	}
+
+	targetRole, ok := communityRoleTx(initializers.DB, community.ID, uint(userID))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of this community"})
+		return
+	}
+	if targetRole == models.RoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The community owner cannot be removed"})
+		return
+	}
+	if targetRole == models.RoleAdmin && actorRole != models.RoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the community owner can remove admins"})
+		return
+	}
+
+	// SQL: SELECT * FROM users WHERE id = ? LIMIT 1;
+	var target models.User
+	if err := initializers.DB.First(&target, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var left []channelLeave
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		left, err = leaveCommunityTx(tx, community.ID, target, currentUser, models.MembershipRemoved,
+			memberDepartedEvent(currentUser, target, models.MembershipRemoved, true))
+		return err
+	})
+	if errors.Is(err, errNotCommunityMember) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of this community"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not remove member"})
+		return
+	}
+
+	for _, l := range left {
+		publishDeparture(l.ChannelID, "member.removed", target, l.Result)
+	}
+	realtime.Publish(realtime.UserTopic(target.Id), realtime.Event{Type: "community.removed", Data: gin.H{"community_id": community.ID}})
+	c.JSON(http.StatusOK, gin.H{"message": "User removed from community"})
+}
+
+// LeaveCommunity removes the current user from a community and from all of
+// its channels. The owner cannot leave.
+func LeaveCommunity(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	community, ok := findCommunity(c)
+	if !ok {
+		return
+	}
+
+	role, ok := communityRoleTx(initializers.DB, community.ID, currentUser.Id)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this community"})
+		return
+	}
+	if role == models.RoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The community owner cannot leave"})
+		return
+	}
+
+	var left []channelLeave
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		left, err = leaveCommunityTx(tx, community.ID, currentUser, currentUser, models.MembershipLeft,
+			memberDepartedEvent(currentUser, currentUser, models.MembershipLeft, true))
+		return err
+	})
+	if errors.Is(err, errNotCommunityMember) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this community"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not leave community"})
+		return
+	}
+
+	for _, l := range left {
+		publishDeparture(l.ChannelID, "member.left", currentUser, l.Result)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "You left the community"})
+}
+
+// CreateChannel adds a channel to a community. The creator owns it. Every
+// community member joins a public channel with their community role; a
+// private channel starts with only its creator, who adds members as in any
+// group.
+func CreateChannel(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	community, ok := findCommunity(c)
+	if !ok {
+		return
+	}
+	if _, ok := requireCommunityAdmin(c, community.ID, user.Id, "You cannot create channels in this community"); !ok {
+		return
+	}
+
+	var body struct {
+		Name    string `json:"name"`
+		Topic   string `json:"topic"`
+		Private bool   `json:"private"`
+	}
+	if err := c.Bind(&body); err != nil || strings.TrimSpace(body.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel name"})
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if len(body.Name) > maxGroupNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Name can be at most %d characters", maxGroupNameLength)})
+		return
+	}
+	if len(body.Topic) > maxGroupTopicLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Topic can be at most %d characters", maxGroupTopicLength)})
+		return
+	}
+
+	channel := models.Group{
+		Name:           body.Name,
+		Topic:          strings.TrimSpace(body.Topic),
+		CreatedBy:      user.Id,
+		CreatedAt:      time.Now(),
+		CommunityID:    &community.ID,
+		PrivateChannel: body.Private,
+	}
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// SQL: INSERT INTO groups (name, topic, created_by, created_at, community_id, private_channel) VALUES (?, ?, ?, ?, ?, ?);
+		if err := tx.Create(&channel).Error; err != nil {
+			return err
+		}
+		owner := models.GroupMember{GroupID: channel.ID, UserID: user.Id, Role: models.RoleOwner, JoinedAt: channel.CreatedAt}
+		// SQL: INSERT INTO group_members (group_id, user_id, role, joined_at) VALUES (?, ?, 'owner', ?);
+		if err := tx.Create(&owner).Error; err != nil {
+			return err
+		}
+		if err := recordMembership(tx, channel.ID, user.Id, user.Id, models.MembershipAdded); err != nil {
+			return err
+		}
+		conv, err := groupConversationTx(tx, channel.ID)
+		if err != nil {
+			return err
+		}
+		if _, err := PostSystemMessage(tx, conv.ID, user.Id, groupCreatedEvent(user, channel.Name)); err != nil {
+			return err
+		}
+		if channel.PrivateChannel {
+			return nil
+		}
+
+		// SQL: SELECT * FROM community_members WHERE community_id = ? AND user_id <> ? ORDER BY joined_at, id;
+		var members []models.CommunityMember
+		err = tx.Where("community_id = ? AND user_id <> ?", community.ID, user.Id).Order("joined_at, id").Find(&members).Error
+		if err != nil {
+			return err
+		}
+		for _, m := range members {
+			if err := addGroupMemberTx(tx, channel.ID, m.UserID, channelRole(m.Role)); err != nil {
+				return err
+			}
+			if err := recordMembership(tx, channel.ID, m.UserID, user.Id, models.MembershipJoinedCommunity); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Channel name already taken in this community"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create channel"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"group_id": channel.ID, "community_id": community.ID, "private": channel.PrivateChannel})
+}
+
+// JoinChannel adds the current user back to a public channel of a community
+// they belong to, for instance after leaving it.
+func JoinChannel(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	community, ok := findCommunity(c)
+	if !ok {
+		return
+	}
+	role, ok := communityRoleTx(initializers.DB, community.ID, user.Id)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this community"})
+		return
+	}
+
+	channelID, err := strconv.Atoi(c.Param("channelId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel ID"})
+		return
+	}
+	// SQL: SELECT * FROM groups WHERE id = ? AND community_id = ? AND purge_at IS NULL LIMIT 1;
+	var channel models.Group
+	err = initializers.DB.Where("community_id = ? AND purge_at IS NULL", community.ID).First(&channel, channelID).Error
+	if err != nil || channel.PrivateChannel {
+		// Private channels are not revealed to non-members
+		c.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := addGroupMemberTx(tx, channel.ID, user.Id, channelRole(role)); err != nil {
+			return err
+		}
+		if err := recordMembership(tx, channel.ID, user.Id, user.Id, models.MembershipJoinedChannel); err != nil {
+			return err
+		}
+
+		conv, err := groupConversationTx(tx, channel.ID)
+		if err != nil {
+			return err
+		}
+		_, err = PostSystemMessage(tx, conv.ID, user.Id, memberJoinedEvent(user, user, models.MembershipJoinedChannel))
+		return err
+	})
+	if errors.Is(err, errAlreadyMember) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You are already a member of this channel"})
+		return
+	}
+	if errors.Is(err, errBanned) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are banned from this channel"})
+		return
+	}
+	if err != nil {
+		membershipError(c, err)
+		return
+	}
+
+	realtime.Publish(realtime.GroupTopic(channel.ID), realtime.Event{Type: "member.joined", Data: gin.H{"group_id": channel.ID, "user_id": user.Id}})
+	c.JSON(http.StatusOK, gin.H{"message": "You joined the channel", "group_id": channel.ID})
+}
+
+// addCommunityMemberTx adds a user to a community with the given role. The
+// community row is locked so concurrent additions cannot exceed the member
+// limit together.
+func addCommunityMemberTx(tx *gorm.DB, communityID uint, userID uint, role string) error {
+	// SQL: SELECT * FROM communities WHERE id = ? FOR UPDATE;
+	var community models.Community
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&community, communityID).Error; err != nil {
+		return err
+	}
+
+	if _, ok := communityRoleTx(tx, communityID, userID); ok {
+		return errAlreadyCommunityMember
+	}
+	// Every member joins every public channel, so a community holds no more
+	// members than the largest group the server allows
+	// SQL: SELECT COUNT(*) FROM community_members WHERE community_id = ?;
+	var count int64
+	tx.Model(&models.CommunityMember{}).Where("community_id = ?", communityID).Count(&count)
+	if int(count) >= initializers.PlatformGroupLimits.MaxMembers {
+		return errCommunityFull
+	}
+
+	member := models.CommunityMember{CommunityID: communityID, UserID: userID, Role: role, JoinedAt: time.Now()}
+	// SQL: INSERT INTO community_members (community_id, user_id, role, joined_at) VALUES (?, ?, ?, ?);
+	return tx.Create(&member).Error
+}
+
+// joinCommunityChannelsTx adds a new community member to every public channel
+// of the community that is not read-only and that they are not banned from,
+// with their community role, and announces them there.
+func joinCommunityChannelsTx(tx *gorm.DB, communityID uint, user models.User, actor models.User) ([]channelJoin, error) {
+	role, ok := communityRoleTx(tx, communityID, user.Id)
+	if !ok {
+		return nil, errNotCommunityMember
+	}
+
+	// SQL: SELECT * FROM groups WHERE community_id = ? AND NOT private_channel AND archived_at IS NULL AND purge_at IS NULL
+	//        AND id NOT IN (SELECT group_id FROM group_bans WHERE user_id = ?) ORDER BY id;
+	var channels []models.Group
+	err := tx.Where("community_id = ? AND NOT private_channel AND archived_at IS NULL AND purge_at IS NULL", communityID).
+		Where("id NOT IN (?)", tx.Model(&models.GroupBan{}).Select("group_id").Where("user_id = ?", user.Id)).
+		Order("id").Find(&channels).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var joined []channelJoin
+	for _, ch := range channels {
+		err := addGroupMemberTx(tx, ch.ID, user.Id, channelRole(role))
+		if errors.Is(err, errAlreadyMember) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := recordMembership(tx, ch.ID, user.Id, actor.Id, models.MembershipJoinedCommunity); err != nil {
+			return nil, err
+		}
+
+		conv, err := groupConversationTx(tx, ch.ID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := PostSystemMessage(tx, conv.ID, actor.Id, memberJoinedEvent(actor, user, models.MembershipJoinedCommunity)); err != nil {
+			return nil, err
+		}
+		joined = append(joined, channelJoin{ChannelID: ch.ID, Role: channelRole(role)})
+	}
+	return joined, nil
+}
+
+// leaveCommunityTx deletes a community membership and removes the user from
+// every channel of the community, with the usual succession in each channel.
+// Removals are written to each channel's audit log.
+func leaveCommunityTx(tx *gorm.DB, communityID uint, target models.User, actor models.User, action string, announcement systemEvent) ([]channelLeave, error) {
+	// SQL: DELETE FROM community_members WHERE community_id = ? AND user_id = ?;
+	result := tx.Where("community_id = ? AND user_id = ?", communityID, target.Id).Delete(&models.CommunityMember{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errNotCommunityMember
+	}
+
+	// SQL: SELECT groups.* FROM groups JOIN group_members ON group_members.group_id = groups.id
+	//        WHERE groups.community_id = ? AND groups.purge_at IS NULL AND group_members.user_id = ? ORDER BY groups.id;
+	var channels []models.Group
+	err := tx.Joins("JOIN group_members ON group_members.group_id = groups.id").
+		Where("groups.community_id = ? AND groups.purge_at IS NULL AND group_members.user_id = ?", communityID, target.Id).
+		Order("groups.id").Find(&channels).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var left []channelLeave
+	for _, ch := range channels {
+		result, err := removeChannelMemberTx(tx, ch, target, actor, action, announcement)
+		if err != nil {
+			return nil, err
+		}
+		if action == models.MembershipRemoved {
+			if err := recordAudit(tx, ch.ID, actor.Id, models.AuditMemberRemoved, auditUser(target.Id), gin.H{"role": result.Role}, nil); err != nil {
+				return nil, err
+			}
+		}
+		left = append(left, channelLeave{ChannelID: ch.ID, Result: result})
+	}
+	return left, nil
+}
+
+// publishChannelJoins notifies live subscribers of each channel a user was
+// added to.
+func publishChannelJoins(joined []channelJoin, userID uint) {
+	for _, j := range joined {
+		realtime.Publish(realtime.GroupTopic(j.ChannelID), realtime.Event{Type: "member.joined", Data: gin.H{"group_id": j.ChannelID, "user_id": userID, "role": j.Role}})
+	}
+}
+
+// communityRoleTx returns a user's role in a community, and false if they are
+// not a member.
+func communityRoleTx(tx *gorm.DB, communityID uint, userID uint) (string, bool) {
+	var member models.CommunityMember
+	// SQL: SELECT * FROM community_members WHERE community_id = ? AND user_id = ? LIMIT 1;
+	if err := tx.Where("community_id = ? AND user_id = ?", communityID, userID).First(&member).Error; err != nil {
+		return "", false
+	}
+	return member.Role, true
+}
+
+// channelRole is the role a community role gives in the community's channels.
+// The community owner and admins administer every channel, but each channel
+// keeps its own owner.
+func channelRole(communityRole string) string {
+	if communityRole == models.RoleOwner || communityRole == models.RoleAdmin {
+		return models.RoleAdmin
+	}
+	return models.RoleMember
+}
+
+// requireCommunityAdmin writes an error response and returns false unless the
+// user is the owner or an admin of the community. It returns their role.
+func requireCommunityAdmin(c *gin.Context, communityID uint, userID uint, denied string) (string, bool) {
+	role, ok := communityRoleTx(initializers.DB, communityID, userID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this community"})
+		return "", false
+	}
+	if role != models.RoleOwner && role != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": denied})
+		return "", false
+	}
+	return role, true
+}
+
+// findCommunity loads the community in the :id URL param, writing an error
+// response and returning false if it does not exist.
+func findCommunity(c *gin.Context) (models.Community, bool) {
+	var community models.Community
+
+	communityID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid community ID"})
+		return community, false
+	}
+
+	// SQL: SELECT * FROM communities WHERE id = ? LIMIT 1;
+	if err := initializers.DB.First(&community, communityID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Community not found"})
+		return community, false
+	}
+	return community, true
+}
+
+// communityResponse returns the fields of a community sent to clients.
+func communityResponse(community models.Community) gin.H {
+	return gin.H{
+		"id":          community.ID,
+		"name":        community.Name,
+		"description": community.Description,
+		"created_by":  community.CreatedBy,
+		"created_at":  community.CreatedAt,
+	}
+}
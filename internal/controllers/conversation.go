@@ -0,0 +1,421 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+
+	// Largest participant set of an ad-hoc direct conversation
+	maxDirectParticipants = 25
+)
+
+// ListConversations returns a preview of every conversation the user is in,
+// direct and group alike, with the most recently active first.
+func ListConversations(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	page, limit := parsePagination(c, 20, 100)
+
+	var results []struct {
+		ConversationID uint
+		Kind           string
+		GroupID        *uint
+		GroupName      *string
+		CommunityID    *uint
+		CommunityName  *string
+		ArchivedAt     *time.Time
+		Participants   string
+		SenderID       *uint
+		Content        *string
+		CreatedAt      *time.Time
+	}
+
+	// SQL:
+	// SELECT c.id, c.kind, c.group_id, g.name, g.community_id, co.name, g.archived_at, <other members>,
+	//   last.sender_id, last.content, last.created_at
+	// FROM conversation_members me
+	// JOIN conversations c ON c.id = me.conversation_id
+	// LEFT JOIN groups g ON g.id = c.group_id
+	// LEFT JOIN communities co ON co.id = g.community_id
+	// LEFT JOIN LATERAL (SELECT * FROM messages WHERE conversation_id = c.id ORDER BY created_at DESC LIMIT 1) last ON true
+	// WHERE me.user_id = {userId} AND g.purge_at IS NULL
+	// ORDER BY COALESCE(last.created_at, c.created_at) DESC;
+	initializers.DB.Raw(`
+		SELECT c.id AS conversation_id, c.kind, c.group_id, g.name AS group_name,
+			g.community_id, co.name AS community_name, g.archived_at,
+			COALESCE((
+				SELECT string_agg(u.username, ',' ORDER BY u.username)
+				FROM conversation_members cm
+				JOIN users u ON u.id = cm.user_id
+				WHERE cm.conversation_id = c.id AND cm.user_id <> ? AND c.kind = 'direct'
+			), '') AS participants,
+			last.sender_id, last.content, last.created_at
+		FROM conversation_members me
+		JOIN conversations c ON c.id = me.conversation_id
+		LEFT JOIN groups g ON g.id = c.group_id
+		LEFT JOIN communities co ON co.id = g.community_id
+		LEFT JOIN LATERAL (
+			SELECT m.sender_id, m.content, m.created_at FROM messages m
+			WHERE m.conversation_id = c.id ORDER BY m.created_at DESC LIMIT 1
+		) last ON true
+		WHERE me.user_id = ? AND g.purge_at IS NULL
+		ORDER BY COALESCE(last.created_at, c.created_at) DESC
+		LIMIT ? OFFSET ?
+	`, user.Id, user.Id, limit, (page-1)*limit).Scan(&results)
+
+	resp := []gin.H{}
+	for _, r := range results {
+		entry := gin.H{
+			"id":   r.ConversationID,
+			"kind": r.Kind,
+		}
+		if r.Kind == models.ConversationGroup {
+			entry["group_id"] = r.GroupID
+			entry["group_name"] = r.GroupName
+			entry["archived"] = r.ArchivedAt != nil
+			if r.CommunityID != nil {
+				entry["community_id"] = r.CommunityID
+				entry["community_name"] = r.CommunityName
+			}
+		} else {
+			participants := []string{}
+			if r.Participants != "" {
+				participants = strings.Split(r.Participants, ",")
+			}
+			entry["participants"] = participants
+		}
+		if r.CreatedAt != nil {
+			entry["last_message"] = gin.H{
+				"sender_id":  r.SenderID,
+				"content":    r.Content,
+				"created_at": r.CreatedAt,
+			}
+		}
+		resp = append(resp, entry)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// OpenDirectConversation returns the direct conversation with another user,
+// creating it if the two have never talked.
+func OpenDirectConversation(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// SQL: SELECT * FROM users WHERE id = ? LIMIT 1;
+	var partner models.User
+	if err := initializers.DB.First(&partner, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var conv models.Conversation
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		conv, err = models.FindOrCreateDirectConversation(tx, []uint{user.Id, partner.Id})
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not open conversation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"conversation_id": conv.ID})
+}
+
+// StartDirectConversation finds the unnamed direct conversation between the
+// user and a set of other users, creating it the first time. The same set of
+// people always gets the same conversation. An optional first message is sent
+// right away.
+func StartDirectConversation(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var body struct {
+		UserIDs []uint `json:"user_ids"`
+		Content string `json:"content"`
+	}
+	if err := c.Bind(&body); err != nil || len(body.UserIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one participant required"})
+		return
+	}
+
+	participants := append([]uint{user.Id}, body.UserIDs...)
+	ids := strings.Split(models.DirectKey(participants), ":")
+	if len(ids) > maxDirectParticipants {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A direct conversation can have at most %d participants", maxDirectParticipants)})
+		return
+	}
+
+	// SQL: SELECT COUNT(*) FROM users WHERE id IN (...);
+	var found int64
+	initializers.DB.Model(&models.User{}).Where("id IN ?", ids).Count(&found)
+	if int(found) != len(ids) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var conv models.Conversation
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		conv, err = models.FindOrCreateDirectConversation(tx, participants)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not open conversation"})
+		return
+	}
+
+	resp := gin.H{"conversation_id": conv.ID}
+	if body.Content != "" {
+		msg, err := PostMessage(conv.ID, user.Id, body.Content)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+			return
+		}
+		resp["message"] = MessageResponse(msg)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetConversationMessages returns the latest messages of a conversation,
+// newest first. Older pages are fetched by passing the oldest message ID seen
+// as the before query param; pages follow the order of the messages' times,
+// which for imported history is not the order of their IDs.
+func GetConversationMessages(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	conv, ok := findMemberConversation(c, user)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultHistoryLimit)))
+	if err != nil || limit < 1 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	var before *models.Message
+	if v := c.Query("before"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before cursor"})
+			return
+		}
+		// SQL: SELECT * FROM messages WHERE id = ? AND conversation_id = ? LIMIT 1;
+		var cursor models.Message
+		if err := initializers.DB.Where("conversation_id = ?", conv.ID).First(&cursor, id).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before cursor"})
+			return
+		}
+		before = &cursor
+	}
+
+	resp := []gin.H{}
+	for _, msg := range ConversationHistory(conv.ID, limit, before) {
+		resp = append(resp, MessageResponse(msg))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// SendConversationMessage posts a message to a conversation the user is in.
+func SendConversationMessage(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	conv, ok := findMemberConversation(c, user)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Content string `json:"content"`
+	}
+	if err := c.Bind(&body); err != nil || body.Content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message content"})
+		return
+	}
+
+	if conv.GroupID != nil {
+		if !requireCapability(c, *conv.GroupID, user.Id, models.CapPost, "You cannot post in this group") {
+			return
+		}
+		msg, ok := postGroupMessage(c, *conv.GroupID, conv.ID, user.Id, body.Content)
+		if ok {
+			c.JSON(http.StatusOK, MessageResponse(msg))
+		}
+		return
+	}
+
+	msg, err := PostMessage(conv.ID, user.Id, body.Content)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse(msg))
+}
+
+// GroupConversation returns the conversation of a group.
+func GroupConversation(groupID uint) (models.Conversation, error) {
+	return groupConversationTx(initializers.DB, groupID)
+}
+
+// groupConversationTx returns the conversation of a group inside a transaction.
+func groupConversationTx(tx *gorm.DB, groupID uint) (models.Conversation, error) {
+	var conv models.Conversation
+	// SQL: SELECT * FROM conversations WHERE group_id = ? LIMIT 1;
+	err := tx.Where("group_id = ?", groupID).First(&conv).Error
+	return conv, err
+}
+
+// IsConversationMember checks if a user takes part in a conversation. The
+// conversations of groups awaiting deletion have no members.
+func IsConversationMember(conversationID uint, userID uint) bool {
+	var count int64
+	// SQL: SELECT COUNT(*) FROM conversation_members
+	//        JOIN conversations ON conversations.id = conversation_members.conversation_id
+	//        LEFT JOIN groups ON groups.id = conversations.group_id
+	//        WHERE conversation_members.conversation_id = ? AND conversation_members.user_id = ? AND groups.purge_at IS NULL;
+	initializers.DB.Model(&models.ConversationMember{}).
+		Joins("JOIN conversations ON conversations.id = conversation_members.conversation_id").
+		Joins("LEFT JOIN groups ON groups.id = conversations.group_id").
+		Where("conversation_members.conversation_id = ? AND conversation_members.user_id = ? AND groups.purge_at IS NULL", conversationID, userID).
+		Count(&count)
+	return count > 0
+}
+
+// PostMessage stores a new message in a conversation.
+func PostMessage(conversationID uint, senderID uint, content string) (models.Message, error) {
+	return postMessageTx(initializers.DB, conversationID, senderID, content)
+}
+
+// postMessageTx is PostMessage inside a transaction.
+func postMessageTx(tx *gorm.DB, conversationID uint, senderID uint, content string) (models.Message, error) {
+	msg := models.Message{
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		Kind:           models.MessageUser,
+		Content:        content,
+		CreatedAt:      time.Now(),
+	}
+
+	// SQL: INSERT INTO messages (conversation_id, sender_id, kind, content, created_at) VALUES (?, ?, 'user', ?, ?);
+	err := tx.Create(&msg).Error
+	return msg, err
+}
+
+// legacyMessageID returns the ID of the message that the old group_messages
+// or direct_messages table (by kind) knew as id, or id itself if it is not an
+// old ID. Only the routes that predate conversations take old IDs.
+func legacyMessageID(kind string, id string) string {
+	var legacy models.LegacyMessageID
+	// SQL: SELECT * FROM legacy_message_ids WHERE kind = ? AND legacy_id = ? LIMIT 1;
+	if err := initializers.DB.Where("kind = ? AND legacy_id = ?", kind, id).First(&legacy).Error; err != nil {
+		return id
+	}
+	return strconv.FormatUint(uint64(legacy.MessageID), 10)
+}
+
+// PostSystemMessage stores an announcement in a conversation, attributed to
+// the user whose action caused it. The event's type and details are kept
+// alongside its text so clients can render it in their own language.
+func PostSystemMessage(tx *gorm.DB, conversationID uint, actorID uint, event systemEvent) (models.Message, error) {
+	data, err := jsonColumn(event.Data)
+	if err != nil {
+		return models.Message{}, err
+	}
+	msg := models.Message{
+		ConversationID: conversationID,
+		SenderID:       actorID,
+		Kind:           models.MessageSystem,
+		Content:        event.Content,
+		Event:          event.Type,
+		EventData:      data,
+		CreatedAt:      time.Now(),
+	}
+
+	// SQL: INSERT INTO messages (conversation_id, sender_id, kind, content, event, event_data, created_at) VALUES (?, ?, 'system', ?, ?, ?, ?);
+	err = tx.Create(&msg).Error
+	return msg, err
+}
+
+// ConversationHistory returns up to limit messages of a conversation, newest
+// first, optionally only those that come before the message before in that
+// order.
+func ConversationHistory(conversationID uint, limit int, before *models.Message) []models.Message {
+	query := initializers.DB.Where("conversation_id = ?", conversationID)
+	if before != nil {
+		query = query.Where("(created_at, id) < (?, ?)", before.CreatedAt, before.ID)
+	}
+
+	// SQL: SELECT * FROM messages WHERE conversation_id = ? [AND (created_at, id) < (?, ?)] ORDER BY created_at DESC, id DESC LIMIT ?;
+	var messages []models.Message
+	query.Order("created_at DESC, id DESC").Limit(limit).Find(&messages)
+	return messages
+}
+
+// MessageResponse returns the fields of a message sent to clients.
+// System messages also carry their event type and details.
+func MessageResponse(msg models.Message) gin.H {
+	resp := gin.H{
+		"id":              msg.ID,
+		"conversation_id": msg.ConversationID,
+		"sender_id":       msg.SenderID,
+		"kind":            msg.Kind,
+		"content":         msg.Content,
+		"created_at":      msg.CreatedAt,
+		"updated_at":      msg.UpdatedAt.UTC(),
+		"pinned_at":       msg.PinnedAt,
+	}
+	if msg.Kind == models.MessageSystem {
+		resp["event"] = msg.Event
+		resp["data"] = rawJSON(msg.EventData)
+	}
+	return resp
+}
+
+// findMemberConversation loads the conversation in the :id URL param,
+// writing an error response and returning false unless the user is in it.
+func findMemberConversation(c *gin.Context, user models.User) (models.Conversation, bool) {
+	var conv models.Conversation
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return conv, false
+	}
+
+	// SQL: SELECT * FROM conversations WHERE id = ? LIMIT 1;
+	if err := initializers.DB.First(&conv, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+		return conv, false
+	}
+
+	if !IsConversationMember(conv.ID, user.Id) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this conversation"})
+		return conv, false
+	}
+
+	return conv, true
+}
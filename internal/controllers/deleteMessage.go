@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"MessagingSystemBackend/internal/realtime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DeleteMessage deletes a message. Anyone may delete their own messages; in
+// groups, members who may delete messages can also delete other members'
+// messages and announcements.
+func DeleteMessage(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var msg models.Message
+
+	// SQL: SELECT * FROM messages WHERE id = ? LIMIT 1;
+	//      SELECT * FROM conversations WHERE id = ?;
+	if err := initializers.DB.Preload("Conversation").First(&msg, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+
+	if !IsConversationMember(msg.ConversationID, user.Id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+
+	groupID := msg.Conversation.GroupID
+	if groupID != nil && groupReadOnlyTx(initializers.DB, *groupID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This group is archived or scheduled for deletion"})
+		return
+	}
+	own := msg.SenderID == user.Id && msg.Kind == models.MessageUser
+	if !own && (groupID == nil || !Authorize(*groupID, user.Id, models.CapDeleteMessages)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to delete this message"})
+		return
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// Cascades to the message's edit history and any poll it carries
+		// SQL: DELETE FROM messages WHERE id = ?;
+		if err := tx.Delete(&models.Message{}, msg.ID).Error; err != nil {
+			return err
+		}
+		// Moderators deleting others' messages is audited; members tidying up their own is not
+		if own || groupID == nil {
+			return nil
+		}
+		return recordAudit(tx, *groupID, user.Id, models.AuditMessageDeleted, auditMessage(msg.ID, msg.SenderID),
+			gin.H{"kind": msg.Kind, "content": msg.Content, "created_at": msg.CreatedAt}, nil)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete message"})
+		return
+	}
+
+	if groupID != nil {
+		realtime.Publish(realtime.GroupTopic(*groupID), realtime.Event{Type: "message.deleted", Data: gin.H{"group_id": *groupID, "message_id": msg.ID}})
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Message deleted"})
+}
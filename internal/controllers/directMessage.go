@@ -5,9 +5,9 @@ import (
 	"MessagingSystemBackend/internal/models"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // SendDirectMessage handles sending a direct message from one user to another.
@@ -46,18 +46,20 @@ func SendDirectMessage(c *gin.Context) {
 	// Get the currently authenticated user (sender)
 	sender := c.MustGet("user").(models.User)
 
-	// Create a new direct message record
-	message := models.DirectMessage{
-		SenderID:   sender.Id,
-		ReceiverID: receiver.Id,
-		Content:    body.Content,
-		CreatedAt:  time.Now(),
+	// Find the conversation between the two users, starting it on the first message
+	var conv models.Conversation
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		conv, err = models.FindOrCreateDirectConversation(tx, []uint{sender.Id, receiver.Id})
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+		return
 	}
 
-	// SQL: INSERT INTO direct_messages (sender_id, receiver_id, content, created_at)
-	//        VALUES (?, ?, ?, ?);
-	// Save the new direct message to the database
-	if err := initializers.DB.Create(&message).Error; err != nil {
+	// Save the new message to the conversation
+	if _, err := PostMessage(conv.ID, sender.Id, body.Content); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
 		return
 	}
@@ -69,11 +71,15 @@ func SendDirectMessage(c *gin.Context) {
 // GetDirectMessage handles retrieving a direct message by its ID.
 func GetDirectMessage(c *gin.Context) {
 	msgID := c.Param("id") // Get message ID from the URL path
-	var msg models.DirectMessage
+	var msg models.Message
 
-	// SQL: SELECT * FROM direct_messages WHERE id = msgID LIMIT 1;
+	// SQL: SELECT messages.* FROM messages JOIN conversations ON conversations.id = messages.conversation_id
+	//        WHERE messages.id = msgID AND conversations.kind = 'direct' LIMIT 1;
 	// Fetch the message from the database
-	if err := initializers.DB.First(&msg, msgID).Error; err != nil {
+	err := initializers.DB.Joins("JOIN conversations ON conversations.id = messages.conversation_id").
+		Where("conversations.kind = ?", models.ConversationDirect).
+		First(&msg, msgID).Error
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
 		return
 	}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"MessagingSystemBackend/internal/realtime"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// How often an idle event stream is pinged to keep proxies from closing it
+const streamKeepAlive = 30 * time.Second
+
+// StreamGroupEvents pushes live group events (such as poll updates) to a member
+// using Server-Sent Events until the client disconnects.
+func StreamGroupEvents(c *gin.Context) {
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// SQL: SELECT * FROM groups WHERE id = ? LIMIT 1;
+	var group models.Group
+	if err := initializers.DB.First(&group, groupID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	if !IsGroupMember(group.ID, user.Id) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// The stream ends when the user leaves or is removed from the group
+	streamEvents(c, realtime.GroupTopic(group.ID), user.Id)
+}
+
+// StreamUserEvents pushes events addressed to the current user (such as the
+// outcome of their join requests) using Server-Sent Events.
+func StreamUserEvents(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	streamEvents(c, realtime.UserTopic(user.Id), user.Id)
+}
+
+// streamEvents forwards the events of a topic to the client until it
+// disconnects or is kicked from the topic.
+func streamEvents(c *gin.Context, topic string, userID uint) {
+	events, unsubscribe := realtime.Subscribe(topic, userID)
+	defer unsubscribe()
+
+	ticker := time.NewTicker(streamKeepAlive)
+	defer ticker.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event.Data)
+			return true
+		case <-ticker.C:
+			c.SSEvent("ping", gin.H{"time": time.Now().UTC()})
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
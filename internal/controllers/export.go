@@ -0,0 +1,372 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Number of exported messages written between flushes to the client
+const exportFlushEvery = 200
+
+// exportedMessage is a single row of an export, including its edit history
+// and references to content attached to it. System messages carry their
+// event; their sender is the member whose action caused them.
+type exportedMessage struct {
+	ID             uint            `json:"id"`
+	Kind           string          `json:"kind"`
+	Event          string          `json:"event,omitempty"`
+	Data           json.RawMessage `json:"data,omitempty"`
+	SenderID       uint            `json:"sender_id"`
+	SenderUsername string          `json:"sender_username"`
+	Content        string          `json:"content"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	Edits          []exportedEdit  `json:"edits"`
+	PollID         *uint           `json:"poll_id,omitempty"`
+}
+
+type exportedEdit struct {
+	PreviousContent string    `json:"previous_content"`
+	EditedAt        time.Time `json:"edited_at"`
+}
+
+// exportWriter renders an export in one output format.
+type exportWriter interface {
+	Header(w io.Writer) error
+	Message(w io.Writer, msg exportedMessage) error
+	Footer(w io.Writer) error
+}
+
+// ExportChat streams the full history of a DM or group the user belongs to.
+// The format query param selects jsonl (default), html or txt.
+func ExportChat(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	chatType := c.Param("type")
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	var title string
+	var conversationID uint
+
+	switch chatType {
+	case "dm":
+		// SQL: SELECT * FROM users WHERE id = ? LIMIT 1;
+		var partner models.User
+		if err := initializers.DB.First(&partner, id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+
+		// A pair that never talked exports an empty history
+		// SQL: SELECT * FROM conversations WHERE direct_key = ? LIMIT 1;
+		var conv models.Conversation
+		initializers.DB.Where("direct_key = ?", models.DirectKey([]uint{user.Id, partner.Id})).First(&conv)
+
+		title = fmt.Sprintf("Direct messages between %s and %s", user.Username, partner.Username)
+		conversationID = conv.ID
+
+	case "group":
+		// SQL: SELECT * FROM groups WHERE id = ? LIMIT 1;
+		var group models.Group
+		if err := initializers.DB.First(&group, id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+			return
+		}
+
+		if !IsGroupMember(group.ID, user.Id) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this group"})
+			return
+		}
+
+		conv, err := GroupConversation(group.ID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+			return
+		}
+
+		title = fmt.Sprintf("Group %s", group.Name)
+		conversationID = conv.ID
+
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat type"})
+		return
+	}
+
+	streamExport(c, conversationID, title, fmt.Sprintf("%s-%d", chatType, id))
+}
+
+// ExportConversation streams the full history of a conversation the user is in.
+func ExportConversation(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	conv, ok := findMemberConversation(c, user)
+	if !ok {
+		return
+	}
+
+	title := fmt.Sprintf("Conversation %d", conv.ID)
+	if conv.GroupID != nil {
+		// SQL: SELECT * FROM groups WHERE id = ? LIMIT 1;
+		var group models.Group
+		initializers.DB.First(&group, *conv.GroupID)
+		title = fmt.Sprintf("Group %s", group.Name)
+	} else {
+		// SQL: SELECT users.username FROM conversation_members JOIN users ... WHERE conversation_id = ? ORDER BY username;
+		var usernames []string
+		initializers.DB.Model(&models.ConversationMember{}).
+			Joins("JOIN users ON users.id = conversation_members.user_id").
+			Where("conversation_members.conversation_id = ?", conv.ID).
+			Order("users.username").
+			Pluck("users.username", &usernames)
+		title = "Direct messages between " + strings.Join(usernames, ", ")
+	}
+
+	streamExport(c, conv.ID, title, fmt.Sprintf("conversation-%d", conv.ID))
+}
+
+// streamExport writes every message of a conversation in the format selected
+// by the format query param.
+func streamExport(c *gin.Context, conversationID uint, title, fileName string) {
+	// SQL:
+	// SELECT m.id, m.kind, m.event, m.event_data, m.sender_id, u.username, m.content, ..., <edits as JSON>, p.id AS poll_id
+	// FROM messages m
+	// JOIN users u ON u.id = m.sender_id
+	// LEFT JOIN polls p ON p.message_id = m.id
+	// WHERE m.conversation_id = ?
+	// ORDER BY m.created_at, m.id;
+	query := `
+		SELECT m.id, m.kind, m.event, m.event_data, m.sender_id, u.username AS sender_username, m.content, m.created_at, m.updated_at,
+			COALESCE((
+				SELECT json_agg(json_build_object('previous_content', e.previous_content, 'edited_at', e.edited_at) ORDER BY e.edited_at)
+				FROM message_edits e WHERE e.message_id = m.id
+			), '[]')::text AS edits,
+			p.id AS poll_id
+		FROM messages m
+		JOIN users u ON u.id = m.sender_id
+		LEFT JOIN polls p ON p.message_id = m.id
+		WHERE m.conversation_id = ?
+		ORDER BY m.created_at, m.id`
+
+	var writer exportWriter
+	var contentType, extension string
+	switch c.DefaultQuery("format", "jsonl") {
+	case "jsonl":
+		writer, contentType, extension = jsonLinesExport{}, "application/x-ndjson", "jsonl"
+	case "html":
+		writer, contentType, extension = htmlExport{title: title}, "text/html; charset=utf-8", "html"
+	case "txt":
+		writer, contentType, extension = textExport{title: title}, "text/plain; charset=utf-8", "txt"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export format"})
+		return
+	}
+
+	// Rows are read one at a time so the history is never held in memory at once
+	rows, err := initializers.DB.Raw(query, conversationID).Rows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export chat"})
+		return
+	}
+	defer rows.Close()
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, fileName, extension))
+	c.Status(http.StatusOK)
+
+	if err := writer.Header(c.Writer); err != nil {
+		return
+	}
+
+	count := 0
+	for rows.Next() {
+		var row struct {
+			ID             uint
+			Kind           string
+			Event          string
+			EventData      *string
+			SenderID       uint
+			SenderUsername string
+			Content        string
+			CreatedAt      time.Time
+			UpdatedAt      time.Time
+			Edits          string
+			PollID         *uint
+		}
+		if err := initializers.DB.ScanRows(rows, &row); err != nil {
+			return
+		}
+
+		msg := exportedMessage{
+			ID:             row.ID,
+			Kind:           row.Kind,
+			Event:          row.Event,
+			SenderID:       row.SenderID,
+			SenderUsername: row.SenderUsername,
+			Content:        row.Content,
+			CreatedAt:      row.CreatedAt,
+			UpdatedAt:      row.UpdatedAt,
+			PollID:         row.PollID,
+		}
+		json.Unmarshal([]byte(row.Edits), &msg.Edits)
+		if row.EventData != nil {
+			msg.Data = json.RawMessage(*row.EventData)
+		}
+
+		if err := writer.Message(c.Writer, msg); err != nil {
+			return
+		}
+
+		count++
+		if count%exportFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+	}
+
+	writer.Footer(c.Writer)
+	c.Writer.Flush()
+}
+
+// jsonLinesExport writes one JSON object per message.
+type jsonLinesExport struct{}
+
+func (jsonLinesExport) Header(w io.Writer) error { return nil }
+
+func (jsonLinesExport) Message(w io.Writer, msg exportedMessage) error {
+	if msg.Edits == nil {
+		msg.Edits = []exportedEdit{}
+	}
+	return json.NewEncoder(w).Encode(msg)
+}
+
+func (jsonLinesExport) Footer(w io.Writer) error { return nil }
+
+// textExport writes a plain text transcript.
+type textExport struct {
+	title string
+}
+
+func (e textExport) Header(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s\nExported %s\n\n", e.title, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+func (textExport) Message(w io.Writer, msg exportedMessage) error {
+	timestamp := msg.CreatedAt.UTC().Format("2006-01-02 15:04:05")
+	// System messages are announcements, not something the sender wrote
+	if msg.Kind == models.MessageSystem {
+		_, err := fmt.Fprintf(w, "[%s] * %s\n", timestamp, msg.Content)
+		return err
+	}
+
+	line := fmt.Sprintf("[%s] %s: %s", timestamp, msg.SenderUsername, msg.Content)
+	if len(msg.Edits) > 0 {
+		line += " (edited)"
+	}
+	if msg.PollID != nil {
+		line += fmt.Sprintf(" [poll #%d]", *msg.PollID)
+	}
+	if _, err := fmt.Fprintln(w, line); err != nil {
+		return err
+	}
+
+	for _, edit := range msg.Edits {
+		if _, err := fmt.Fprintf(w, "    before edit at %s: %s\n", edit.EditedAt.UTC().Format("2006-01-02 15:04:05"), edit.PreviousContent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (textExport) Footer(w io.Writer) error { return nil }
+
+// htmlExport writes a self-contained HTML transcript with inline styles.
+type htmlExport struct {
+	title string
+}
+
+func (e htmlExport) Header(w io.Writer) error {
+	_, err := fmt.Fprintf(w, `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>%[1]s</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; max-width: 800px; margin: 2em auto; color: #222; }
+.msg { padding: 0.5em 0; border-bottom: 1px solid #eee; }
+.sender { font-weight: bold; }
+.time { color: #888; font-size: 0.85em; margin-left: 0.5em; }
+.content { white-space: pre-wrap; margin-top: 0.25em; }
+.edits { color: #666; font-size: 0.85em; margin: 0.25em 0 0 1em; }
+.tag { background: #eef; border-radius: 3px; padding: 0 0.3em; font-size: 0.8em; }
+.system { color: #666; font-style: italic; }
+</style>
+</head>
+<body>
+<h1>%[1]s</h1>
+<p class="time">Exported %[2]s</p>
+`, html.EscapeString(e.title), time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+func (htmlExport) Message(w io.Writer, msg exportedMessage) error {
+	// System messages are announcements, not something the sender wrote
+	if msg.Kind == models.MessageSystem {
+		_, err := fmt.Fprintf(w, "<div class=\"msg system\" id=\"m%d\"><span class=\"time\">%s</span> %s</div>\n",
+			msg.ID,
+			msg.CreatedAt.UTC().Format("2006-01-02 15:04:05"),
+			html.EscapeString(msg.Content),
+		)
+		return err
+	}
+
+	tags := ""
+	if len(msg.Edits) > 0 {
+		tags += ` <span class="tag">edited</span>`
+	}
+	if msg.PollID != nil {
+		tags += fmt.Sprintf(` <span class="tag">poll #%d</span>`, *msg.PollID)
+	}
+
+	_, err := fmt.Fprintf(w, `<div class="msg" id="m%d"><span class="sender">%s</span><span class="time">%s</span>%s<div class="content">%s</div>`,
+		msg.ID,
+		html.EscapeString(msg.SenderUsername),
+		msg.CreatedAt.UTC().Format("2006-01-02 15:04:05"),
+		tags,
+		html.EscapeString(msg.Content),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, edit := range msg.Edits {
+		_, err := fmt.Fprintf(w, `<div class="edits">Before edit at %s: %s</div>`,
+			edit.EditedAt.UTC().Format("2006-01-02 15:04:05"),
+			html.EscapeString(edit.PreviousContent),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintln(w, `</div>`)
+	return err
+}
+
+func (htmlExport) Footer(w io.Writer) error {
+	_, err := fmt.Fprintln(w, "</body>\n</html>")
+	return err
+}
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"MessagingSystemBackend/internal/realtime"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDirectoryLimit = 20
+	maxDirectoryLimit     = 100
+)
+
+// ListGroupDirectory lists the public groups anyone can join. Archived
+// groups, groups awaiting deletion and community channels are left out.
+// Query params: q (matches the start of the name or description, or any
+// words of the name, description and topic), sort (members, the default, or
+// activity), page and limit.
+func ListGroupDirectory(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	q := strings.TrimSpace(c.Query("q"))
+	sort := c.DefaultQuery("sort", "members")
+	if sort != "members" && sort != "activity" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be members or activity"})
+		return
+	}
+	page, limit := parsePagination(c, defaultDirectoryLimit, maxDirectoryLimit)
+
+	var results []struct {
+		ID              uint
+		Name            string
+		Description     string
+		Topic           string
+		Color           string
+		AvatarUpdatedAt *time.Time
+		CreatedAt       time.Time
+		MemberCount     int64
+		LastActivityAt  *time.Time
+		IsMember        bool
+		Total           int64
+	}
+
+	// SQL:
+	// SELECT g.*, (SELECT COUNT(*) FROM group_members WHERE group_id = g.id) AS member_count,
+	//   last.created_at AS last_activity_at, EXISTS (<user is a member>) AS is_member, COUNT(*) OVER () AS total
+	// FROM groups g
+	// JOIN conversations c ON c.group_id = g.id
+	// LEFT JOIN LATERAL (SELECT created_at FROM messages WHERE conversation_id = c.id ORDER BY created_at DESC LIMIT 1) last ON true
+	// WHERE g.visibility = 'public' AND g.community_id IS NULL AND g.archived_at IS NULL AND g.purge_at IS NULL
+	//   AND ({q} = '' OR g.name ILIKE '{q}%' OR g.description ILIKE '{q}%' OR g.profile_tsv @@ websearch_to_tsquery('english', {q}))
+	// ORDER BY member_count DESC | last_activity_at DESC NULLS LAST, g.id
+	// LIMIT ? OFFSET ?;
+	order := "member_count DESC, g.id"
+	if sort == "activity" {
+		order = "last_activity_at DESC NULLS LAST, g.id"
+	}
+	prefix := likePrefix(q)
+	err := initializers.DB.Raw(`
+		SELECT g.id, g.name, g.description, g.topic, g.color, g.avatar_updated_at, g.created_at,
+			(SELECT COUNT(*) FROM group_members gm WHERE gm.group_id = g.id) AS member_count,
+			last.created_at AS last_activity_at,
+			EXISTS (SELECT 1 FROM group_members me WHERE me.group_id = g.id AND me.user_id = ?) AS is_member,
+			COUNT(*) OVER () AS total
+		FROM groups g
+		JOIN conversations c ON c.group_id = g.id
+		LEFT JOIN LATERAL (
+			SELECT m.created_at FROM messages m
+			WHERE m.conversation_id = c.id ORDER BY m.created_at DESC LIMIT 1
+		) last ON true
+		WHERE g.visibility = ? AND g.community_id IS NULL AND g.archived_at IS NULL AND g.purge_at IS NULL
+			AND (? = '' OR g.name ILIKE ? OR g.description ILIKE ? OR g.profile_tsv @@ websearch_to_tsquery('english', ?))
+		ORDER BY `+order+`
+		LIMIT ? OFFSET ?
+	`, user.Id, models.GroupPublic, q, prefix, prefix, q, limit, (page-1)*limit).Scan(&results).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not list groups"})
+		return
+	}
+
+	var total int64
+	resp := []gin.H{}
+	for _, r := range results {
+		total = r.Total
+		entry := groupProfileResponse(models.Group{
+			ID:              r.ID,
+			Name:            r.Name,
+			Description:     r.Description,
+			Topic:           r.Topic,
+			Visibility:      models.GroupPublic,
+			Color:           r.Color,
+			AvatarUpdatedAt: r.AvatarUpdatedAt,
+			CreatedAt:       r.CreatedAt,
+		})
+		delete(entry, "created_by")
+		entry["member_count"] = r.MemberCount
+		entry["last_activity_at"] = r.LastActivityAt
+		entry["is_member"] = r.IsMember
+		resp = append(resp, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groups": resp,
+		"page":   page,
+		"limit":  limit,
+		"total":  total,
+	})
+}
+
+// JoinPublicGroup adds the current user to a public group as a member. The
+// member limit applies as for any other addition.
+func JoinPublicGroup(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !group.IsPublic() {
+		// Private groups are not revealed to non-members
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := addGroupMemberTx(tx, group.ID, user.Id, models.RoleMember); err != nil {
+			return err
+		}
+		if err := recordMembership(tx, group.ID, user.Id, user.Id, models.MembershipJoinedPublic); err != nil {
+			return err
+		}
+
+		conv, err := groupConversationTx(tx, group.ID)
+		if err != nil {
+			return err
+		}
+		_, err = PostSystemMessage(tx, conv.ID, user.Id, memberJoinedEvent(user, user, models.MembershipJoinedPublic))
+		return err
+	})
+	if errors.Is(err, errAlreadyMember) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You are already a member of this group"})
+		return
+	}
+	if errors.Is(err, errBanned) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are banned from this group"})
+		return
+	}
+	if err != nil {
+		membershipError(c, err)
+		return
+	}
+
+	realtime.Publish(realtime.GroupTopic(group.ID), realtime.Event{Type: "member.joined", Data: gin.H{"group_id": group.ID, "user_id": user.Id}})
+	c.JSON(http.StatusOK, gin.H{"message": "You joined the group", "group_id": group.ID})
+}
+
+// likePrefix returns a LIKE pattern matching values that start with s, with
+// the LIKE wildcards in s escaped.
+func likePrefix(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return s + "%"
+}
@@ -0,0 +1,345 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"MessagingSystemBackend/internal/realtime"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errInvitationClosed is returned when an invitation was already answered or withdrawn.
+var errInvitationClosed = errors.New("invitation is no longer pending")
+
+// errAlreadyInvited is returned when the user already has a pending invitation to the group.
+var errAlreadyInvited = errors.New("user already has a pending invitation")
+
+// InviteToGroup invites a user to a group. Unlike AddGroupMember the user
+// only joins once they accept, so it works whatever their group add
+// preference is.
+func InviteToGroup(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapAddMembers, "You cannot invite members to this group") {
+		return
+	}
+
+	var body struct {
+		Username string `json:"username"`
+		IsAdmin  bool   `json:"is_admin"`
+	}
+	if err := c.Bind(&body); err != nil || body.Username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid username"})
+		return
+	}
+
+	// SQL: SELECT * FROM users WHERE username = ?;
+	var user models.User
+	if err := initializers.DB.Where("username = ?", body.Username).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User does not exist"})
+		return
+	}
+
+	if IsGroupMember(group.ID, user.Id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User already a member"})
+		return
+	}
+	if isBannedTx(initializers.DB, group.ID, user.Id) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "User is banned from this group"})
+		return
+	}
+
+	role := models.RoleMember
+	if body.IsAdmin {
+		if !Authorize(group.ID, currentUser.Id, models.CapManageRoles) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only an admin can invite admins"})
+			return
+		}
+		role = models.RoleAdmin
+	}
+
+	invitation := models.GroupInvitation{
+		GroupID:   group.ID,
+		UserID:    user.Id,
+		InvitedBy: currentUser.Id,
+		Role:      role,
+		Status:    models.InvitationPending,
+		CreatedAt: time.Now(),
+	}
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// The partial unique index allows only one pending invitation per user and group
+		// SQL: INSERT INTO group_invitations (group_id, user_id, invited_by, role, status, created_at) VALUES (?, ?, ?, ?, 'pending', ?)
+		//      ON CONFLICT DO NOTHING;
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&invitation)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errAlreadyInvited
+		}
+		return recordAudit(tx, group.ID, currentUser.Id, models.AuditInvitationSent, auditUser(user.Id), nil,
+			gin.H{"invitation_id": invitation.ID, "role": invitation.Role})
+	})
+	if errors.Is(err, errAlreadyInvited) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User already has a pending invitation to this group"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not send invitation"})
+		return
+	}
+
+	invitation.Group = group
+	invitation.Inviter = currentUser
+	realtime.Publish(realtime.UserTopic(user.Id), realtime.Event{Type: "invitation.received", Data: invitationResponse(invitation)})
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation sent", "invitation_id": invitation.ID})
+}
+
+// ListGroupInvitations returns the pending invitations of a group, newest first.
+func ListGroupInvitations(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapAddMembers, "You cannot see the invitations of this group") {
+		return
+	}
+
+	// SQL: SELECT * FROM group_invitations WHERE group_id = ? AND status = 'pending' ORDER BY created_at DESC;
+	//      SELECT * FROM users WHERE id IN (...);
+	var invitations []models.GroupInvitation
+	initializers.DB.Preload("User").Preload("Inviter").
+		Where("group_id = ? AND status = ?", group.ID, models.InvitationPending).
+		Order("created_at DESC").
+		Find(&invitations)
+
+	resp := []gin.H{}
+	for _, inv := range invitations {
+		resp = append(resp, gin.H{
+			"id":         inv.ID,
+			"user_id":    inv.UserID,
+			"username":   inv.User.Username,
+			"role":       inv.Role,
+			"invited_by": inv.Inviter.Username,
+			"created_at": inv.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// CancelGroupInvitation withdraws a pending invitation.
+func CancelGroupInvitation(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapAddMembers, "You cannot manage the invitations of this group") {
+		return
+	}
+
+	invitationID, err := strconv.Atoi(c.Param("invitationId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	// SQL: SELECT * FROM group_invitations WHERE id = ? AND group_id = ? LIMIT 1;
+	var invitation models.GroupInvitation
+	if err := initializers.DB.Where("group_id = ?", group.ID).First(&invitation, invitationID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// SQL: UPDATE group_invitations SET status = 'cancelled', responded_at = ? WHERE id = ? AND status = 'pending';
+		result := tx.Model(&invitation).Where("status = ?", models.InvitationPending).
+			Updates(map[string]any{"status": models.InvitationCancelled, "responded_at": time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvitationClosed
+		}
+		return recordAudit(tx, group.ID, currentUser.Id, models.AuditInvitationCancelled, auditUser(invitation.UserID),
+			gin.H{"invitation_id": invitation.ID, "role": invitation.Role}, nil)
+	})
+	if errors.Is(err, errInvitationClosed) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invitation is no longer pending"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not cancel invitation"})
+		return
+	}
+
+	realtime.Publish(realtime.UserTopic(invitation.UserID), realtime.Event{Type: "invitation.cancelled", Data: gin.H{"id": invitation.ID, "group_id": group.ID}})
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation cancelled"})
+}
+
+// ListMyInvitations returns the current user's pending group invitations, newest first.
+func ListMyInvitations(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	// SQL: SELECT * FROM group_invitations WHERE user_id = ? AND status = 'pending' ORDER BY created_at DESC;
+	//      SELECT * FROM groups WHERE id IN (...); SELECT * FROM users WHERE id IN (...);
+	var invitations []models.GroupInvitation
+	initializers.DB.Preload("Group").Preload("Inviter").
+		Where("user_id = ? AND status = ?", user.Id, models.InvitationPending).
+		Order("created_at DESC").
+		Find(&invitations)
+
+	resp := []gin.H{}
+	for _, inv := range invitations {
+		resp = append(resp, invitationResponse(inv))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// AcceptInvitation adds the current user to the group they were invited to,
+// subject to the group's member and admin limits.
+func AcceptInvitation(c *gin.Context) {
+	answerInvitation(c, models.InvitationAccepted)
+}
+
+// DeclineInvitation turns down a group invitation.
+func DeclineInvitation(c *gin.Context) {
+	answerInvitation(c, models.InvitationDeclined)
+}
+
+// answerInvitation closes the current user's pending invitation addressed by
+// the URL with the given status and tells the group.
+func answerInvitation(c *gin.Context, status string) {
+	user := c.MustGet("user").(models.User)
+
+	invitationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	var invitation models.GroupInvitation
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// SQL: SELECT * FROM group_invitations WHERE id = ? AND user_id = ? LIMIT 1 FOR UPDATE;
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", user.Id).First(&invitation, invitationID).Error
+		if err != nil {
+			return err
+		}
+		if invitation.Status != models.InvitationPending {
+			return errInvitationClosed
+		}
+
+		// SQL: UPDATE group_invitations SET status = ?, responded_at = ? WHERE id = ?;
+		err = tx.Model(&invitation).Updates(map[string]any{"status": status, "responded_at": time.Now()}).Error
+		if err != nil || status != models.InvitationAccepted {
+			return err
+		}
+
+		invitation.Role = grantableRoleTx(tx, invitation.GroupID, invitation.InvitedBy, invitation.Role)
+		if err := addGroupMemberTx(tx, invitation.GroupID, user.Id, invitation.Role); err != nil {
+			return err
+		}
+		if err := recordMembership(tx, invitation.GroupID, user.Id, invitation.InvitedBy, models.MembershipInviteAccepted); err != nil {
+			return err
+		}
+		if invitation.Role == models.RoleAdmin {
+			if err := recordMembership(tx, invitation.GroupID, user.Id, invitation.InvitedBy, models.MembershipPromoted); err != nil {
+				return err
+			}
+		}
+
+		conv, err := groupConversationTx(tx, invitation.GroupID)
+		if err != nil {
+			return err
+		}
+		_, err = PostSystemMessage(tx, conv.ID, user.Id, memberJoinedEvent(user, user, models.MembershipInviteAccepted))
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+	if errors.Is(err, errInvitationClosed) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invitation is no longer pending"})
+		return
+	}
+	if errors.Is(err, errAlreadyMember) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You are already a member of this group"})
+		return
+	}
+	if errors.Is(err, errBanned) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are banned from this group"})
+		return
+	}
+	if err != nil {
+		membershipError(c, err)
+		return
+	}
+
+	topic := realtime.GroupTopic(invitation.GroupID)
+	if status == models.InvitationAccepted {
+		realtime.Publish(topic, realtime.Event{Type: "member.joined", Data: gin.H{"group_id": invitation.GroupID, "user_id": user.Id}})
+		c.JSON(http.StatusOK, gin.H{"message": "You joined the group", "group_id": invitation.GroupID, "role": invitation.Role})
+		return
+	}
+	realtime.Publish(topic, realtime.Event{Type: "invitation.declined", Data: gin.H{"id": invitation.ID, "group_id": invitation.GroupID, "user_id": user.Id}})
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"})
+}
+
+// allowsDirectAdd reports whether the actor may add user to a group without
+// an invitation, according to the user's group add preference.
+func allowsDirectAdd(actorID uint, user models.User) bool {
+	switch user.GroupAddPolicy {
+	case models.GroupAddInvitation:
+		return false
+	case models.GroupAddContacts:
+		return isContact(user.Id, actorID)
+	default:
+		return true
+	}
+}
+
+// isContact reports whether the user has written to other in their
+// one-to-one conversation, which makes other one of their contacts.
+func isContact(userID uint, otherID uint) bool {
+	// SQL: SELECT COUNT(*) FROM messages JOIN conversations ON conversations.id = messages.conversation_id
+	//        WHERE conversations.direct_key = ? AND messages.sender_id = ? AND messages.kind = 'user';
+	var sent int64
+	initializers.DB.Model(&models.Message{}).
+		Joins("JOIN conversations ON conversations.id = messages.conversation_id").
+		Where("conversations.direct_key = ? AND messages.sender_id = ? AND messages.kind = ?",
+			models.DirectKey([]uint{otherID, userID}), userID, models.MessageUser).
+		Count(&sent)
+	return sent > 0
+}
+
+// invitationResponse returns an invitation as seen by the invitee.
+func invitationResponse(inv models.GroupInvitation) gin.H {
+	return gin.H{
+		"id":         inv.ID,
+		"group_id":   inv.GroupID,
+		"group_name": inv.Group.Name,
+		"role":       inv.Role,
+		"invited_by": inv.Inviter.Username,
+		"created_at": inv.CreatedAt,
+	}
+}
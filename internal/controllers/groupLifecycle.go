@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"MessagingSystemBackend/internal/realtime"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ArchiveGroup makes a group read-only: its history stays readable, but
+// nobody can post, edit, vote or change members, roles or settings until it is
+// unarchived.
+func ArchiveGroup(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapEditSettings, "You cannot archive this group") {
+		return
+	}
+
+	now := time.Now()
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// Announced before archiving, while the group still takes messages
+		conv, err := groupConversationTx(tx, group.ID)
+		if err != nil {
+			return err
+		}
+		if _, err := PostSystemMessage(tx, conv.ID, currentUser.Id, groupEvent(currentUser, models.SystemGroupArchived)); err != nil {
+			return err
+		}
+
+		// SQL: UPDATE groups SET archived_at = ?, archived_by = ? WHERE id = ?;
+		if err := tx.Model(&group).Updates(map[string]any{"archived_at": now, "archived_by": currentUser.Id}).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, group.ID, currentUser.Id, models.AuditGroupArchived, auditTarget{}, nil, gin.H{"archived_at": now})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not archive group"})
+		return
+	}
+
+	realtime.Publish(realtime.GroupTopic(group.ID), realtime.Event{Type: "group.archived", Data: gin.H{"group_id": group.ID, "archived_at": now}})
+	c.JSON(http.StatusOK, gin.H{"message": "Group archived"})
+}
+
+// UnarchiveGroup makes an archived group writable again. It needs the same
+// capability as archiving, which is checked even though the group is read-only.
+func UnarchiveGroup(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapEditSettings, "You cannot unarchive this group", evenIfReadOnly) {
+		return
+	}
+
+	if group.ArchivedAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Group is not archived"})
+		return
+	}
+
+	archivedAt, archivedBy := group.ArchivedAt, group.ArchivedBy
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// SQL: UPDATE groups SET archived_at = NULL, archived_by = NULL WHERE id = ?;
+		if err := tx.Model(&group).Updates(map[string]any{"archived_at": nil, "archived_by": nil}).Error; err != nil {
+			return err
+		}
+		err := recordAudit(tx, group.ID, currentUser.Id, models.AuditGroupUnarchived, auditTarget{},
+			gin.H{"archived_at": archivedAt, "archived_by": archivedBy}, nil)
+		if err != nil {
+			return err
+		}
+
+		conv, err := groupConversationTx(tx, group.ID)
+		if err != nil {
+			return err
+		}
+		_, err = PostSystemMessage(tx, conv.ID, currentUser.Id, groupEvent(currentUser, models.SystemGroupUnarchived))
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not unarchive group"})
+		return
+	}
+
+	realtime.Publish(realtime.GroupTopic(group.ID), realtime.Event{Type: "group.unarchived", Data: gin.H{"group_id": group.ID}})
+	c.JSON(http.StatusOK, gin.H{"message": "Group unarchived"})
+}
+
+// DeleteGroup schedules a group for deletion. Only the owner can delete a
+// group. It disappears for its members right away, but its history is only
+// purged once the grace period has passed; until then the owner can restore it.
+func DeleteGroup(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapDeleteGroup, "Only the group owner can delete the group", evenIfReadOnly) {
+		return
+	}
+
+	purgeAt := time.Now().Add(initializers.GroupDeletionGrace)
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		conv, err := groupConversationTx(tx, group.ID)
+		if err != nil {
+			return err
+		}
+		if _, err := PostSystemMessage(tx, conv.ID, currentUser.Id, groupEvent(currentUser, models.SystemGroupDeleted)); err != nil {
+			return err
+		}
+
+		// SQL: UPDATE groups SET purge_at = ?, delete_requested_by = ? WHERE id = ?;
+		if err := tx.Model(&group).Updates(map[string]any{"purge_at": purgeAt, "delete_requested_by": currentUser.Id}).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, group.ID, currentUser.Id, models.AuditGroupDeleted, auditTarget{}, nil, gin.H{"purge_at": purgeAt})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete group"})
+		return
+	}
+
+	realtime.Publish(realtime.GroupTopic(group.ID), realtime.Event{Type: "group.deleted", Data: gin.H{"group_id": group.ID, "purge_at": purgeAt}})
+	c.JSON(http.StatusOK, gin.H{"message": "Group deleted; it can be restored until purge_at", "purge_at": purgeAt})
+}
+
+// RestoreGroup brings back a deleted group before it is purged. Only its
+// owner can restore it; groups deleted because everyone left have no owner
+// and are purged.
+func RestoreGroup(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// Loaded directly because findGroup hides deleted groups
+	// SQL: SELECT * FROM groups WHERE id = ? LIMIT 1;
+	var group models.Group
+	if err := initializers.DB.First(&group, groupID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapDeleteGroup, "Only the group owner can restore the group", evenIfReadOnly) {
+		return
+	}
+
+	if group.PurgeAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Group is not deleted"})
+		return
+	}
+
+	purgeAt := group.PurgeAt
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// Past purge_at the group belongs to the purge job
+		// SQL: UPDATE groups SET purge_at = NULL, delete_requested_by = NULL WHERE id = ? AND purge_at > ?;
+		result := tx.Model(&group).Where("purge_at > ?", time.Now()).
+			Updates(map[string]any{"purge_at": nil, "delete_requested_by": nil})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := recordAudit(tx, group.ID, currentUser.Id, models.AuditGroupRestored, auditTarget{}, gin.H{"purge_at": purgeAt}, nil); err != nil {
+			return err
+		}
+
+		conv, err := groupConversationTx(tx, group.ID)
+		if err != nil {
+			return err
+		}
+		_, err = PostSystemMessage(tx, conv.ID, currentUser.Id, groupEvent(currentUser, models.SystemGroupRestored))
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusGone, gin.H{"error": "Group has already been purged"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not restore group"})
+		return
+	}
+
+	realtime.Publish(realtime.GroupTopic(group.ID), realtime.Event{Type: "group.restored", Data: gin.H{"group_id": group.ID}})
+	c.JSON(http.StatusOK, gin.H{"message": "Group restored"})
+}
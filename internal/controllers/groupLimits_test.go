@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const concurrentCallers = 20
+
+// hammer runs call once per index in parallel while sampling count, and
+// returns how many calls succeeded and the highest count seen at any time.
+func hammer(t *testing.T, n int, count func() int64, call func(i int) int) (succeeded int, highest int64) {
+	t.Helper()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		done     = make(chan struct{})
+		sampling = make(chan struct{})
+	)
+
+	go func() {
+		defer close(sampling)
+		for {
+			if seen := count(); seen > highest {
+				highest = seen
+			}
+			select {
+			case <-done:
+				return
+			default:
+			}
+		}
+	}()
+
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			if call(i) == http.StatusOK {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	close(done)
+	<-sampling
+	if seen := count(); seen > highest {
+		highest = seen
+	}
+	return succeeded, highest
+}
+
+func groupParams(group models.Group) gin.Params {
+	return gin.Params{{Key: "id", Value: strconv.Itoa(int(group.ID))}}
+}
+
+func TestAddGroupMemberConcurrentlyKeepsMemberCap(t *testing.T) {
+	requireTestDB(t)
+
+	const maxMembers = 5
+	owner := createTestUser(t)
+	group := createTestGroup(t, owner, maxMembers, 2)
+
+	users := make([]models.User, concurrentCallers)
+	for i := range users {
+		users[i] = createTestUser(t)
+	}
+
+	succeeded, highest := hammer(t, concurrentCallers,
+		func() int64 { return countMembers(t, group.ID) },
+		func(i int) int {
+			body := fmt.Sprintf(`{"username": %q}`, users[i].Username)
+			return callHandler(AddGroupMember, owner, groupParams(group), body).Code
+		})
+
+	if highest > maxMembers {
+		t.Errorf("group had %d members at once, cap is %d", highest, maxMembers)
+	}
+	if got := countMembers(t, group.ID); got != maxMembers {
+		t.Errorf("group ended with %d members, want %d", got, maxMembers)
+	}
+	if succeeded != maxMembers-1 {
+		t.Errorf("%d adds succeeded, want %d", succeeded, maxMembers-1)
+	}
+}
+
+func TestAddAdminConcurrentlyKeepsAdminCap(t *testing.T) {
+	requireTestDB(t)
+
+	const maxAdmins = 3
+	owner := createTestUser(t)
+	group := createTestGroup(t, owner, 100, maxAdmins)
+
+	// Half of them are already members and get promoted, the rest are added as admins
+	users := make([]models.User, concurrentCallers)
+	for i := range users {
+		users[i] = createTestUser(t)
+		if i%2 == 0 {
+			addTestMember(t, group, users[i])
+		}
+	}
+
+	succeeded, highest := hammer(t, concurrentCallers,
+		func() int64 { return countMembers(t, group.ID, models.RoleOwner, models.RoleAdmin) },
+		func(i int) int {
+			body := fmt.Sprintf(`{"username": %q}`, users[i].Username)
+			return callHandler(AddAdmin, owner, groupParams(group), body).Code
+		})
+
+	if highest > maxAdmins {
+		t.Errorf("group had %d admins at once, cap is %d", highest, maxAdmins)
+	}
+	if got := countMembers(t, group.ID, models.RoleOwner, models.RoleAdmin); got != maxAdmins {
+		t.Errorf("group ended with %d admins, want %d", got, maxAdmins)
+	}
+	if succeeded != maxAdmins-1 {
+		t.Errorf("%d promotions succeeded, want %d", succeeded, maxAdmins-1)
+	}
+}
+
+func TestPromoteToAdminConcurrentlyKeepsAdminCap(t *testing.T) {
+	requireTestDB(t)
+
+	const maxAdmins = 3
+	owner := createTestUser(t)
+	group := createTestGroup(t, owner, 100, maxAdmins)
+
+	users := make([]models.User, concurrentCallers)
+	for i := range users {
+		users[i] = createTestUser(t)
+		addTestMember(t, group, users[i])
+	}
+
+	succeeded, highest := hammer(t, concurrentCallers,
+		func() int64 { return countMembers(t, group.ID, models.RoleOwner, models.RoleAdmin) },
+		func(i int) int {
+			params := append(groupParams(group), gin.Param{Key: "userId", Value: strconv.Itoa(int(users[i].Id))})
+			return callHandler(SetMemberRole, owner, params, `{"role": "admin"}`).Code
+		})
+
+	if highest > maxAdmins {
+		t.Errorf("group had %d admins at once, cap is %d", highest, maxAdmins)
+	}
+	if got := countMembers(t, group.ID, models.RoleOwner, models.RoleAdmin); got != maxAdmins {
+		t.Errorf("group ended with %d admins, want %d", got, maxAdmins)
+	}
+	if succeeded != maxAdmins-1 {
+		t.Errorf("%d promotions succeeded, want %d", succeeded, maxAdmins-1)
+	}
+}
+
+func TestCreateGroupRollsBackWhenOwnerInsertFails(t *testing.T) {
+	requireTestDB(t)
+
+	// Fail every insert into group_members while the test runs
+	const callback = "test:fail_group_members"
+	err := initializers.DB.Callback().Create().Before("gorm:create").Register(callback, func(db *gorm.DB) {
+		if db.Statement.Schema != nil && db.Statement.Schema.Table == "group_members" {
+			db.AddError(errors.New("owner insert failed"))
+		}
+	})
+	if err != nil {
+		t.Fatalf("registering callback: %v", err)
+	}
+	defer initializers.DB.Callback().Create().Remove(callback)
+
+	owner := createTestUser(t)
+	name := uniqueName("group")
+	w := callHandler(CreateGroup, owner, nil, fmt.Sprintf(`{"name": %q}`, name))
+	if w.Code == http.StatusOK {
+		t.Fatalf("CreateGroup succeeded without an owner: %s", w.Body.String())
+	}
+
+	var groups int64
+	initializers.DB.Model(&models.Group{}).Where("name = ?", name).Count(&groups)
+	if groups != 0 {
+		t.Errorf("group %q was kept after its owner insert failed", name)
+	}
+}
@@ -0,0 +1,689 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"MessagingSystemBackend/internal/realtime"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// errNotGroupMember is returned when the member to act on is not in the group.
+	errNotGroupMember = errors.New("user is not a member of this group")
+	// errRoleNotAllowed is returned when a role change is above the actor's own role.
+	errRoleNotAllowed = errors.New("you cannot change this member's role")
+	// errOwnerRole is returned when a role change would add or remove the owner.
+	errOwnerRole = errors.New("the owner can only change through an ownership transfer")
+	// errSameRole is returned when the member already has the requested role.
+	errSameRole = errors.New("user already has this role")
+	// errAdminLimit is returned when a promotion would exceed the admin cap.
+	errAdminLimit = errors.New("group has reached its admin limit")
+	// errGroupReadOnly is returned when adding someone to, or changing roles in, an archived group or one awaiting deletion.
+	errGroupReadOnly = errors.New("group is read-only")
+	// errGroupFull is returned when an addition would exceed the member cap.
+	errGroupFull = errors.New("group has reached its member limit")
+	// errAlreadyMember is returned when adding someone who is already in the group.
+	errAlreadyMember = errors.New("user already a member")
+	// errAlreadyAdmin is returned when promoting someone who is already an admin.
+	errAlreadyAdmin = errors.New("user is already an admin")
+	// errBanned is returned when adding someone who is banned from the group.
+	errBanned = errors.New("user is banned from this group")
+	// errRemoveOwner is returned when someone else tries to remove the owner.
+	errRemoveOwner = errors.New("the group owner cannot be removed")
+	// errRemoveOutranked is returned when removing a member with a higher role.
+	errRemoveOutranked = errors.New("you cannot remove a member with a higher role")
+)
+
+// departure describes what happened when a member left or was removed.
+type departure struct {
+	Role      string       // The member's role when they left
+	Promoted  *models.User // Member promoted because the last admin left
+	NewOwner  *models.User // Member who took over because the owner left
+	Dissolved bool         // The group was scheduled for deletion because nobody was left
+}
+
+// RemoveGroupMember lets a member who may remove members remove someone with
+// the same or a lower role. The owner cannot be removed.
+func RemoveGroupMember(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapRemoveMembers, "You cannot remove members from this group") {
+		return
+	}
+
+	if uint(userID) == currentUser.Id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use leave to remove yourself from a group"})
+		return
+	}
+
+	// SQL: SELECT * FROM users WHERE id = ? LIMIT 1;
+	var target models.User
+	if err := initializers.DB.First(&target, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var result departure
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = removeGroupMemberTx(tx, group, target, currentUser, models.MembershipRemoved,
+			memberDepartedEvent(currentUser, target, models.MembershipRemoved, false))
+		if err != nil {
+			return err
+		}
+		return recordAudit(tx, group.ID, currentUser.Id, models.AuditMemberRemoved, auditUser(target.Id), gin.H{"role": result.Role}, nil)
+	})
+	if errors.Is(err, errNotGroupMember) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of this group"})
+		return
+	}
+	if errors.Is(err, errRemoveOwner) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The group owner cannot be removed"})
+		return
+	}
+	if errors.Is(err, errRemoveOutranked) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot remove a member with a higher role"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not remove member"})
+		return
+	}
+
+	publishDeparture(group.ID, "member.removed", target, result)
+	c.JSON(http.StatusOK, gin.H{"message": "User removed from group"})
+}
+
+// LeaveGroup removes the current user from a group. If they were the last
+// admin or the owner, the highest-ranked, longest-standing remaining member
+// takes their place (see heirOrder); if nobody is left, the group is
+// scheduled for deletion.
+func LeaveGroup(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	result, err := removeGroupMember(group, currentUser, currentUser, models.MembershipLeft,
+		memberDepartedEvent(currentUser, currentUser, models.MembershipLeft, false))
+	if errors.Is(err, errNotGroupMember) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not leave group"})
+		return
+	}
+
+	publishDeparture(group.ID, "member.left", currentUser, result)
+
+	resp := gin.H{"message": "You left the group"}
+	if result.Dissolved {
+		resp["message"] = "You left the group and it was deleted"
+	}
+	if result.Promoted != nil {
+		resp["promoted_user_id"] = result.Promoted.Id
+	}
+	if result.NewOwner != nil {
+		resp["new_owner_id"] = result.NewOwner.Id
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// removeGroupMember deletes a membership, records it in the membership history
+// as action and announces it in the group.
+func removeGroupMember(group models.Group, target models.User, actor models.User, action string, announcement systemEvent) (departure, error) {
+	var result departure
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = removeGroupMemberTx(tx, group, target, actor, action, announcement)
+		return err
+	})
+	return result, err
+}
+
+// removeGroupMemberTx is removeGroupMember inside a transaction. The group row
+// is locked so two last admins leaving at once cannot both skip succession.
+// Someone removing another member must outrank them, and nobody removes the
+// owner; errRemoveOwner and errRemoveOutranked report otherwise.
+func removeGroupMemberTx(tx *gorm.DB, group models.Group, target models.User, actor models.User, action string, announcement systemEvent) (departure, error) {
+	locked, member, err := lockMembershipTx(tx, group.ID, target.Id)
+	if err != nil {
+		return departure{}, err
+	}
+
+	// Checked under the lock so the roles cannot change before the removal
+	if actor.Id != target.Id {
+		actorRole, _ := groupRoleTx(tx, group.ID, actor.Id)
+		if member.Role == models.RoleOwner {
+			return departure{}, errRemoveOwner
+		}
+		if !outranks(actorRole, member.Role) {
+			return departure{}, errRemoveOutranked
+		}
+	}
+
+	return dropMemberTx(tx, locked, member, target, actor, action, announcement)
+}
+
+// removeChannelMemberTx removes a member from a channel on behalf of its
+// community. The community's roles decided who may do it, so the channel's
+// are not checked and channel owners are removed like anyone else.
+func removeChannelMemberTx(tx *gorm.DB, channel models.Group, target models.User, actor models.User, action string, announcement systemEvent) (departure, error) {
+	locked, member, err := lockMembershipTx(tx, channel.ID, target.Id)
+	if err != nil {
+		return departure{}, err
+	}
+	return dropMemberTx(tx, locked, member, target, actor, action, announcement)
+}
+
+// lockMembershipTx locks a group and loads a user's membership in it,
+// returning errNotGroupMember if there is none.
+func lockMembershipTx(tx *gorm.DB, groupID uint, userID uint) (models.Group, models.GroupMember, error) {
+	var member models.GroupMember
+
+	locked, err := lockGroup(tx, groupID)
+	if err != nil {
+		return locked, member, err
+	}
+
+	// SQL: SELECT * FROM group_members WHERE group_id = ? AND user_id = ? LIMIT 1;
+	if err := tx.Where("group_id = ? AND user_id = ?", groupID, userID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return locked, member, errNotGroupMember
+		}
+		return locked, member, err
+	}
+	return locked, member, nil
+}
+
+// dropMemberTx deletes a membership loaded by lockMembershipTx and handles the
+// succession, for removeGroupMemberTx and removeChannelMemberTx.
+func dropMemberTx(tx *gorm.DB, group models.Group, member models.GroupMember, target models.User, actor models.User, action string, announcement systemEvent) (departure, error) {
+	result := departure{Role: member.Role}
+
+	// Deleted by value so the hook also removes the conversation membership
+	// SQL: DELETE FROM group_members WHERE id = ?;
+	if err := tx.Delete(&member).Error; err != nil {
+		return result, err
+	}
+
+	// Offers to or from someone who is no longer in the group cannot be accepted
+	// SQL: UPDATE ownership_transfers SET status = 'cancelled' WHERE group_id = ? AND status = 'pending' AND (from_user_id = ? OR to_user_id = ?);
+	err := tx.Model(&models.OwnershipTransfer{}).
+		Where("group_id = ? AND status = ? AND (from_user_id = ? OR to_user_id = ?)", group.ID, models.TransferPending, target.Id, target.Id).
+		Updates(map[string]any{"status": models.TransferCancelled, "responded_at": time.Now()}).Error
+	if err != nil {
+		return result, err
+	}
+
+	// SQL: SELECT COUNT(*) FROM group_members WHERE group_id = ?;
+	var remaining int64
+	tx.Model(&models.GroupMember{}).Where("group_id = ?", group.ID).Count(&remaining)
+	if remaining == 0 {
+		result.Dissolved = true
+		// Like any deleted group, its history is kept until the purge job erases it
+		// SQL: UPDATE groups SET purge_at = ? WHERE id = ?;
+		return result, tx.Model(&group).Update("purge_at", time.Now().Add(initializers.GroupDeletionGrace)).Error
+	}
+
+	if err := recordMembership(tx, group.ID, target.Id, actor.Id, action); err != nil {
+		return result, err
+	}
+
+	conv, err := groupConversationTx(tx, group.ID)
+	if err != nil {
+		return result, err
+	}
+	if _, err := PostSystemMessage(tx, conv.ID, actor.Id, announcement); err != nil {
+		return result, err
+	}
+
+	switch member.Role {
+	case models.RoleOwner:
+		// The owner is gone: the highest-ranked, longest-standing member
+		// takes over. Read-only and muted members only inherit if nobody
+		// else is left, so the group always keeps an owner.
+		// SQL: SELECT * FROM group_members WHERE group_id = ? ORDER BY <heir order> LIMIT 1;
+		var heir models.GroupMember
+		err := tx.Preload("User").Where("group_id = ?", group.ID).Order(heirOrder()).First(&heir).Error
+		if err != nil {
+			return result, err
+		}
+		// SQL: UPDATE group_members SET role = 'owner' WHERE id = ?;
+		if err := tx.Model(&heir).Update("role", models.RoleOwner).Error; err != nil {
+			return result, err
+		}
+		result.NewOwner = &heir.User
+
+		if err := recordMembership(tx, group.ID, heir.UserID, actor.Id, models.MembershipBecameOwner); err != nil {
+			return result, err
+		}
+		_, err = PostSystemMessage(tx, conv.ID, actor.Id, ownerChangedEvent(actor, heir.User, nil))
+		return result, err
+
+	case models.RoleAdmin:
+		// SQL: SELECT COUNT(*) FROM group_members WHERE group_id = ? AND role IN ('owner', 'admin');
+		var admins int64
+		tx.Model(&models.GroupMember{}).Where("group_id = ? AND role IN ?", group.ID, []string{models.RoleOwner, models.RoleAdmin}).Count(&admins)
+		if admins > 0 {
+			return result, nil
+		}
+
+		// The last admin is gone: promote the highest-ranked, longest-standing
+		// member. Read-only and muted members are not promoted.
+		// SQL: SELECT * FROM group_members WHERE group_id = ? AND role <> 'read_only'
+		//        AND NOT EXISTS (<active mute>) ORDER BY <heir order> LIMIT 1;
+		var successor models.GroupMember
+		err := tx.Preload("User").
+			Where("group_id = ? AND role <> ?", group.ID, models.RoleReadOnly).
+			Where("NOT EXISTS (SELECT 1 FROM group_mutes gm WHERE gm.group_id = group_members.group_id AND gm.user_id = group_members.user_id AND gm.muted_until > ?)", time.Now()).
+			Order(heirOrder()).First(&successor).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+		previous := successor.Role
+		// SQL: UPDATE group_members SET role = 'admin' WHERE id = ?;
+		if err := tx.Model(&successor).Update("role", models.RoleAdmin).Error; err != nil {
+			return result, err
+		}
+		result.Promoted = &successor.User
+
+		if err := recordMembership(tx, group.ID, successor.UserID, actor.Id, models.MembershipPromoted); err != nil {
+			return result, err
+		}
+		_, err = PostSystemMessage(tx, conv.ID, actor.Id, roleChangedEvent(actor, successor.User, previous, models.RoleAdmin))
+		return result, err
+	}
+
+	return result, nil
+}
+
+// heirOrder orders the members of a group by who should succeed the owner or
+// the last admin: members who are neither read-only nor muted first, then by
+// role rank, then by how long they have been in the group.
+func heirOrder() clause.Expr {
+	rank := "CASE role"
+	vars := []any{models.RoleReadOnly, time.Now()}
+	for _, role := range models.Roles {
+		rank += " WHEN ? THEN ?"
+		vars = append(vars, role, models.RoleRank(role))
+	}
+	rank += " ELSE 0 END"
+
+	return clause.Expr{
+		SQL: "role = ?, " +
+			"EXISTS (SELECT 1 FROM group_mutes gm WHERE gm.group_id = group_members.group_id AND gm.user_id = group_members.user_id AND gm.muted_until > ?), " +
+			rank + " DESC, joined_at, id",
+		Vars: vars,
+	}
+}
+
+// publishDeparture notifies live subscribers of a group about a member leaving
+// and ends the member's own subscriptions once they got the event.
+func publishDeparture(groupID uint, eventType string, user models.User, result departure) {
+	if result.Dissolved {
+		realtime.Publish(realtime.GroupTopic(groupID), realtime.Event{Type: "group.deleted", Data: gin.H{"group_id": groupID}})
+		realtime.Kick(realtime.GroupTopic(groupID), user.Id)
+		return
+	}
+
+	realtime.Publish(realtime.GroupTopic(groupID), realtime.Event{Type: eventType, Data: gin.H{"group_id": groupID, "user_id": user.Id}})
+	realtime.Kick(realtime.GroupTopic(groupID), user.Id)
+	if result.Promoted != nil {
+		realtime.Publish(realtime.GroupTopic(groupID), realtime.Event{Type: "member.promoted", Data: gin.H{"group_id": groupID, "user_id": result.Promoted.Id}})
+	}
+	if result.NewOwner != nil {
+		realtime.Publish(realtime.GroupTopic(groupID), realtime.Event{Type: "owner.changed", Data: gin.H{"group_id": groupID, "user_id": result.NewOwner.Id}})
+	}
+}
+
+// DemoteAdmin turns an admin back into a regular member. Any admin may demote
+// another admin or themselves, but nobody can demote the owner.
+func DemoteAdmin(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapManageRoles, "Only an admin can demote admins") {
+		return
+	}
+
+	var body struct {
+		Username string `json:"username"`
+	}
+	if err := c.Bind(&body); err != nil || body.Username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username required"})
+		return
+	}
+
+	// SQL: SELECT * FROM users WHERE username = ?;
+	var target models.User
+	if err := initializers.DB.Where("username = ?", body.Username).First(&target).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	role, _ := GroupRole(group.ID, target.Id)
+	if role == models.RoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The group owner cannot be demoted; transfer ownership first"})
+		return
+	}
+	if role != models.RoleAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User is not an admin of this group"})
+		return
+	}
+
+	if err := changeMemberRole(group.ID, target, currentUser, models.RoleMember); err != nil {
+		roleChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Admin demoted"})
+}
+
+// SetMemberRole changes a member's role. The actor may only hand out roles up
+// to their own and only change members who do not outrank them; the owner
+// changes only through an ownership transfer.
+func SetMemberRole(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapManageRoles, "You cannot change roles in this group") {
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var body struct {
+		Role string `json:"role"`
+	}
+	if err := c.Bind(&body); err != nil || !models.IsValidRole(body.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+
+	// SQL: SELECT * FROM users WHERE id = ? LIMIT 1;
+	var target models.User
+	if err := initializers.DB.First(&target, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := changeMemberRole(group.ID, target, currentUser, body.Role); err != nil {
+		roleChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated", "user_id": target.Id, "role": body.Role})
+}
+
+// changeMemberRole gives a member a new role, records it in the membership
+// history and announces it in the group.
+func changeMemberRole(groupID uint, target models.User, actor models.User, role string) error {
+	var previous string
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		previous, err = changeMemberRoleTx(tx, groupID, target, actor, role)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	publishRoleChange(groupID, target.Id, previous, role)
+	return nil
+}
+
+// publishRoleChange notifies live subscribers of a group about a member's new role.
+func publishRoleChange(groupID uint, userID uint, previous string, role string) {
+	eventType := "member.role_changed"
+	switch {
+	case role == models.RoleAdmin:
+		eventType = "member.promoted"
+	case previous == models.RoleAdmin:
+		eventType = "member.demoted"
+	}
+	realtime.Publish(realtime.GroupTopic(groupID), realtime.Event{Type: eventType, Data: gin.H{"group_id": groupID, "user_id": userID, "role": role}})
+}
+
+// changeMemberRoleTx is changeMemberRole inside a transaction. It returns the
+// member's previous role. The group row is locked so two concurrent
+// promotions cannot both pass the admin cap.
+func changeMemberRoleTx(tx *gorm.DB, groupID uint, target models.User, actor models.User, role string) (string, error) {
+	if _, err := lockGroup(tx, groupID); err != nil {
+		return "", err
+	}
+
+	switch authorizeTx(tx, groupID, actor.Id, models.CapManageRoles) {
+	case granted:
+	case deniedReadOnly:
+		return "", errGroupReadOnly
+	default:
+		return "", errRoleNotAllowed
+	}
+	actorRole, _ := groupRoleTx(tx, groupID, actor.Id)
+
+	// SQL: SELECT * FROM group_members WHERE group_id = ? AND user_id = ? LIMIT 1;
+	var member models.GroupMember
+	if err := tx.Where("group_id = ? AND user_id = ?", groupID, target.Id).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", errNotGroupMember
+		}
+		return "", err
+	}
+	previous := member.Role
+
+	if role == models.RoleOwner || member.Role == models.RoleOwner {
+		return "", errOwnerRole
+	}
+	if !outranks(actorRole, member.Role) || !outranks(actorRole, role) {
+		return "", errRoleNotAllowed
+	}
+	if member.Role == role {
+		return "", errSameRole
+	}
+	if role == models.RoleAdmin && !canAddAdminTx(tx, groupID) {
+		return "", errAdminLimit
+	}
+
+	return previous, setMemberRoleTx(tx, member, target, actor, role)
+}
+
+// setMemberRoleTx gives a member a new role once the caller has checked it is
+// allowed, recording it in the membership history and the audit log and
+// announcing it in the group.
+func setMemberRoleTx(tx *gorm.DB, member models.GroupMember, target models.User, actor models.User, role string) error {
+	previous := member.Role
+
+	// SQL: UPDATE group_members SET role = ? WHERE id = ?;
+	if err := tx.Model(&member).Update("role", role).Error; err != nil {
+		return err
+	}
+
+	action := models.MembershipRoleChanged
+	switch {
+	case role == models.RoleAdmin:
+		action = models.MembershipPromoted
+	case previous == models.RoleAdmin:
+		action = models.MembershipDemoted
+	}
+	if err := recordMembership(tx, member.GroupID, target.Id, actor.Id, action); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, member.GroupID, actor.Id, models.AuditRoleChanged, auditUser(target.Id), gin.H{"role": previous}, gin.H{"role": role}); err != nil {
+		return err
+	}
+
+	conv, err := groupConversationTx(tx, member.GroupID)
+	if err != nil {
+		return err
+	}
+	_, err = PostSystemMessage(tx, conv.ID, actor.Id, roleChangedEvent(actor, target, previous, role))
+	return err
+}
+
+// roleChangeError writes the response for an error from changeMemberRole.
+func roleChangeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errNotGroupMember):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of this group"})
+	case errors.Is(err, errRoleNotAllowed), errors.Is(err, errOwnerRole):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, errGroupReadOnly):
+		c.JSON(http.StatusForbidden, gin.H{"error": "This group is archived or scheduled for deletion"})
+	case errors.Is(err, errSameRole), errors.Is(err, errAdminLimit):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not change role"})
+	}
+}
+
+// roleTitle describes a role in announcements.
+func roleTitle(role string) string {
+	switch role {
+	case models.RoleAdmin:
+		return "an admin"
+	case models.RoleModerator:
+		return "a moderator"
+	case models.RoleReadOnly:
+		return "read-only"
+	default:
+		return "a member"
+	}
+}
+
+// GetMembershipHistory returns every membership change of a group, newest first.
+func GetMembershipHistory(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !IsGroupMember(group.ID, currentUser.Id) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	page, limit := parsePagination(c, 50, 200)
+
+	var results []struct {
+		UserID        uint
+		Username      string
+		ActorID       uint
+		ActorUsername string
+		Action        string
+		CreatedAt     time.Time
+	}
+
+	// SQL: SELECT e.*, u.username, a.username FROM group_membership_events e
+	//      LEFT JOIN users u ON u.id = e.user_id LEFT JOIN users a ON a.id = e.actor_id
+	//      WHERE e.group_id = ? ORDER BY e.created_at DESC, e.id DESC LIMIT ? OFFSET ?;
+	initializers.DB.Raw(`
+		SELECT e.user_id, u.username, e.actor_id, a.username AS actor_username, e.action, e.created_at
+		FROM group_membership_events e
+		LEFT JOIN users u ON u.id = e.user_id
+		LEFT JOIN users a ON a.id = e.actor_id
+		WHERE e.group_id = ?
+		ORDER BY e.created_at DESC, e.id DESC
+		LIMIT ? OFFSET ?
+	`, group.ID, limit, (page-1)*limit).Scan(&results)
+
+	resp := []gin.H{}
+	for _, r := range results {
+		resp = append(resp, gin.H{
+			"user_id":        r.UserID,
+			"username":       r.Username,
+			"actor_id":       r.ActorID,
+			"actor_username": r.ActorUsername,
+			"action":         r.Action,
+			"created_at":     r.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// recordMembership appends a change to a group's membership history.
+func recordMembership(tx *gorm.DB, groupID uint, userID uint, actorID uint, action string) error {
+	event := models.GroupMembershipEvent{
+		GroupID:   groupID,
+		UserID:    userID,
+		ActorID:   actorID,
+		Action:    action,
+		CreatedAt: time.Now(),
+	}
+	// SQL: INSERT INTO group_membership_events (group_id, user_id, actor_id, action, created_at) VALUES (?, ?, ?, ?, ?);
+	return tx.Create(&event).Error
+}
+
+// lockGroup locks a group's row until the transaction ends. Everything that
+// counts members or admins before changing them takes this lock first, so
+// concurrent changes to one group run one after another.
+func lockGroup(tx *gorm.DB, groupID uint) (models.Group, error) {
+	var group models.Group
+	// SQL: SELECT * FROM groups WHERE id = ? FOR UPDATE;
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&group, groupID).Error
+	return group, err
+}
+
+// findGroup loads the group in the :id URL param, writing an error response
+// and returning false if it does not exist or is awaiting deletion.
+func findGroup(c *gin.Context) (models.Group, bool) {
+	var group models.Group
+
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return group, false
+	}
+
+	// SQL: SELECT * FROM groups WHERE id = ? LIMIT 1;
+	if err := initializers.DB.First(&group, groupID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return group, false
+	}
+
+	if group.PurgeAt != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "Group is scheduled for deletion"})
+		return group, false
+	}
+
+	return group, true
+}
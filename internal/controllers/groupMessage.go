@@ -27,30 +27,50 @@ func CreateGroup(c *gin.Context) {
 	user := c.MustGet("user").(models.User)
 
 	// Check if group name already exists
-	// SQL: SELECT * FROM groups WHERE name = ? LIMIT 1
+	// SQL: SELECT * FROM groups WHERE name = ? AND community_id IS NULL LIMIT 1
 	var existing models.Group
-	initializers.DB.Where("name = ?", body.Name).First(&existing)
+	initializers.DB.Where("name = ? AND community_id IS NULL", body.Name).First(&existing)
 	if existing.ID != 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Group name already taken"})
 		return
 	}
 
-	// Create the group
-	// SQL: INSERT INTO groups (name, created_by, created_at) VALUES (?, ?, ?)
+	// Create the group together with its owner so it never exists without one
 	group := models.Group{
 		Name:      body.Name,
 		CreatedBy: user.Id,
 		CreatedAt: time.Now(),
 	}
-	if err := initializers.DB.Create(&group).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not create group"})
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// SQL: INSERT INTO groups (name, created_by, created_at) VALUES (?, ?, ?)
+		if err := tx.Create(&group).Error; err != nil {
+			return err
+		}
+
+		// The creator owns the group
+		// SQL: INSERT INTO group_members (group_id, user_id, role, joined_at) VALUES (?, ?, 'owner', ?)
+		owner := models.GroupMember{GroupID: group.ID, UserID: user.Id, Role: models.RoleOwner, JoinedAt: group.CreatedAt}
+		if err := tx.Create(&owner).Error; err != nil {
+			return err
+		}
+		if err := recordMembership(tx, group.ID, user.Id, user.Id, models.MembershipAdded); err != nil {
+			return err
+		}
+
+		conv, err := groupConversationTx(tx, group.ID)
+		if err != nil {
+			return err
+		}
+		_, err = PostSystemMessage(tx, conv.ID, user.Id, groupCreatedEvent(user, group.Name))
+		return err
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// Another group took the name since the check above
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Group name already taken"})
 		return
 	}
-
-	// Promote the creator to admin
-	// (Assumes PromoteToAdmin internally performs an INSERT or UPDATE on group_members or similar table)
-	if err := PromoteToAdmin(group.ID, user.Id); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not create group"})
 		return
 	}
 
@@ -89,97 +109,153 @@ func SendGroupMessage(c *gin.Context) {
 
 	user := c.MustGet("user").(models.User)
 
-	// SQL: SELECT * FROM group_members WHERE group_id = ? AND user_id = ? LIMIT 1;
-	var member models.GroupMember
-	result := initializers.DB.Where("group_id = ? AND user_id = ?", group.ID, user.Id).First(&member)
-	if result.Error != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this group"})
+	if !requireCapability(c, group.ID, user.Id, models.CapPost, "You cannot post in this group") {
 		return
 	}
 
-	// Create the group message
-	msg := models.GroupMessage{
-		GroupID:   group.ID,
-		SenderID:  user.Id,
-		Content:   body.Content,
-		CreatedAt: time.Now(),
+	conv, err := GroupConversation(group.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+		return
 	}
 
-	// SQL: INSERT INTO group_messages (group_id, sender_id, content, created_at) VALUES (?, ?, ?, ?);
-	initializers.DB.Create(&msg)
+	// Save the message to the group's conversation
+	if _, ok := postGroupMessage(c, group.ID, conv.ID, user.Id, body.Content); !ok {
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Message sent"})
 }
 
-// CanAddGroupMember returns true if the group has fewer than 25 members.
-func CanAddGroupMember(groupID uint) bool {
+// canAddGroupMemberTx returns true if the group is below its member limit.
+// Channels have no limit of their own: their community's member limit bounds
+// them. Callers must hold the group row lock for the answer to stay true until they insert.
+func canAddGroupMemberTx(tx *gorm.DB, groupID uint) bool {
+	// SQL: SELECT * FROM groups WHERE id = ?;
+	var group models.Group
+	tx.First(&group, groupID)
+	if group.CommunityID != nil {
+		return true
+	}
+
 	var count int64
 	// SQL: SELECT COUNT(*) FROM group_members WHERE group_id = ?;
-	initializers.DB.Model(&models.GroupMember{}).Where("group_id = ?", groupID).Count(&count)
-	return count < 25
+	tx.Model(&models.GroupMember{}).Where("group_id = ?", groupID).Count(&count)
+	return int(count) < initializers.GroupLimitsFor(group).MaxMembers
 }
 
-// CanAddAdmin returns true if there are fewer than 2 admins in the group.
-func CanAddAdmin(groupID uint) bool {
+// canAddAdminTx returns true if the group is below its admin limit. The owner
+// counts as an admin; channels have no admin limit, since every community
+// admin is an admin of them. Callers must hold the group row lock.
+func canAddAdminTx(tx *gorm.DB, groupID uint) bool {
+	// SQL: SELECT * FROM groups WHERE id = ?;
+	var group models.Group
+	tx.First(&group, groupID)
+	if group.CommunityID != nil {
+		return true
+	}
+
 	var adminCount int64
-	// SQL: SELECT COUNT(*) FROM group_members WHERE group_id = ? AND is_admin = true;
-	initializers.DB.Model(&models.GroupMember{}).Where("group_id = ? AND is_admin = true", groupID).Count(&adminCount)
-	return adminCount < 2
+	// SQL: SELECT COUNT(*) FROM group_members WHERE group_id = ? AND role IN ('owner', 'admin');
+	tx.Model(&models.GroupMember{}).Where("group_id = ? AND role IN ?", groupID, []string{models.RoleOwner, models.RoleAdmin}).Count(&adminCount)
+	return int(adminCount) < initializers.GroupLimitsFor(group).MaxAdmins
 }
 
-// PromoteToAdmin promotes a user to admin if allowed.
-func PromoteToAdmin(groupID uint, userID uint) error {
-	var member models.GroupMember
-
-	err := initializers.DB.
-		Where("group_id = ? AND user_id = ?", groupID, userID).
-		First(&member).Error
-
+// addGroupMemberTx adds a user to a group with the given role, enforcing the
+// member and admin limits. Nobody joins a read-only group, and banned users
+// join no group. Channels only take members of their community, with at least
+// the role the community gives them. The group row is locked so concurrent additions
+// are counted one after another and cannot exceed the limits together.
+func addGroupMemberTx(tx *gorm.DB, groupID uint, userID uint, role string) error {
+	group, err := lockGroup(tx, groupID)
 	if err != nil {
-		// If not found, add user as admin if max admin count is not reached
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			if !CanAddAdmin(groupID) {
-				return fmt.Errorf("group already has 2 admins")
-			}
-
-			newMember := models.GroupMember{
-				GroupID: groupID,
-				UserID:  userID,
-				IsAdmin: true,
-			}
+		return err
+	}
+	if group.IsReadOnly() {
+		return errGroupReadOnly
+	}
+	if isBannedTx(tx, groupID, userID) {
+		return errBanned
+	}
 
-			return initializers.DB.Create(&newMember).Error
+	// SQL: SELECT COUNT(*) FROM group_members WHERE group_id = ? AND user_id = ?;
+	var existing int64
+	tx.Model(&models.GroupMember{}).Where("group_id = ? AND user_id = ?", groupID, userID).Count(&existing)
+	if existing > 0 {
+		return errAlreadyMember
+	}
+	if group.CommunityID != nil {
+		communityRole, ok := communityRoleTx(tx, *group.CommunityID, userID)
+		if !ok {
+			return errNotCommunityMember
+		}
+		if inherited := channelRole(communityRole); models.RoleRank(inherited) > models.RoleRank(role) {
+			role = inherited
 		}
+	}
+	if !canAddGroupMemberTx(tx, groupID) {
+		return errGroupFull
+	}
+	if role == models.RoleAdmin && !canAddAdminTx(tx, groupID) {
+		return errAdminLimit
+	}
+
+	member := models.GroupMember{
+		GroupID:  groupID,
+		UserID:   userID,
+		Role:     role,
+		JoinedAt: time.Now(), // Used to pick a successor when the last admin leaves
+	}
+	// SQL: INSERT INTO group_members (group_id, user_id, role, joined_at) VALUES (?, ?, ?, ?);
+	return tx.Create(&member).Error
+}
 
-		// Some other DB error
-		return fmt.Errorf("error checking membership: %v", err)
+// promoteToAdminTx promotes a user to admin if the admin limit allows it,
+// adding them to the group first if they are not a member. It returns the
+// user's role before the promotion, or "" if they were added to the group.
+func promoteToAdminTx(tx *gorm.DB, groupID uint, userID uint) (string, error) {
+	if _, err := lockGroup(tx, groupID); err != nil {
+		return "", err
 	}
 
-	if member.IsAdmin {
-		return fmt.Errorf("user is already an admin")
+	// SQL: SELECT * FROM group_members WHERE group_id = ? AND user_id = ? LIMIT 1;
+	var member models.GroupMember
+	err := tx.Where("group_id = ? AND user_id = ?", groupID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Not a member yet: add them as admin if both limits allow it
+		return "", addGroupMemberTx(tx, groupID, userID, models.RoleAdmin)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error checking membership: %v", err)
 	}
 
-	// Check max admin limit
-	if !CanAddAdmin(groupID) {
-		return fmt.Errorf("group already has 2 admins")
+	if member.Role == models.RoleOwner || member.Role == models.RoleAdmin {
+		return "", errAlreadyAdmin
+	}
+	if !canAddAdminTx(tx, groupID) {
+		return "", errAdminLimit
 	}
 
-	// Promote existing member to admin
-	member.IsAdmin = true
-	return initializers.DB.Save(&member).Error
+	// SQL: UPDATE group_members SET role = 'admin' WHERE id = ?;
+	return member.Role, tx.Model(&member).Update("role", models.RoleAdmin).Error
 }
 
-// IsGroupAdmin checks if a user is an admin of a group.
-func IsGroupAdmin(groupID uint, userID uint) bool {
+// IsGroupMember checks if a user belongs to a group. Groups awaiting
+// deletion have no members until they are restored.
+func IsGroupMember(groupID uint, userID uint) bool {
 	var gm models.GroupMember
-	// SQL: SELECT * FROM group_members WHERE group_id = ? AND user_id = ? AND is_admin = true LIMIT 1;
+	// SQL: SELECT group_members.* FROM group_members JOIN groups ON groups.id = group_members.group_id
+	//        WHERE group_members.group_id = ? AND group_members.user_id = ? AND groups.purge_at IS NULL LIMIT 1;
 	err := initializers.DB.
-		Where("group_id = ? AND user_id = ? AND is_admin = true", groupID, userID).
+		Joins("JOIN groups ON groups.id = group_members.group_id").
+		Where("group_members.group_id = ? AND group_members.user_id = ? AND groups.purge_at IS NULL", groupID, userID).
 		First(&gm).Error
 	return err == nil
 }
 
-// AddAdmin promotes another user to admin, if the requester is an admin.
+// AddAdmin promotes another user to admin, if the requester may manage roles.
+// Someone who is not a member yet is added only if their group add preference
+// allows it.
 func AddAdmin(c *gin.Context) {
 	groupIDParam := c.Param("id")
 	if groupIDParam == "" {
@@ -202,8 +278,7 @@ func AddAdmin(c *gin.Context) {
 
 	currentUser := c.MustGet("user").(models.User)
 
-	if !IsGroupAdmin(group.ID, currentUser.Id) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Only an admin can promote members"})
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapManageRoles, "Only an admin can promote members") {
 		return
 	}
 
@@ -222,25 +297,53 @@ func AddAdmin(c *gin.Context) {
 		return
 	}
 
-	if IsGroupAdmin(group.ID, targetUser.Id) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User is already an admin"})
+	// Promoting a member is fine, but adding someone new needs their consent
+	if !allowsDirectAdd(currentUser.Id, targetUser) && !IsGroupMember(group.ID, targetUser.Id) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This user can only be invited to groups; send an invitation instead"})
 		return
 	}
 
-	if !CanAddAdmin(group.ID) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Group already has 2 admins"})
-		return
-	}
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		previous, err := promoteToAdminTx(tx, group.ID, targetUser.Id)
+		if err != nil {
+			return err
+		}
+		event := roleChangedEvent(currentUser, targetUser, previous, models.RoleAdmin)
+		if previous == "" {
+			if err := recordMembership(tx, group.ID, targetUser.Id, currentUser.Id, models.MembershipAdded); err != nil {
+				return err
+			}
+			if err := recordAudit(tx, group.ID, currentUser.Id, models.AuditMemberAdded, auditUser(targetUser.Id), nil, gin.H{"role": models.RoleAdmin}); err != nil {
+				return err
+			}
+			event = memberAddedEvent(currentUser, targetUser, models.RoleAdmin)
+		} else {
+			err := recordAudit(tx, group.ID, currentUser.Id, models.AuditRoleChanged, auditUser(targetUser.Id), gin.H{"role": previous}, gin.H{"role": models.RoleAdmin})
+			if err != nil {
+				return err
+			}
+		}
+		if err := recordMembership(tx, group.ID, targetUser.Id, currentUser.Id, models.MembershipPromoted); err != nil {
+			return err
+		}
 
-	if err := PromoteToAdmin(group.ID, targetUser.Id); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		conv, err := groupConversationTx(tx, group.ID)
+		if err != nil {
+			return err
+		}
+		_, err = PostSystemMessage(tx, conv.ID, currentUser.Id, event)
+		return err
+	})
+	if err != nil {
+		membershipError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "User promoted to admin"})
 }
 
-// AddGroupMember adds a new member to a group, optionally as an admin.
+// AddGroupMember adds a new member to a group, optionally as an admin, if
+// their group add preference allows it. Otherwise they need an invitation.
 func AddGroupMember(c *gin.Context) {
 	groupIDParam := c.Param("id")
 	groupID, err := strconv.ParseUint(groupIDParam, 10, 64)
@@ -258,13 +361,7 @@ func AddGroupMember(c *gin.Context) {
 
 	currentUser := c.MustGet("user").(models.User)
 
-	if !IsGroupAdmin(group.ID, currentUser.Id) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Only admins can add members to this group"})
-		return
-	}
-
-	if !CanAddGroupMember(group.ID) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Group already has 25 members"})
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapAddMembers, "You cannot add members to this group") {
 		return
 	}
 
@@ -284,41 +381,73 @@ func AddGroupMember(c *gin.Context) {
 		return
 	}
 
-	// SQL: SELECT * FROM group_members WHERE group_id = ? AND user_id = ?;
-	var existing models.GroupMember
-	initializers.DB.Where("group_id = ? AND user_id = ?", group.ID, user.Id).First(&existing)
-	if existing.ID != 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User already a member"})
+	if !allowsDirectAdd(currentUser.Id, user) && !IsGroupMember(group.ID, user.Id) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This user can only be invited to groups; send an invitation instead"})
 		return
 	}
 
 	// Add as admin or normal member
+	role := models.RoleMember
 	if body.IsAdmin {
-		if err := PromoteToAdmin(group.ID, user.Id); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if !Authorize(group.ID, currentUser.Id, models.CapManageRoles) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only an admin can add admins"})
 			return
 		}
-	} else {
-		member := models.GroupMember{
-			UserID:   user.Id,
-			GroupID:  group.ID,
-			IsAdmin:  false,
-			JoinedAt: time.Now(),
-		}
-		// SQL: INSERT INTO group_members (...) VALUES (...);
-		initializers.DB.Create(&member)
+		role = models.RoleAdmin
+	}
+
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		_, err := addMemberTx(tx, group.ID, user, currentUser, role)
+		return err
+	})
+	if err != nil {
+		membershipError(c, err)
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "User added to group"})
 }
 
+// addMemberTx adds a user to a group on behalf of actor, records it in the
+// membership history and the audit log and announces it in the group. It
+// returns the role the user got, which in a channel may be higher than the
+// one asked for.
+func addMemberTx(tx *gorm.DB, groupID uint, user models.User, actor models.User, role string) (string, error) {
+	if err := addGroupMemberTx(tx, groupID, user.Id, role); err != nil {
+		return "", err
+	}
+	role, _ = groupRoleTx(tx, groupID, user.Id)
+	if err := recordMembership(tx, groupID, user.Id, actor.Id, models.MembershipAdded); err != nil {
+		return "", err
+	}
+	if err := recordAudit(tx, groupID, actor.Id, models.AuditMemberAdded, auditUser(user.Id), nil, gin.H{"role": role}); err != nil {
+		return "", err
+	}
+	if role == models.RoleAdmin {
+		if err := recordMembership(tx, groupID, user.Id, actor.Id, models.MembershipPromoted); err != nil {
+			return "", err
+		}
+	}
+
+	conv, err := groupConversationTx(tx, groupID)
+	if err != nil {
+		return "", err
+	}
+	_, err = PostSystemMessage(tx, conv.ID, actor.Id, memberAddedEvent(actor, user, role))
+	return role, err
+}
+
 // GetGroupMessage fetches a group message by its ID.
 func GetGroupMessage(c *gin.Context) {
-	msgID := c.Param("id")
-	var msg models.GroupMessage
-
-	// SQL: SELECT * FROM group_messages WHERE id = ?;
-	if err := initializers.DB.First(&msg, msgID).Error; err != nil {
+	msgID := legacyMessageID(models.ConversationGroup, c.Param("id"))
+	var msg models.Message
+
+	// SQL: SELECT messages.* FROM messages JOIN conversations ON conversations.id = messages.conversation_id
+	//        WHERE messages.id = ? AND conversations.kind = 'group';
+	err := initializers.DB.Joins("JOIN conversations ON conversations.id = messages.conversation_id").
+		Where("conversations.kind = ?", models.ConversationGroup).
+		First(&msg, msgID).Error
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
 		return
 	}
@@ -331,3 +460,25 @@ func GetGroupMessage(c *gin.Context) {
 		"updated_at": msg.UpdatedAt.UTC(), // 👈 ensures UTC
 	})
 }
+
+// membershipError writes the response for an error from adding or promoting a member.
+func membershipError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errAlreadyMember):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User already a member"})
+	case errors.Is(err, errAlreadyAdmin):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User is already an admin"})
+	case errors.Is(err, errGroupFull):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Group has reached its member limit"})
+	case errors.Is(err, errAdminLimit):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Group has reached its admin limit"})
+	case errors.Is(err, errGroupReadOnly):
+		c.JSON(http.StatusForbidden, gin.H{"error": "This group is archived or scheduled for deletion"})
+	case errors.Is(err, errBanned):
+		c.JSON(http.StatusForbidden, gin.H{"error": "User is banned from this group"})
+	case errors.Is(err, errNotCommunityMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": "User is not a member of this channel's community"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update membership"})
+	}
+}
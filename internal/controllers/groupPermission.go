@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"MessagingSystemBackend/internal/realtime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetGroupPermissions returns what every role may do in a group, with the
+// group's overrides applied, and the current user's role.
+func GetGroupPermissions(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	role, ok := GroupRole(group.ID, currentUser.Id)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"group_id":    group.ID,
+		"role":        role,
+		"permissions": EffectivePermissions(group.ID),
+	})
+}
+
+// SetGroupPermission grants or revokes a capability for a role in a group.
+// Sending a null allowed resets the capability to the role's default. The
+// owner's capabilities cannot be changed, and only a higher role can change a
+// role's capabilities, so nobody can grant their own role more.
+func SetGroupPermission(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapEditSettings, "You cannot change the settings of this group") {
+		return
+	}
+
+	var body struct {
+		Role       string `json:"role"`
+		Capability string `json:"capability"`
+		Allowed    *bool  `json:"allowed"`
+	}
+	if err := c.Bind(&body); err != nil || !models.IsValidRole(body.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+	if !models.IsConfigurableCapability(body.Capability) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid capability"})
+		return
+	}
+
+	actorRole, _ := GroupRole(group.ID, currentUser.Id)
+	if body.Role == models.RoleOwner || models.RoleRank(actorRole) <= models.RoleRank(body.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot change the permissions of this role"})
+		return
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// nil means the role used the default for this capability
+		var previous *bool
+		var existing models.GroupPermissionOverride
+		// SQL: SELECT * FROM group_permission_overrides WHERE group_id = ? AND role = ? AND capability = ? LIMIT 1;
+		if tx.Where("group_id = ? AND role = ? AND capability = ?", group.ID, body.Role, body.Capability).First(&existing).Error == nil {
+			previous = &existing.Allowed
+		}
+
+		var err error
+		if body.Allowed == nil {
+			// SQL: DELETE FROM group_permission_overrides WHERE group_id = ? AND role = ? AND capability = ?;
+			err = tx.Where("group_id = ? AND role = ? AND capability = ?", group.ID, body.Role, body.Capability).
+				Delete(&models.GroupPermissionOverride{}).Error
+		} else {
+			override := models.GroupPermissionOverride{GroupID: group.ID, Role: body.Role, Capability: body.Capability, Allowed: *body.Allowed}
+			// SQL: INSERT INTO group_permission_overrides (group_id, role, capability, allowed) VALUES (?, ?, ?, ?)
+			//      ON CONFLICT (group_id, role, capability) DO UPDATE SET allowed = EXCLUDED.allowed;
+			err = tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "group_id"}, {Name: "role"}, {Name: "capability"}},
+				DoUpdates: clause.AssignmentColumns([]string{"allowed"}),
+			}).Create(&override).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(tx, group.ID, currentUser.Id, models.AuditPermissionChanged, auditTarget{},
+			gin.H{"role": body.Role, "capability": body.Capability, "allowed": previous},
+			gin.H{"role": body.Role, "capability": body.Capability, "allowed": body.Allowed})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update permissions"})
+		return
+	}
+
+	permissions := EffectivePermissions(group.ID)
+	realtime.Publish(realtime.GroupTopic(group.ID), realtime.Event{Type: "permissions.updated", Data: gin.H{"group_id": group.ID, "permissions": permissions}})
+	c.JSON(http.StatusOK, gin.H{"message": "Permissions updated", "permissions": permissions})
+}
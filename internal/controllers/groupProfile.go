@@ -0,0 +1,383 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"MessagingSystemBackend/internal/realtime"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Group profile limits
+const (
+	maxGroupNameLength        = 255
+	maxGroupTopicLength       = 255
+	maxGroupDescriptionLength = 2000
+	maxGroupAvatarBytes       = 1 << 20
+)
+
+// Image types accepted as group avatars
+var groupAvatarTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+var groupColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// errGroupNameTaken is returned when a group is renamed to the name of another group.
+var errGroupNameTaken = errors.New("group name already taken")
+
+// GetGroupInfo returns a group's profile and its members with their roles.
+// Public groups can be looked at by anyone, private ones only by members.
+func GetGroupInfo(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findVisibleGroup(c, currentUser)
+	if !ok {
+		return
+	}
+
+	// SQL: SELECT * FROM group_members WHERE group_id = ? ORDER BY joined_at; SELECT * FROM users WHERE id IN (...);
+	var members []models.GroupMember
+	initializers.DB.Preload("User").Where("group_id = ?", group.ID).Order("joined_at").Find(&members)
+
+	memberList := []gin.H{}
+	for _, m := range members {
+		memberList = append(memberList, gin.H{
+			"user_id":   m.UserID,
+			"username":  m.User.Username,
+			"role":      m.Role,
+			"joined_at": m.JoinedAt,
+		})
+	}
+
+	resp := groupProfileResponse(group)
+	resp["member_count"] = len(members)
+	resp["members"] = memberList
+	c.JSON(http.StatusOK, resp)
+}
+
+// UpdateGroupProfile changes a group's name, description, topic, visibility
+// or color. Each field is optional, and every change is announced in the chat.
+func UpdateGroupProfile(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapEditSettings, "You cannot edit the profile of this group") {
+		return
+	}
+
+	var body struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		Topic       *string `json:"topic"`
+		Visibility  *string `json:"visibility"`
+		Color       *string `json:"color"`
+	}
+	if err := c.Bind(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group profile"})
+		return
+	}
+
+	updates := map[string]any{}
+	if body.Name != nil {
+		name := strings.TrimSpace(*body.Name)
+		if name == "" || len(name) > maxGroupNameLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group name"})
+			return
+		}
+		updates["name"] = name
+	}
+	if body.Description != nil {
+		if len(*body.Description) > maxGroupDescriptionLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Description can be at most %d characters", maxGroupDescriptionLength)})
+			return
+		}
+		updates["description"] = strings.TrimSpace(*body.Description)
+	}
+	if body.Topic != nil {
+		if len(*body.Topic) > maxGroupTopicLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Topic can be at most %d characters", maxGroupTopicLength)})
+			return
+		}
+		updates["topic"] = strings.TrimSpace(*body.Topic)
+	}
+	if body.Visibility != nil {
+		if *body.Visibility != models.GroupPrivate && *body.Visibility != models.GroupPublic {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "visibility must be private or public"})
+			return
+		}
+		if *body.Visibility == models.GroupPublic && group.PrivateChannel {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A private channel cannot be made public"})
+			return
+		}
+		updates["visibility"] = *body.Visibility
+	}
+	if body.Color != nil {
+		if *body.Color != "" && !groupColorPattern.MatchString(*body.Color) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "color must look like #rrggbb, or be empty for the default"})
+			return
+		}
+		updates["color"] = strings.ToLower(*body.Color)
+	}
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// Locked so concurrent edits announce what each one actually changed
+		before, err := lockGroup(tx, group.ID)
+		if err != nil {
+			return err
+		}
+
+		// The unique index on name settles concurrent renames to the same name
+		// SQL: UPDATE groups SET name = ?, description = ?, topic = ?, visibility = ?, color = ? WHERE id = ?;
+		err = tx.Model(&group).Updates(updates).Error
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return errGroupNameTaken
+		}
+		if err != nil {
+			return err
+		}
+		// SQL: SELECT * FROM groups WHERE id = ?;
+		if err := tx.First(&group, group.ID).Error; err != nil {
+			return err
+		}
+
+		conv, err := groupConversationTx(tx, group.ID)
+		if err != nil {
+			return err
+		}
+		for _, event := range profileChangedEvents(currentUser, before, group) {
+			if _, err := PostSystemMessage(tx, conv.ID, currentUser.Id, event); err != nil {
+				return err
+			}
+		}
+		return recordAudit(tx, group.ID, currentUser.Id, models.AuditProfileChanged, auditTarget{},
+			groupAuditFields(before, updates), groupAuditFields(group, updates))
+	})
+	if errors.Is(err, errGroupNameTaken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Group name already taken"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update group profile"})
+		return
+	}
+
+	resp := groupProfileResponse(group)
+	realtime.Publish(realtime.GroupTopic(group.ID), realtime.Event{Type: "group.updated", Data: resp})
+	c.JSON(http.StatusOK, resp)
+}
+
+// UploadGroupAvatar sets a group's picture from an uploaded image (multipart
+// field avatar; PNG, JPEG, GIF or WebP).
+func UploadGroupAvatar(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapEditSettings, "You cannot edit the profile of this group") {
+		return
+	}
+
+	header, err := c.FormFile("avatar")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Avatar image required"})
+		return
+	}
+	if header.Size > maxGroupAvatarBytes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Avatar can be at most %d KB", maxGroupAvatarBytes/1024)})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read avatar image"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxGroupAvatarBytes+1))
+	if err != nil || len(data) > maxGroupAvatarBytes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read avatar image"})
+		return
+	}
+
+	// Trust the content rather than the client's declared type
+	contentType := http.DetectContentType(data)
+	if !groupAvatarTypes[contentType] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Avatar must be a PNG, JPEG, GIF or WebP image"})
+		return
+	}
+
+	now := time.Now()
+	previous := group.AvatarUpdatedAt
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		avatar := models.GroupAvatar{GroupID: group.ID, ContentType: contentType, Data: data, UpdatedAt: now}
+		// SQL: INSERT INTO group_avatars (group_id, content_type, data, updated_at) VALUES (?, ?, ?, ?)
+		//      ON CONFLICT (group_id) DO UPDATE SET content_type = excluded.content_type, data = excluded.data, updated_at = excluded.updated_at;
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "group_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"content_type", "data", "updated_at"}),
+		}).Create(&avatar).Error
+		if err != nil {
+			return err
+		}
+		// SQL: UPDATE groups SET avatar_updated_at = ? WHERE id = ?;
+		if err := tx.Model(&group).Update("avatar_updated_at", now).Error; err != nil {
+			return err
+		}
+		err = recordAudit(tx, group.ID, currentUser.Id, models.AuditAvatarChanged, auditTarget{},
+			gin.H{"avatar_updated_at": previous}, gin.H{"avatar_updated_at": now, "content_type": contentType})
+		if err != nil {
+			return err
+		}
+
+		conv, err := groupConversationTx(tx, group.ID)
+		if err != nil {
+			return err
+		}
+		_, err = PostSystemMessage(tx, conv.ID, currentUser.Id, groupEvent(currentUser, models.SystemAvatarChanged))
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update avatar"})
+		return
+	}
+	group.AvatarUpdatedAt = &now
+
+	resp := groupProfileResponse(group)
+	realtime.Publish(realtime.GroupTopic(group.ID), realtime.Event{Type: "group.updated", Data: resp})
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteGroupAvatar removes a group's picture.
+func DeleteGroupAvatar(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapEditSettings, "You cannot edit the profile of this group") {
+		return
+	}
+
+	if group.AvatarUpdatedAt == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group has no avatar"})
+		return
+	}
+
+	previous := group.AvatarUpdatedAt
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// SQL: DELETE FROM group_avatars WHERE group_id = ?;
+		if err := tx.Delete(&models.GroupAvatar{}, group.ID).Error; err != nil {
+			return err
+		}
+		// SQL: UPDATE groups SET avatar_updated_at = NULL WHERE id = ?;
+		if err := tx.Model(&group).Update("avatar_updated_at", nil).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, group.ID, currentUser.Id, models.AuditAvatarChanged, auditTarget{}, gin.H{"avatar_updated_at": previous}, nil); err != nil {
+			return err
+		}
+
+		conv, err := groupConversationTx(tx, group.ID)
+		if err != nil {
+			return err
+		}
+		_, err = PostSystemMessage(tx, conv.ID, currentUser.Id, groupEvent(currentUser, models.SystemAvatarRemoved))
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not remove avatar"})
+		return
+	}
+	group.AvatarUpdatedAt = nil
+
+	resp := groupProfileResponse(group)
+	realtime.Publish(realtime.GroupTopic(group.ID), realtime.Event{Type: "group.updated", Data: resp})
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetGroupAvatar serves a group's picture to anyone who may see the group.
+func GetGroupAvatar(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findVisibleGroup(c, currentUser)
+	if !ok {
+		return
+	}
+
+	// SQL: SELECT * FROM group_avatars WHERE group_id = ? LIMIT 1;
+	var avatar models.GroupAvatar
+	if err := initializers.DB.Where("group_id = ?", group.ID).First(&avatar).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group has no avatar"})
+		return
+	}
+
+	c.Header("Last-Modified", avatar.UpdatedAt.UTC().Format(http.TimeFormat))
+	c.Data(http.StatusOK, avatar.ContentType, avatar.Data)
+}
+
+// findVisibleGroup loads the group in the :id URL param, writing an error
+// response and returning false unless it exists and the user may see it.
+func findVisibleGroup(c *gin.Context, user models.User) (models.Group, bool) {
+	group, ok := findGroup(c)
+	if !ok {
+		return group, false
+	}
+
+	if !group.IsPublic() && !IsGroupMember(group.ID, user.Id) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this group"})
+		return group, false
+	}
+
+	return group, true
+}
+
+// groupProfileResponse returns the profile fields of a group sent to clients.
+func groupProfileResponse(group models.Group) gin.H {
+	resp := gin.H{
+		"id":          group.ID,
+		"name":        group.Name,
+		"description": group.Description,
+		"topic":       group.Topic,
+		"visibility":  group.Visibility,
+		"color":       group.Color,
+		"avatar_url":  nil,
+		"created_by":  group.CreatedBy,
+		"created_at":  group.CreatedAt,
+	}
+	if group.CommunityID != nil {
+		resp["community_id"] = *group.CommunityID
+		resp["private_channel"] = group.PrivateChannel
+	}
+	if group.AvatarUpdatedAt != nil {
+		// The timestamp changes the URL whenever the picture does, so clients can cache it
+		resp["avatar_url"] = fmt.Sprintf("/groups/%d/avatar?v=%d", group.ID, group.AvatarUpdatedAt.Unix())
+	}
+	return resp
+}
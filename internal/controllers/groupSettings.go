@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"MessagingSystemBackend/internal/realtime"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errSettingsTooLow is returned when a new limit is below what the group already has.
+var errSettingsTooLow = errors.New("limit is below the group's current size")
+
+// GetGroupSettings returns the settings of a group: the limits it uses, next
+// to the server defaults and platform maximums they are chosen from, whether
+// it accepts join requests, who may post and its slow mode.
+func GetGroupSettings(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !IsGroupMember(group.ID, currentUser.Id) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, groupSettingsResponse(group))
+}
+
+// UpdateGroupSettings changes a group's limits, whether it accepts join
+// requests, who may post and its slow mode. Each field is optional; a 0 limit
+// resets it to the server default, and a 0 slow_mode_seconds turns slow mode
+// off. Limits cannot exceed the platform maximums or drop below what the group
+// already has, and channels take no member or admin limit. posting_roles is
+// only used, and then required, with the roles posting policy.
+func UpdateGroupSettings(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapEditSettings, "You cannot change the settings of this group") {
+		return
+	}
+
+	var body struct {
+		MaxMembers        *int     `json:"max_members"`
+		MaxAdmins         *int     `json:"max_admins"`
+		EditWindowMinutes *int     `json:"edit_window_minutes"`
+		AllowJoinRequests *bool    `json:"allow_join_requests"`
+		PostingPolicy     *string  `json:"posting_policy"`
+		PostingRoles      []string `json:"posting_roles"`
+		SlowModeSeconds   *int     `json:"slow_mode_seconds"`
+	}
+	if err := c.Bind(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid settings"})
+		return
+	}
+
+	if group.CommunityID != nil && (body.MaxMembers != nil || body.MaxAdmins != nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Channels have no member or admin limits of their own"})
+		return
+	}
+
+	platform := initializers.PlatformGroupLimits
+	limits := []struct {
+		name  string
+		value *int
+		max   int
+	}{
+		{"max_members", body.MaxMembers, platform.MaxMembers},
+		{"max_admins", body.MaxAdmins, platform.MaxAdmins},
+		{"edit_window_minutes", body.EditWindowMinutes, int(platform.EditWindow.Minutes())},
+	}
+	updates := map[string]any{}
+	for _, l := range limits {
+		if l.value == nil {
+			continue
+		}
+		if *l.value < 0 || *l.value > l.max {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be between 1 and %d, or 0 for the default", l.name, l.max)})
+			return
+		}
+		if *l.value == 0 {
+			updates[l.name] = nil
+		} else {
+			updates[l.name] = *l.value
+		}
+	}
+	if body.AllowJoinRequests != nil {
+		updates["allow_join_requests"] = *body.AllowJoinRequests
+	}
+	if body.PostingPolicy != nil {
+		if !models.IsValidPostingPolicy(*body.PostingPolicy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "posting_policy must be everyone, admins or roles"})
+			return
+		}
+		updates["posting_policy"] = *body.PostingPolicy
+		updates["posting_roles"] = ""
+		if *body.PostingPolicy == models.PostingRoles {
+			if len(body.PostingRoles) == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "posting_roles must list at least one role"})
+				return
+			}
+			for _, role := range body.PostingRoles {
+				if !models.IsValidRole(role) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown role %q in posting_roles", role)})
+					return
+				}
+			}
+			updates["posting_roles"] = strings.Join(body.PostingRoles, ",")
+		}
+	} else if body.PostingRoles != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "posting_roles needs posting_policy roles"})
+		return
+	}
+	if body.SlowModeSeconds != nil {
+		if *body.SlowModeSeconds < 0 || *body.SlowModeSeconds > maxSlowModeSeconds {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("slow_mode_seconds must be between 0 and %d", maxSlowModeSeconds)})
+			return
+		}
+		updates["slow_mode_seconds"] = *body.SlowModeSeconds
+	}
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No settings to update"})
+		return
+	}
+
+	var tooLow string
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// Locked so members cannot be added past the new limit while it is checked
+		before, err := lockGroup(tx, group.ID)
+		if err != nil {
+			return err
+		}
+
+		// SQL: UPDATE groups SET max_members = ?, max_admins = ?, edit_window_minutes = ?, allow_join_requests = ?,
+		//      posting_policy = ?, posting_roles = ?, slow_mode_seconds = ? WHERE id = ?;
+		if err := tx.Model(&group).Updates(updates).Error; err != nil {
+			return err
+		}
+		// SQL: SELECT * FROM groups WHERE id = ?;
+		if err := tx.First(&group, group.ID).Error; err != nil {
+			return err
+		}
+		limits := initializers.GroupLimitsFor(group)
+
+		// SQL: SELECT COUNT(*) FROM group_members WHERE group_id = ?;
+		var members int64
+		tx.Model(&models.GroupMember{}).Where("group_id = ?", group.ID).Count(&members)
+		if int(members) > limits.MaxMembers {
+			tooLow = fmt.Sprintf("The group already has %d members", members)
+			return errSettingsTooLow
+		}
+
+		// SQL: SELECT COUNT(*) FROM group_members WHERE group_id = ? AND role IN ('owner', 'admin');
+		var admins int64
+		tx.Model(&models.GroupMember{}).Where("group_id = ? AND role IN ?", group.ID, []string{models.RoleOwner, models.RoleAdmin}).Count(&admins)
+		if int(admins) > limits.MaxAdmins {
+			tooLow = fmt.Sprintf("The group already has %d admins", admins)
+			return errSettingsTooLow
+		}
+
+		return recordAudit(tx, group.ID, currentUser.Id, models.AuditSettingsChanged, auditTarget{},
+			groupAuditFields(before, updates), groupAuditFields(group, updates))
+	})
+	if errors.Is(err, errSettingsTooLow) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tooLow})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update settings"})
+		return
+	}
+
+	resp := groupSettingsResponse(group)
+	realtime.Publish(realtime.GroupTopic(group.ID), realtime.Event{Type: "settings.updated", Data: resp})
+	c.JSON(http.StatusOK, resp)
+}
+
+// groupLimitsTx returns the limits a group uses inside a transaction.
+func groupLimitsTx(tx *gorm.DB, groupID uint) initializers.GroupLimits {
+	var group models.Group
+	// SQL: SELECT * FROM groups WHERE id = ?;
+	tx.First(&group, groupID)
+	return initializers.GroupLimitsFor(group)
+}
+
+// postingRoles returns the roles allowed to post under the roles posting
+// policy, or an empty list under the other policies.
+func postingRoles(group models.Group) []string {
+	if group.PostingPolicy != models.PostingRoles || group.PostingRoles == "" {
+		return []string{}
+	}
+	return strings.Split(group.PostingRoles, ",")
+}
+
+// groupSettingsResponse returns a group's effective limits with the defaults
+// and maximums that apply to it. Channels have no member or admin limit.
+func groupSettingsResponse(group models.Group) gin.H {
+	limits := initializers.GroupLimitsFor(group)
+	defaults := initializers.DefaultGroupLimits
+	platform := initializers.PlatformGroupLimits
+
+	resp := gin.H{
+		"group_id":            group.ID,
+		"max_members":         limits.MaxMembers,
+		"max_admins":          limits.MaxAdmins,
+		"edit_window_minutes": int(limits.EditWindow.Minutes()),
+		"allow_join_requests": group.AllowJoinRequests,
+		"posting_policy":      group.PostingPolicy,
+		"posting_roles":       postingRoles(group),
+		"slow_mode_seconds":   group.SlowModeSeconds,
+		"overridden": gin.H{
+			"max_members":         group.MaxMembers != nil,
+			"max_admins":          group.MaxAdmins != nil,
+			"edit_window_minutes": group.EditWindowMinutes != nil,
+		},
+		"defaults": gin.H{
+			"max_members":         defaults.MaxMembers,
+			"max_admins":          defaults.MaxAdmins,
+			"edit_window_minutes": int(defaults.EditWindow.Minutes()),
+		},
+		"maximums": gin.H{
+			"max_members":         platform.MaxMembers,
+			"max_admins":          platform.MaxAdmins,
+			"edit_window_minutes": int(platform.EditWindow.Minutes()),
+		},
+	}
+	if group.CommunityID != nil {
+		resp["max_members"] = nil
+		resp["max_admins"] = nil
+	}
+	return resp
+}
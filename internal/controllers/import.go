@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/importer"
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportChatHistory imports a Slack workspace export ZIP or a WhatsApp chat
+// export uploaded as multipart form data.
+// Form fields: source (slack|whatsapp), file, optionally user_map (a JSON
+// object from Slack user IDs or WhatsApp contact names to existing usernames),
+// and for WhatsApp optionally group_name, date_order (dmy|mdy) and timezone
+// (e.g. Europe/Berlin).
+func ImportChatHistory(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	source := c.PostForm("source")
+	if source != "slack" && source != "whatsapp" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "source must be slack or whatsapp"})
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Export file required"})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read export file"})
+		return
+	}
+	defer file.Close()
+
+	location := time.UTC
+	if tz := c.PostForm("timezone"); tz != "" {
+		if location, err = time.LoadLocation(tz); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+			return
+		}
+	}
+
+	var users map[string]string
+	if raw := c.PostForm("user_map"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &users); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user_map must be a JSON object of usernames"})
+			return
+		}
+	}
+
+	report, err := importer.Import(file, header.Size, importer.Options{
+		Source:    source,
+		FileName:  header.Filename,
+		GroupName: c.PostForm("group_name"),
+		DateOrder: c.PostForm("date_order"),
+		Location:  location,
+		Users:     users,
+		StartedBy: &user.Id,
+	})
+	if errors.Is(err, importer.ErrUnknownUser) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, importer.ErrInvalidExport) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "report": report})
+		return
+	}
+	if err != nil {
+		// Completed batches are kept; uploading the same file again resumes the import
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "report": report})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ListImportJobs returns the most recent import runs with their status.
+func ListImportJobs(c *gin.Context) {
+	// SQL: SELECT * FROM import_jobs ORDER BY started_at DESC LIMIT 50;
+	var jobs []models.ImportJob
+	initializers.DB.Order("started_at DESC").Limit(50).Find(&jobs)
+
+	resp := []gin.H{}
+	for _, job := range jobs {
+		resp = append(resp, gin.H{
+			"id":                job.ID,
+			"source":            job.Source,
+			"file_name":         job.FileName,
+			"status":            job.Status,
+			"error":             job.Error,
+			"messages_imported": job.MessagesImported,
+			"messages_skipped":  job.MessagesSkipped,
+			"started_by":        job.StartedBy,
+			"started_at":        job.StartedAt,
+			"finished_at":       job.FinishedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,310 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"MessagingSystemBackend/internal/realtime"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errInviteInactive is returned when an invite is revoked, expired or used up.
+var errInviteInactive = errors.New("invite link is no longer valid")
+
+// CreateGroupInvite mints a shareable invite link for a group. Expiry, a
+// maximum number of uses and the role given to people who join are optional;
+// handing out a role above member also needs permission to manage roles.
+func CreateGroupInvite(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapAddMembers, "You cannot invite members to this group") {
+		return
+	}
+
+	var body struct {
+		Role      string     `json:"role"`
+		MaxUses   *int       `json:"max_uses"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := c.Bind(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invite"})
+		return
+	}
+
+	if body.Role == "" {
+		body.Role = models.RoleMember
+	}
+	if !models.IsValidRole(body.Role) || body.Role == models.RoleOwner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+	if models.RoleRank(body.Role) > models.RoleRank(models.RoleMember) {
+		actorRole, _ := GroupRole(group.ID, currentUser.Id)
+		if !Authorize(group.ID, currentUser.Id, models.CapManageRoles) || !outranks(actorRole, body.Role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You cannot invite members with this role"})
+			return
+		}
+	}
+	if body.MaxUses != nil && *body.MaxUses <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_uses must be positive"})
+		return
+	}
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	token, err := newInviteToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create invite"})
+		return
+	}
+
+	invite := models.GroupInvite{
+		GroupID:   group.ID,
+		Token:     token,
+		CreatedBy: currentUser.Id,
+		Role:      body.Role,
+		MaxUses:   body.MaxUses,
+		ExpiresAt: body.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// SQL: INSERT INTO group_invites (group_id, token, created_by, role, max_uses, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?);
+		if err := tx.Create(&invite).Error; err != nil {
+			return err
+		}
+		// The token is left out so the log does not hand out working links
+		return recordAudit(tx, group.ID, currentUser.Id, models.AuditInviteCreated, auditTarget{}, nil,
+			gin.H{"invite_id": invite.ID, "role": invite.Role, "max_uses": invite.MaxUses, "expires_at": invite.ExpiresAt})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create invite"})
+		return
+	}
+
+	c.JSON(http.StatusOK, inviteResponse(invite))
+}
+
+// ListGroupInvites returns a group's invite links that can still be redeemed.
+func ListGroupInvites(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapAddMembers, "You cannot see the invites of this group") {
+		return
+	}
+
+	now := time.Now()
+	// SQL: SELECT * FROM group_invites WHERE group_id = ? AND revoked_at IS NULL
+	//        AND (expires_at IS NULL OR expires_at > ?) AND (max_uses IS NULL OR uses < max_uses) ORDER BY created_at DESC;
+	var invites []models.GroupInvite
+	initializers.DB.
+		Where("group_id = ? AND revoked_at IS NULL", group.ID).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Where("max_uses IS NULL OR uses < max_uses").
+		Order("created_at DESC").
+		Find(&invites)
+
+	resp := []gin.H{}
+	for _, invite := range invites {
+		resp = append(resp, inviteResponse(invite))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// RevokeGroupInvite stops an invite link from being redeemed.
+func RevokeGroupInvite(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	invite, ok := findGroupInvite(c, currentUser)
+	if !ok {
+		return
+	}
+
+	if invite.RevokedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invite is already revoked"})
+		return
+	}
+
+	now := time.Now()
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// SQL: UPDATE group_invites SET revoked_at = ? WHERE id = ?;
+		if err := tx.Model(&invite).Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, invite.GroupID, currentUser.Id, models.AuditInviteRevoked, auditTarget{},
+			gin.H{"invite_id": invite.ID, "uses": invite.Uses}, gin.H{"invite_id": invite.ID, "revoked_at": now})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not revoke invite"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invite revoked"})
+}
+
+// GetGroupInviteUses returns who joined a group through an invite link.
+func GetGroupInviteUses(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	invite, ok := findGroupInvite(c, currentUser)
+	if !ok {
+		return
+	}
+
+	// SQL: SELECT * FROM group_invite_uses WHERE invite_id = ? ORDER BY joined_at; SELECT * FROM users WHERE id IN (...);
+	var uses []models.GroupInviteUse
+	initializers.DB.Preload("User").Where("invite_id = ?", invite.ID).Order("joined_at").Find(&uses)
+
+	resp := []gin.H{}
+	for _, use := range uses {
+		resp = append(resp, gin.H{
+			"user_id":   use.UserID,
+			"username":  use.User.Username,
+			"joined_at": use.JoinedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invite": inviteResponse(invite), "uses": resp})
+}
+
+// JoinGroupByInvite adds the current user to the group of an invite link,
+// with the invite's role. The member limit still applies.
+func JoinGroupByInvite(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var invite models.GroupInvite
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// Locked so the last use of a limited invite cannot be redeemed twice
+		// SQL: SELECT * FROM group_invites WHERE token = ? LIMIT 1 FOR UPDATE;
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token = ?", c.Param("token")).First(&invite).Error
+		if err != nil {
+			return err
+		}
+		if !invite.IsActive(time.Now()) {
+			return errInviteInactive
+		}
+
+		invite.Role = grantableRoleTx(tx, invite.GroupID, invite.CreatedBy, invite.Role)
+		if err := addGroupMemberTx(tx, invite.GroupID, user.Id, invite.Role); err != nil {
+			return err
+		}
+
+		// SQL: UPDATE group_invites SET uses = uses + 1 WHERE id = ?;
+		if err := tx.Model(&invite).Update("uses", gorm.Expr("uses + 1")).Error; err != nil {
+			return err
+		}
+		use := models.GroupInviteUse{InviteID: invite.ID, UserID: user.Id, JoinedAt: time.Now()}
+		// SQL: INSERT INTO group_invite_uses (invite_id, user_id, joined_at) VALUES (?, ?, ?);
+		if err := tx.Create(&use).Error; err != nil {
+			return err
+		}
+		if err := recordMembership(tx, invite.GroupID, user.Id, invite.CreatedBy, models.MembershipJoinedByInvite); err != nil {
+			return err
+		}
+
+		conv, err := groupConversationTx(tx, invite.GroupID)
+		if err != nil {
+			return err
+		}
+		_, err = PostSystemMessage(tx, conv.ID, user.Id, memberJoinedEvent(user, user, models.MembershipJoinedByInvite))
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found"})
+		return
+	}
+	if errors.Is(err, errInviteInactive) {
+		c.JSON(http.StatusGone, gin.H{"error": "Invite link is no longer valid"})
+		return
+	}
+	if errors.Is(err, errAlreadyMember) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You are already a member of this group"})
+		return
+	}
+	if errors.Is(err, errBanned) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are banned from this group"})
+		return
+	}
+	if err != nil {
+		membershipError(c, err)
+		return
+	}
+
+	realtime.Publish(realtime.GroupTopic(invite.GroupID), realtime.Event{Type: "member.joined", Data: gin.H{"group_id": invite.GroupID, "user_id": user.Id}})
+	c.JSON(http.StatusOK, gin.H{"message": "You joined the group", "group_id": invite.GroupID, "role": invite.Role})
+}
+
+// findGroupInvite loads the invite addressed by the :id and :inviteId URL
+// params, writing an error response and returning false unless it exists and
+// the user may manage the group's invites.
+func findGroupInvite(c *gin.Context, user models.User) (models.GroupInvite, bool) {
+	var invite models.GroupInvite
+
+	group, ok := findGroup(c)
+	if !ok {
+		return invite, false
+	}
+
+	if !requireCapability(c, group.ID, user.Id, models.CapAddMembers, "You cannot manage the invites of this group") {
+		return invite, false
+	}
+
+	inviteID, err := strconv.Atoi(c.Param("inviteId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invite ID"})
+		return invite, false
+	}
+
+	// SQL: SELECT * FROM group_invites WHERE id = ? AND group_id = ? LIMIT 1;
+	if err := initializers.DB.Where("group_id = ?", group.ID).First(&invite, inviteID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found"})
+		return invite, false
+	}
+
+	return invite, true
+}
+
+// newInviteToken returns a random, URL-safe invite token.
+func newInviteToken() (string, error) {
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// inviteResponse returns the fields of an invite sent to clients.
+func inviteResponse(invite models.GroupInvite) gin.H {
+	return gin.H{
+		"id":         invite.ID,
+		"group_id":   invite.GroupID,
+		"token":      invite.Token,
+		"role":       invite.Role,
+		"max_uses":   invite.MaxUses,
+		"uses":       invite.Uses,
+		"expires_at": invite.ExpiresAt,
+		"revoked_at": invite.RevokedAt,
+		"created_by": invite.CreatedBy,
+		"created_at": invite.CreatedAt,
+	}
+}
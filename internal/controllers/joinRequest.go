@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"MessagingSystemBackend/internal/realtime"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Longest message a user can attach to a join request
+const maxJoinRequestMessage = 500
+
+// errJoinRequestClosed is returned when a join request was already reviewed or withdrawn.
+var errJoinRequestClosed = errors.New("join request is no longer pending")
+
+// RequestToJoinGroup asks to join a group that accepts join requests,
+// optionally with a message for the reviewers.
+func RequestToJoinGroup(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !group.AllowJoinRequests || group.IsReadOnly() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This group does not accept join requests"})
+		return
+	}
+
+	if IsGroupMember(group.ID, user.Id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You are already a member of this group"})
+		return
+	}
+	if isBannedTx(initializers.DB, group.ID, user.Id) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are banned from this group"})
+		return
+	}
+
+	var body struct {
+		Message string `json:"message"`
+	}
+	// The message is optional, so an empty body is fine
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid join request"})
+		return
+	}
+	body.Message = strings.TrimSpace(body.Message)
+	if len(body.Message) > maxJoinRequestMessage {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Message can be at most %d characters", maxJoinRequestMessage)})
+		return
+	}
+
+	request := models.GroupJoinRequest{
+		GroupID:   group.ID,
+		UserID:    user.Id,
+		Message:   body.Message,
+		Status:    models.JoinRequestPending,
+		CreatedAt: time.Now(),
+	}
+	// The partial unique index allows only one pending request per user and group
+	// SQL: INSERT INTO group_join_requests (group_id, user_id, message, status, created_at) VALUES (?, ?, ?, 'pending', ?)
+	//      ON CONFLICT DO NOTHING;
+	result := initializers.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&request)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not send join request"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You already asked to join this group"})
+		return
+	}
+
+	realtime.Publish(realtime.GroupTopic(group.ID), realtime.Event{Type: "join_request.created", Data: gin.H{"group_id": group.ID, "request_id": request.ID, "user_id": user.Id}})
+	c.JSON(http.StatusOK, gin.H{"message": "Join request sent", "request_id": request.ID})
+}
+
+// CancelJoinRequest withdraws the current user's pending request to join a group.
+func CancelJoinRequest(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	// SQL: UPDATE group_join_requests SET status = 'cancelled' WHERE group_id = ? AND user_id = ? AND status = 'pending';
+	result := initializers.DB.Model(&models.GroupJoinRequest{}).
+		Where("group_id = ? AND user_id = ? AND status = ?", group.ID, user.Id, models.JoinRequestPending).
+		Update("status", models.JoinRequestCancelled)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not cancel join request"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No pending join request"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Join request cancelled"})
+}
+
+// ListJoinRequests returns a group's pending join requests, oldest first.
+func ListJoinRequests(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapAddMembers, "You cannot review join requests for this group") {
+		return
+	}
+
+	// SQL: SELECT * FROM group_join_requests WHERE group_id = ? AND status = 'pending' ORDER BY created_at;
+	//      SELECT * FROM users WHERE id IN (...);
+	var requests []models.GroupJoinRequest
+	initializers.DB.Preload("User").
+		Where("group_id = ? AND status = ?", group.ID, models.JoinRequestPending).
+		Order("created_at").
+		Find(&requests)
+
+	resp := []gin.H{}
+	for _, r := range requests {
+		resp = append(resp, gin.H{
+			"id":         r.ID,
+			"user_id":    r.UserID,
+			"username":   r.User.Username,
+			"message":    r.Message,
+			"created_at": r.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListMyJoinRequests returns the current user's join requests and their outcome, newest first.
+func ListMyJoinRequests(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	// SQL: SELECT * FROM group_join_requests WHERE user_id = ? ORDER BY created_at DESC LIMIT 50;
+	//      SELECT * FROM groups WHERE id IN (...);
+	var requests []models.GroupJoinRequest
+	initializers.DB.Preload("Group").Where("user_id = ?", user.Id).Order("created_at DESC").Limit(50).Find(&requests)
+
+	resp := []gin.H{}
+	for _, r := range requests {
+		resp = append(resp, joinRequestResponse(r))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ApproveJoinRequest adds the requester to the group, subject to the same
+// member limit and duplicate checks as adding a member directly.
+func ApproveJoinRequest(c *gin.Context) {
+	reviewJoinRequest(c, models.JoinRequestApproved)
+}
+
+// RejectJoinRequest turns down a pending join request.
+func RejectJoinRequest(c *gin.Context) {
+	reviewJoinRequest(c, models.JoinRequestRejected)
+}
+
+// reviewJoinRequest closes the pending join request addressed by the URL with
+// the given status and notifies the requester.
+func reviewJoinRequest(c *gin.Context, status string) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapAddMembers, "You cannot review join requests for this group") {
+		return
+	}
+
+	requestID, err := strconv.Atoi(c.Param("requestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request ID"})
+		return
+	}
+
+	var request models.GroupJoinRequest
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// SQL: SELECT * FROM group_join_requests WHERE id = ? AND group_id = ? LIMIT 1 FOR UPDATE;
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("User").
+			Where("group_id = ?", group.ID).First(&request, requestID).Error
+		if err != nil {
+			return err
+		}
+		if request.Status != models.JoinRequestPending {
+			return errJoinRequestClosed
+		}
+
+		now := time.Now()
+		request.Status = status
+		request.ReviewedBy = &currentUser.Id
+		request.ReviewedAt = &now
+		// SQL: UPDATE group_join_requests SET status = ?, reviewed_by = ?, reviewed_at = ? WHERE id = ?;
+		err = tx.Model(&request).Updates(map[string]any{"status": status, "reviewed_by": currentUser.Id, "reviewed_at": now}).Error
+		if err != nil {
+			return err
+		}
+
+		action := models.AuditJoinRejected
+		if status == models.JoinRequestApproved {
+			action = models.AuditJoinApproved
+		}
+		err = recordAudit(tx, group.ID, currentUser.Id, action, auditUser(request.UserID),
+			gin.H{"request_id": request.ID, "status": models.JoinRequestPending}, gin.H{"request_id": request.ID, "status": status})
+		if err != nil || status != models.JoinRequestApproved {
+			return err
+		}
+
+		if err := addGroupMemberTx(tx, group.ID, request.UserID, models.RoleMember); err != nil {
+			return err
+		}
+		if err := recordMembership(tx, group.ID, request.UserID, currentUser.Id, models.MembershipJoinApproved); err != nil {
+			return err
+		}
+
+		conv, err := groupConversationTx(tx, group.ID)
+		if err != nil {
+			return err
+		}
+		_, err = PostSystemMessage(tx, conv.ID, currentUser.Id, memberJoinedEvent(currentUser, request.User, models.MembershipJoinApproved))
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Join request not found"})
+		return
+	}
+	if errors.Is(err, errJoinRequestClosed) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Join request is no longer pending"})
+		return
+	}
+	if err != nil {
+		membershipError(c, err)
+		return
+	}
+
+	request.Group = group
+	realtime.Publish(realtime.UserTopic(request.UserID), realtime.Event{Type: "join_request." + status, Data: joinRequestResponse(request)})
+	if status == models.JoinRequestApproved {
+		realtime.Publish(realtime.GroupTopic(group.ID), realtime.Event{Type: "member.joined", Data: gin.H{"group_id": group.ID, "user_id": request.UserID}})
+		c.JSON(http.StatusOK, gin.H{"message": "Join request approved"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Join request rejected"})
+}
+
+// joinRequestResponse returns a join request as seen by its requester.
+func joinRequestResponse(r models.GroupJoinRequest) gin.H {
+	return gin.H{
+		"id":          r.ID,
+		"group_id":    r.GroupID,
+		"group_name":  r.Group.Name,
+		"message":     r.Message,
+		"status":      r.Status,
+		"created_at":  r.CreatedAt,
+		"reviewed_at": r.ReviewedAt,
+	}
+}
@@ -0,0 +1,535 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Batch member limits
+const (
+	maxBatchRows     = 500
+	maxBatchCSVBytes = 1 << 20
+)
+
+// Batch member actions
+const (
+	batchAdd     = "add"
+	batchRemove  = "remove"
+	batchSetRole = "set_role"
+)
+
+// batchOperation is one row of a batch: a member to add or remove, or a
+// member whose role changes.
+type batchOperation struct {
+	Action   string `json:"action"`
+	Username string `json:"username"`
+	Role     string `json:"role"` // Role to add with (member by default) or to change to
+}
+
+// batchRow is a batch operation checked against the group.
+type batchRow struct {
+	batchOperation
+	Row      int // 1-based position in the batch
+	User     models.User
+	Previous string // Role before the batch, "" if not a member
+	Error    string // Why the row cannot be applied
+}
+
+// batchRowError is returned when a row fails while the batch is applied.
+type batchRowError struct {
+	Row int
+	Err error
+}
+
+func (e *batchRowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e *batchRowError) Unwrap() error {
+	return e.Err
+}
+
+// BatchUpdateMembers adds, removes or changes the role of many members at
+// once. The operations come as JSON ({"operations": [{"action", "username",
+// "role"}]}) or as a CSV file uploaded in the multipart field file, with a
+// header naming the username, action and role columns (action defaults to
+// add). Every row is checked up front, including the member and admin limits
+// for the end result; if any row fails nothing is changed. Otherwise all rows
+// are applied in one transaction: removals first, then role changes, then
+// additions. With dry_run=true the rows are only checked.
+func BatchUpdateMembers(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+	if group.IsReadOnly() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This group is archived or scheduled for deletion"})
+		return
+	}
+	actorRole, ok := GroupRole(group.ID, currentUser.Id)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	ops, err := readBatchOperations(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(ops) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one operation required"})
+		return
+	}
+	if len(ops) > maxBatchRows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A batch can have at most %d rows", maxBatchRows)})
+		return
+	}
+
+	rows := checkBatch(group, currentUser, actorRole, ops)
+	limitError := checkBatchLimits(group, rows)
+	if limitError != "" || batchHasErrors(rows) {
+		resp := gin.H{"error": "Batch rejected; nothing was changed", "results": batchReport(rows, false)}
+		if limitError != "" {
+			resp["error"] = limitError + "; nothing was changed"
+		}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	if c.Query("dry_run") == "true" {
+		c.JSON(http.StatusOK, gin.H{"dry_run": true, "results": batchReport(rows, false)})
+		return
+	}
+
+	// Removals and demotions first, so the limits only have to hold for the end result
+	ordered := make([]*batchRow, len(rows))
+	for i := range rows {
+		ordered[i] = &rows[i]
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return batchRank(*ordered[i]) < batchRank(*ordered[j])
+	})
+
+	departures := map[uint]departure{}
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		for _, row := range ordered {
+			var err error
+			switch row.Action {
+			case batchAdd:
+				row.Role, err = addMemberTx(tx, group.ID, row.User, currentUser, row.Role)
+			case batchRemove:
+				var result departure
+				result, err = removeGroupMemberTx(tx, group, row.User, currentUser, models.MembershipRemoved,
+					memberDepartedEvent(currentUser, row.User, models.MembershipRemoved, false))
+				if err == nil {
+					departures[row.User.Id] = result
+					err = recordAudit(tx, group.ID, currentUser.Id, models.AuditMemberRemoved, auditUser(row.User.Id), gin.H{"role": result.Role}, nil)
+				}
+			case batchSetRole:
+				_, err = changeMemberRoleTx(tx, group.ID, row.User, currentUser, row.Role)
+			}
+			if err != nil {
+				return &batchRowError{Row: row.Row, Err: err}
+			}
+		}
+		return nil
+	})
+	var rowErr *batchRowError
+	if errors.As(err, &rowErr) && isBatchConflict(rowErr.Err) {
+		// The group changed between the checks and the transaction
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Row %d could not be applied: %v; nothing was changed", rowErr.Row, rowErr.Err), "row": rowErr.Row})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update members"})
+		return
+	}
+
+	counts := map[string]int{}
+	for _, row := range rows {
+		counts[row.Action]++
+		switch row.Action {
+		case batchRemove:
+			publishDeparture(group.ID, "member.removed", row.User, departures[row.User.Id])
+		case batchSetRole:
+			publishRoleChange(group.ID, row.User.Id, row.Previous, row.Role)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results":       batchReport(rows, true),
+		"added":         counts[batchAdd],
+		"removed":       counts[batchRemove],
+		"roles_changed": counts[batchSetRole],
+	})
+}
+
+// readBatchOperations reads the operations of a batch from a CSV upload or a
+// JSON body.
+func readBatchOperations(c *gin.Context) ([]batchOperation, error) {
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, errors.New("CSV file required")
+		}
+		if header.Size > maxBatchCSVBytes {
+			return nil, fmt.Errorf("CSV file can be at most %d bytes", maxBatchCSVBytes)
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, errors.New("Could not read CSV file")
+		}
+		defer file.Close()
+		return parseBatchCSV(file)
+	}
+
+	var body struct {
+		Operations []batchOperation `json:"operations"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		return nil, errors.New("Invalid batch")
+	}
+	return body.Operations, nil
+}
+
+// parseBatchCSV reads batch operations from CSV with a header row. The
+// username column is required; action defaults to add and role is optional.
+func parseBatchCSV(r io.Reader) ([]batchOperation, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("CSV file needs a header row")
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		// Spreadsheets often start the file with a byte order mark
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[name] = i
+	}
+	if _, ok := columns["username"]; !ok {
+		return nil, errors.New("CSV header needs a username column")
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var ops []batchOperation
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CSV: %v", err)
+		}
+		op := batchOperation{
+			Action:   field(record, "action"),
+			Username: field(record, "username"),
+			Role:     field(record, "role"),
+		}
+		if op.Action == "" && op.Username == "" && op.Role == "" {
+			continue // Blank line
+		}
+		if op.Action == "" {
+			op.Action = batchAdd
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// checkBatch checks every operation against the group as it is now, without
+// changing anything, and returns one row per operation.
+func checkBatch(group models.Group, actor models.User, actorRole string, ops []batchOperation) []batchRow {
+	rows := make([]batchRow, len(ops))
+	var usernames []string
+	for i, op := range ops {
+		op.Action = strings.ToLower(strings.TrimSpace(op.Action))
+		op.Username = strings.TrimSpace(op.Username)
+		op.Role = strings.ToLower(strings.TrimSpace(op.Role))
+		rows[i] = batchRow{batchOperation: op, Row: i + 1}
+		if op.Username != "" {
+			usernames = append(usernames, op.Username)
+		}
+	}
+
+	// SQL: SELECT * FROM users WHERE username IN (...);
+	var users []models.User
+	initializers.DB.Where("username IN ?", usernames).Find(&users)
+	byName := map[string]models.User{}
+	var userIDs []uint
+	for _, u := range users {
+		byName[u.Username] = u
+		userIDs = append(userIDs, u.Id)
+	}
+
+	// SQL: SELECT * FROM group_members WHERE group_id = ? AND user_id IN (...);
+	var members []models.GroupMember
+	initializers.DB.Where("group_id = ? AND user_id IN ?", group.ID, userIDs).Find(&members)
+	roles := map[uint]string{}
+	for _, m := range members {
+		roles[m.UserID] = m.Role
+	}
+
+	// SQL: SELECT user_id FROM group_bans WHERE group_id = ? AND user_id IN (...);
+	var bannedIDs []uint
+	initializers.DB.Model(&models.GroupBan{}).Where("group_id = ? AND user_id IN ?", group.ID, userIDs).Pluck("user_id", &bannedIDs)
+	banned := map[uint]bool{}
+	for _, id := range bannedIDs {
+		banned[id] = true
+	}
+
+	// Channels only take members of their community
+	inCommunity := map[uint]bool{}
+	if group.CommunityID != nil {
+		// SQL: SELECT user_id FROM community_members WHERE community_id = ? AND user_id IN (...);
+		var communityIDs []uint
+		initializers.DB.Model(&models.CommunityMember{}).
+			Where("community_id = ? AND user_id IN ?", *group.CommunityID, userIDs).Pluck("user_id", &communityIDs)
+		for _, id := range communityIDs {
+			inCommunity[id] = true
+		}
+	}
+
+	capabilities := map[string]bool{}
+	for _, capability := range []string{models.CapAddMembers, models.CapRemoveMembers, models.CapManageRoles} {
+		capabilities[capability] = Authorize(group.ID, actor.Id, capability)
+	}
+
+	seen := map[string]bool{}
+	for i := range rows {
+		row := &rows[i]
+		switch {
+		case row.Action != batchAdd && row.Action != batchRemove && row.Action != batchSetRole:
+			row.Error = "Action must be add, remove or set_role"
+			continue
+		case row.Username == "":
+			row.Error = "Username required"
+			continue
+		case seen[row.Username]:
+			row.Error = "User appears in more than one row"
+			continue
+		}
+		seen[row.Username] = true
+
+		user, ok := byName[row.Username]
+		if !ok {
+			row.Error = "User not found"
+			continue
+		}
+		row.User = user
+		row.Previous = roles[user.Id]
+
+		switch row.Action {
+		case batchAdd:
+			if row.Role == "" {
+				row.Role = models.RoleMember
+			}
+			row.Error = checkBatchAdd(row, actor, actorRole, capabilities, banned[user.Id], group.CommunityID == nil || inCommunity[user.Id])
+		case batchRemove:
+			row.Error = checkBatchRemove(row, actor, actorRole, capabilities)
+		case batchSetRole:
+			row.Error = checkBatchSetRole(row, actorRole, capabilities)
+		}
+	}
+	return rows
+}
+
+// checkBatchAdd returns why a user cannot be added, or "" if they can.
+func checkBatchAdd(row *batchRow, actor models.User, actorRole string, capabilities map[string]bool, banned bool, inCommunity bool) string {
+	switch {
+	case !capabilities[models.CapAddMembers]:
+		return "You cannot add members to this group"
+	case !models.IsValidRole(row.Role) || row.Role == models.RoleOwner:
+		return "Invalid role"
+	case models.RoleRank(row.Role) > models.RoleRank(models.RoleMember) && (!capabilities[models.CapManageRoles] || !outranks(actorRole, row.Role)):
+		return "You cannot add members with this role"
+	case row.Previous != "":
+		return "User already a member"
+	case banned:
+		return "User is banned from this group"
+	case !inCommunity:
+		return "User is not a member of this channel's community"
+	case !allowsDirectAdd(actor.Id, row.User):
+		return "This user can only be invited to groups; send an invitation instead"
+	}
+	return ""
+}
+
+// checkBatchRemove returns why a member cannot be removed, or "" if they can.
+func checkBatchRemove(row *batchRow, actor models.User, actorRole string, capabilities map[string]bool) string {
+	switch {
+	case !capabilities[models.CapRemoveMembers]:
+		return "You cannot remove members from this group"
+	case row.User.Id == actor.Id:
+		return "Use leave to remove yourself from a group"
+	case row.Previous == "":
+		return "User is not a member of this group"
+	case row.Previous == models.RoleOwner:
+		return "The group owner cannot be removed"
+	case !outranks(actorRole, row.Previous):
+		return "You cannot remove a member with a higher role"
+	}
+	return ""
+}
+
+// checkBatchSetRole returns why a member's role cannot be changed, or "" if it can.
+func checkBatchSetRole(row *batchRow, actorRole string, capabilities map[string]bool) string {
+	switch {
+	case !capabilities[models.CapManageRoles]:
+		return "You cannot change roles in this group"
+	case !models.IsValidRole(row.Role):
+		return "Invalid role"
+	case row.Previous == "":
+		return "User is not a member of this group"
+	case row.Role == models.RoleOwner || row.Previous == models.RoleOwner:
+		return "The owner can only change through an ownership transfer"
+	case !outranks(actorRole, row.Previous) || !outranks(actorRole, row.Role):
+		return "You cannot change this member's role"
+	case row.Previous == row.Role:
+		return "User already has this role"
+	}
+	return ""
+}
+
+// checkBatchLimits returns why the group would end up above its member or
+// admin limit once every row is applied, or "" if it would not. Channels are
+// bounded by their community instead.
+func checkBatchLimits(group models.Group, rows []batchRow) string {
+	if group.CommunityID != nil {
+		return ""
+	}
+
+	// SQL: SELECT COUNT(*) FROM group_members WHERE group_id = ?;
+	//      SELECT COUNT(*) FROM group_members WHERE group_id = ? AND role IN ('owner', 'admin');
+	var members, admins int64
+	initializers.DB.Model(&models.GroupMember{}).Where("group_id = ?", group.ID).Count(&members)
+	initializers.DB.Model(&models.GroupMember{}).Where("group_id = ? AND role IN ?", group.ID, []string{models.RoleOwner, models.RoleAdmin}).Count(&admins)
+
+	for _, row := range rows {
+		if row.Error != "" {
+			continue
+		}
+		wasAdmin := row.Previous == models.RoleAdmin
+		isAdmin := row.Role == models.RoleAdmin
+		switch row.Action {
+		case batchAdd:
+			members++
+			if isAdmin {
+				admins++
+			}
+		case batchRemove:
+			members--
+			if wasAdmin {
+				admins--
+			}
+		case batchSetRole:
+			if isAdmin && !wasAdmin {
+				admins++
+			} else if wasAdmin && !isAdmin {
+				admins--
+			}
+		}
+	}
+
+	limits := initializers.GroupLimitsFor(group)
+	if int(members) > limits.MaxMembers {
+		return fmt.Sprintf("The group would have %d members, above its limit of %d", members, limits.MaxMembers)
+	}
+	if int(admins) > limits.MaxAdmins {
+		return fmt.Sprintf("The group would have %d admins, above its limit of %d", admins, limits.MaxAdmins)
+	}
+	return ""
+}
+
+// batchRank orders rows so removals come first, then demotions, then
+// promotions and other role changes, then additions.
+func batchRank(row batchRow) int {
+	switch {
+	case row.Action == batchRemove:
+		return 0
+	case row.Action == batchSetRole && models.RoleRank(row.Role) < models.RoleRank(row.Previous):
+		return 1
+	case row.Action == batchSetRole:
+		return 2
+	}
+	return 3
+}
+
+// batchHasErrors reports whether any row failed its checks.
+func batchHasErrors(rows []batchRow) bool {
+	for _, row := range rows {
+		if row.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// isBatchConflict reports whether err is a membership error caused by the
+// group changing, as opposed to a database failure.
+func isBatchConflict(err error) bool {
+	for _, conflict := range []error{errAlreadyMember, errNotGroupMember, errGroupFull, errAdminLimit, errBanned,
+		errGroupReadOnly, errRoleNotAllowed, errOwnerRole, errSameRole, errNotCommunityMember, errRemoveOwner, errRemoveOutranked} {
+		if errors.Is(err, conflict) {
+			return true
+		}
+	}
+	return false
+}
+
+// batchReport returns the per-row result of a batch, in the order of the
+// operations. Once applied, rows report what was done; before that, whether
+// they are valid.
+func batchReport(rows []batchRow, applied bool) []gin.H {
+	applyStatus := map[string]string{batchAdd: "added", batchRemove: "removed", batchSetRole: "role_changed"}
+
+	report := []gin.H{}
+	for _, row := range rows {
+		entry := gin.H{
+			"row":      row.Row,
+			"action":   row.Action,
+			"username": row.Username,
+		}
+		if row.User.Id != 0 {
+			entry["user_id"] = row.User.Id
+		}
+		if row.Action != batchRemove && row.Role != "" {
+			entry["role"] = row.Role
+		}
+		if row.Action == batchSetRole && row.Previous != "" {
+			entry["previous_role"] = row.Previous
+		}
+		switch {
+		case row.Error != "":
+			entry["status"] = "error"
+			entry["error"] = row.Error
+		case applied:
+			entry["status"] = applyStatus[row.Action]
+		default:
+			entry["status"] = "valid"
+		}
+		report = append(report, entry)
+	}
+	return report
+}
@@ -0,0 +1,447 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"MessagingSystemBackend/internal/realtime"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// Longest reason a moderator can give for a mute or ban
+	maxModerationReason = 500
+	// Longest a member can be muted for: 30 days
+	maxMuteMinutes = 30 * 24 * 60
+)
+
+// errAlreadyBanned is returned when banning a user who is already banned.
+var errAlreadyBanned = errors.New("user is already banned")
+
+// MuteMember keeps a member from posting or voting until the mute ends. Needs
+// delete_messages, and only members who do not outrank the moderator can be
+// muted. Muting a muted member replaces their mute.
+func MuteMember(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapDeleteMessages, "You cannot mute members of this group") {
+		return
+	}
+
+	target, ok := findModerationTarget(c, currentUser)
+	if !ok {
+		return
+	}
+
+	actorRole, _ := GroupRole(group.ID, currentUser.Id)
+	targetRole, isMember := GroupRole(group.ID, target.Id)
+	if !isMember {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of this group"})
+		return
+	}
+	if !outranks(actorRole, targetRole) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot mute a member with a higher role"})
+		return
+	}
+
+	var body struct {
+		Minutes int    `json:"minutes"`
+		Reason  string `json:"reason"`
+	}
+	if err := c.Bind(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mute"})
+		return
+	}
+	if body.Minutes < 1 || body.Minutes > maxMuteMinutes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("minutes must be between 1 and %d", maxMuteMinutes)})
+		return
+	}
+	reason, ok := moderationReason(c, body.Reason)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	mute := models.GroupMute{
+		GroupID:    group.ID,
+		UserID:     target.Id,
+		MutedBy:    currentUser.Id,
+		Reason:     reason,
+		MutedUntil: now.Add(time.Duration(body.Minutes) * time.Minute),
+		CreatedAt:  now,
+	}
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		var before any
+		if until := mutedUntilTx(tx, group.ID, target.Id); until != nil {
+			before = gin.H{"muted_until": until}
+		}
+
+		// SQL: INSERT INTO group_mutes (group_id, user_id, muted_by, reason, muted_until, created_at) VALUES (?, ?, ?, ?, ?, ?)
+		//      ON CONFLICT (group_id, user_id) DO UPDATE SET muted_by = ?, reason = ?, muted_until = ?, created_at = ?;
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "group_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"muted_by", "reason", "muted_until", "created_at"}),
+		}).Create(&mute).Error
+		if err != nil {
+			return err
+		}
+		if err := recordMembership(tx, group.ID, target.Id, currentUser.Id, models.MembershipMuted); err != nil {
+			return err
+		}
+		return recordAudit(tx, group.ID, currentUser.Id, models.AuditMemberMuted, auditUser(target.Id), before,
+			gin.H{"muted_until": mute.MutedUntil, "reason": reason})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not mute member"})
+		return
+	}
+
+	event := realtime.Event{Type: "member.muted", Data: gin.H{"group_id": group.ID, "user_id": target.Id, "muted_until": mute.MutedUntil}}
+	realtime.Publish(realtime.GroupTopic(group.ID), event)
+	c.JSON(http.StatusOK, gin.H{"message": "Member muted", "muted_until": mute.MutedUntil})
+}
+
+// UnmuteMember lifts a member's mute before it ends.
+func UnmuteMember(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapDeleteMessages, "You cannot unmute members of this group") {
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		var mute models.GroupMute
+		// SQL: DELETE FROM group_mutes WHERE group_id = ? AND user_id = ? AND muted_until > ? RETURNING *;
+		result := tx.Clauses(clause.Returning{}).
+			Where("group_id = ? AND user_id = ? AND muted_until > ?", group.ID, userID, time.Now()).Delete(&mute)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := recordMembership(tx, group.ID, uint(userID), currentUser.Id, models.MembershipUnmuted); err != nil {
+			return err
+		}
+		return recordAudit(tx, group.ID, currentUser.Id, models.AuditMemberUnmuted, auditUser(uint(userID)),
+			gin.H{"muted_until": mute.MutedUntil, "reason": mute.Reason}, nil)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not muted"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not unmute member"})
+		return
+	}
+
+	realtime.Publish(realtime.GroupTopic(group.ID), realtime.Event{Type: "member.unmuted", Data: gin.H{"group_id": group.ID, "user_id": userID}})
+	c.JSON(http.StatusOK, gin.H{"message": "Member unmuted"})
+}
+
+// ListMutes returns the members of a group who are muted right now, the
+// mutes ending soonest first.
+func ListMutes(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapDeleteMessages, "You cannot see the mutes of this group") {
+		return
+	}
+
+	// SQL: SELECT * FROM group_mutes WHERE group_id = ? AND muted_until > ? ORDER BY muted_until;
+	//      SELECT * FROM users WHERE id IN (...);
+	var mutes []models.GroupMute
+	err := initializers.DB.Preload("User").
+		Where("group_id = ? AND muted_until > ?", group.ID, time.Now()).
+		Order("muted_until").Find(&mutes).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not list mutes"})
+		return
+	}
+
+	resp := []gin.H{}
+	for _, m := range mutes {
+		resp = append(resp, gin.H{
+			"user_id":     m.UserID,
+			"username":    m.User.Username,
+			"muted_by":    m.MutedBy,
+			"reason":      m.Reason,
+			"muted_until": m.MutedUntil,
+			"created_at":  m.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// BanMember removes a user from a group, if they are in it, and keeps them
+// from being added again through any route until the ban is lifted. Needs
+// remove_members; members who outrank the moderator cannot be banned. Users
+// who are not members can be banned too, which also withdraws their pending
+// invitations and join requests.
+func BanMember(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapRemoveMembers, "You cannot ban members of this group") {
+		return
+	}
+
+	target, ok := findModerationTarget(c, currentUser)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	// The reason is optional, so an empty body is fine
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ban"})
+		return
+	}
+	reason, ok := moderationReason(c, body.Reason)
+	if !ok {
+		return
+	}
+
+	var result departure
+	wasMember := false
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		ban := models.GroupBan{
+			GroupID:   group.ID,
+			UserID:    target.Id,
+			BannedBy:  currentUser.Id,
+			Reason:    reason,
+			CreatedAt: time.Now(),
+		}
+		// SQL: INSERT INTO group_bans (group_id, user_id, banned_by, reason, created_at) VALUES (?, ?, ?, ?, ?) ON CONFLICT DO NOTHING;
+		created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&ban)
+		if created.Error != nil {
+			return created.Error
+		}
+		if created.RowsAffected == 0 {
+			return errAlreadyBanned
+		}
+
+		// Nothing pending for a banned user can let them in any more
+		// SQL: UPDATE group_invitations SET status = 'cancelled', responded_at = ? WHERE group_id = ? AND user_id = ? AND status = 'pending';
+		err := tx.Model(&models.GroupInvitation{}).
+			Where("group_id = ? AND user_id = ? AND status = ?", group.ID, target.Id, models.InvitationPending).
+			Updates(map[string]any{"status": models.InvitationCancelled, "responded_at": time.Now()}).Error
+		if err != nil {
+			return err
+		}
+		// SQL: UPDATE group_join_requests SET status = 'rejected', reviewed_by = ?, reviewed_at = ? WHERE group_id = ? AND user_id = ? AND status = 'pending';
+		err = tx.Model(&models.GroupJoinRequest{}).
+			Where("group_id = ? AND user_id = ? AND status = ?", group.ID, target.Id, models.JoinRequestPending).
+			Updates(map[string]any{"status": models.JoinRequestRejected, "reviewed_by": currentUser.Id, "reviewed_at": time.Now()}).Error
+		if err != nil {
+			return err
+		}
+
+		var before any
+		result, err = removeGroupMemberTx(tx, group, target, currentUser, models.MembershipBanned,
+			memberDepartedEvent(currentUser, target, models.MembershipBanned, false))
+		switch {
+		case errors.Is(err, errNotGroupMember):
+			// Banned before ever joining, or after leaving
+			if err := recordMembership(tx, group.ID, target.Id, currentUser.Id, models.MembershipBanned); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			wasMember = true
+			before = gin.H{"role": result.Role}
+		}
+		return recordAudit(tx, group.ID, currentUser.Id, models.AuditMemberBanned, auditUser(target.Id), before, gin.H{"reason": reason})
+	})
+	if errors.Is(err, errAlreadyBanned) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User is already banned"})
+		return
+	}
+	if errors.Is(err, errRemoveOwner) || errors.Is(err, errRemoveOutranked) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot ban a member with a higher role"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not ban user"})
+		return
+	}
+
+	if wasMember {
+		publishDeparture(group.ID, "member.banned", target, result)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "User banned from group"})
+}
+
+// UnbanMember lifts a ban. The user is not added back; they can join again
+// through the usual routes.
+func UnbanMember(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapRemoveMembers, "You cannot unban users from this group") {
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		var ban models.GroupBan
+		// SQL: DELETE FROM group_bans WHERE group_id = ? AND user_id = ? RETURNING *;
+		result := tx.Clauses(clause.Returning{}).Where("group_id = ? AND user_id = ?", group.ID, userID).Delete(&ban)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := recordMembership(tx, group.ID, uint(userID), currentUser.Id, models.MembershipUnbanned); err != nil {
+			return err
+		}
+		return recordAudit(tx, group.ID, currentUser.Id, models.AuditMemberUnbanned, auditUser(uint(userID)), gin.H{"reason": ban.Reason}, nil)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not banned"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not unban user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unbanned"})
+}
+
+// ListBans returns the users banned from a group, most recent first.
+func ListBans(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapRemoveMembers, "You cannot see the bans of this group") {
+		return
+	}
+
+	// SQL: SELECT * FROM group_bans WHERE group_id = ? ORDER BY created_at DESC;
+	//      SELECT * FROM users WHERE id IN (...);
+	var bans []models.GroupBan
+	if err := initializers.DB.Preload("User").Where("group_id = ?", group.ID).Order("created_at DESC").Find(&bans).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not list bans"})
+		return
+	}
+
+	resp := []gin.H{}
+	for _, b := range bans {
+		resp = append(resp, gin.H{
+			"user_id":    b.UserID,
+			"username":   b.User.Username,
+			"banned_by":  b.BannedBy,
+			"reason":     b.Reason,
+			"created_at": b.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// mutedUntilTx returns when a member's mute in a group ends, or nil if they
+// are not muted.
+func mutedUntilTx(tx *gorm.DB, groupID uint, userID uint) *time.Time {
+	var mute models.GroupMute
+	// SQL: SELECT * FROM group_mutes WHERE group_id = ? AND user_id = ? AND muted_until > ? LIMIT 1;
+	err := tx.Where("group_id = ? AND user_id = ? AND muted_until > ?", groupID, userID, time.Now()).First(&mute).Error
+	if err != nil {
+		return nil
+	}
+	return &mute.MutedUntil
+}
+
+// isBannedTx reports whether a user is banned from a group.
+func isBannedTx(tx *gorm.DB, groupID uint, userID uint) bool {
+	var count int64
+	// SQL: SELECT COUNT(*) FROM group_bans WHERE group_id = ? AND user_id = ?;
+	tx.Model(&models.GroupBan{}).Where("group_id = ? AND user_id = ?", groupID, userID).Count(&count)
+	return count > 0
+}
+
+// findModerationTarget loads the user in the :userId URL param, writing an
+// error response and returning false if they do not exist or are the actor.
+func findModerationTarget(c *gin.Context, actor models.User) (models.User, bool) {
+	var target models.User
+
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return target, false
+	}
+	if uint(userID) == actor.Id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot moderate yourself"})
+		return target, false
+	}
+
+	// SQL: SELECT * FROM users WHERE id = ? LIMIT 1;
+	if err := initializers.DB.First(&target, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return target, false
+	}
+	return target, true
+}
+
+// moderationReason trims a mute or ban reason, writing an error response and
+// returning false if it is too long.
+func moderationReason(c *gin.Context, reason string) (string, bool) {
+	reason = strings.TrimSpace(reason)
+	if len(reason) > maxModerationReason {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Reason can be at most %d characters", maxModerationReason)})
+		return "", false
+	}
+	return reason, true
+}
@@ -0,0 +1,312 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"MessagingSystemBackend/internal/realtime"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errStaleTransfer is returned when the user who offered a transfer no longer
+// owns the group, or the transfer was answered in the meantime.
+var errStaleTransfer = errors.New("ownership has changed since this transfer was offered")
+
+// RequestOwnershipTransfer lets the owner offer the group to another member.
+// A new offer replaces any pending one.
+func RequestOwnershipTransfer(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapTransferOwnership, "Only the group owner can transfer ownership") {
+		return
+	}
+
+	var body struct {
+		Username string `json:"username"`
+	}
+	if err := c.Bind(&body); err != nil || body.Username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username required"})
+		return
+	}
+
+	// SQL: SELECT * FROM users WHERE username = ?;
+	var target models.User
+	if err := initializers.DB.Where("username = ?", body.Username).First(&target).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if target.Id == currentUser.Id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You already own this group"})
+		return
+	}
+
+	if !IsGroupMember(group.ID, target.Id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ownership can only be transferred to a group member"})
+		return
+	}
+
+	transfer := models.OwnershipTransfer{
+		GroupID:    group.ID,
+		FromUserID: currentUser.Id,
+		ToUserID:   target.Id,
+		Status:     models.TransferPending,
+		CreatedAt:  time.Now(),
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := cancelPendingTransfers(tx, group.ID, currentUser.Id); err != nil {
+			return err
+		}
+		// SQL: INSERT INTO ownership_transfers (group_id, from_user_id, to_user_id, status, created_at) VALUES (?, ?, ?, 'pending', ?);
+		if err := tx.Create(&transfer).Error; err != nil {
+			return err
+		}
+		if err := recordMembership(tx, group.ID, target.Id, currentUser.Id, models.MembershipTransferRequested); err != nil {
+			return err
+		}
+		return recordAudit(tx, group.ID, currentUser.Id, models.AuditTransferOffered, auditUser(target.Id),
+			nil, gin.H{"transfer_id": transfer.ID, "owner": currentUser.Id})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not offer ownership"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Ownership transfer offered", "transfer_id": transfer.ID})
+}
+
+// CancelOwnershipTransfer withdraws the owner's pending offer.
+func CancelOwnershipTransfer(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, group.ID, currentUser.Id, models.CapTransferOwnership, "Only the group owner can cancel a transfer") {
+		return
+	}
+
+	transfer, ok := findPendingTransfer(c, "group_id = ? AND from_user_id = ?", group.ID, currentUser.Id)
+	if !ok {
+		return
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockPendingTransfer(tx, &transfer); err != nil {
+			return err
+		}
+		if err := respondToTransfer(tx, &transfer, models.TransferCancelled); err != nil {
+			return err
+		}
+		if err := recordMembership(tx, group.ID, transfer.ToUserID, currentUser.Id, models.MembershipTransferCancelled); err != nil {
+			return err
+		}
+		return recordAudit(tx, group.ID, currentUser.Id, models.AuditTransferCancelled, auditUser(transfer.ToUserID),
+			gin.H{"transfer_id": transfer.ID, "status": models.TransferPending}, gin.H{"transfer_id": transfer.ID, "status": transfer.Status})
+	})
+	if errors.Is(err, errStaleTransfer) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This transfer is no longer pending"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not cancel transfer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Ownership transfer cancelled"})
+}
+
+// DeclineOwnershipTransfer lets the recipient turn down an offer.
+func DeclineOwnershipTransfer(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	transfer, ok := findPendingTransfer(c, "group_id = ? AND to_user_id = ?", group.ID, currentUser.Id)
+	if !ok {
+		return
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockPendingTransfer(tx, &transfer); err != nil {
+			return err
+		}
+		if err := respondToTransfer(tx, &transfer, models.TransferDeclined); err != nil {
+			return err
+		}
+		if err := recordMembership(tx, group.ID, currentUser.Id, currentUser.Id, models.MembershipTransferDeclined); err != nil {
+			return err
+		}
+		return recordAudit(tx, group.ID, currentUser.Id, models.AuditTransferDeclined, auditUser(currentUser.Id),
+			gin.H{"transfer_id": transfer.ID, "status": models.TransferPending}, gin.H{"transfer_id": transfer.ID, "status": transfer.Status})
+	})
+	if errors.Is(err, errStaleTransfer) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This transfer is no longer pending"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not decline transfer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Ownership transfer declined"})
+}
+
+// AcceptOwnershipTransfer makes the recipient of a pending offer the owner.
+// The previous owner becomes an admin, or a regular member if that would
+// exceed the admin limit.
+func AcceptOwnershipTransfer(c *gin.Context) {
+	currentUser := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	transfer, ok := findPendingTransfer(c, "group_id = ? AND to_user_id = ?", group.ID, currentUser.Id)
+	if !ok {
+		return
+	}
+
+	var previousOwner models.User
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockGroup(tx, group.ID); err != nil {
+			return err
+		}
+		if err := lockPendingTransfer(tx, &transfer); err != nil {
+			return err
+		}
+		if role, _ := groupRoleTx(tx, group.ID, transfer.FromUserID); role != models.RoleOwner {
+			return errStaleTransfer
+		}
+
+		// SQL: SELECT * FROM users WHERE id = ?;
+		if err := tx.First(&previousOwner, transfer.FromUserID).Error; err != nil {
+			return err
+		}
+
+		// SQL: SELECT * FROM group_members WHERE group_id = ? AND user_id = ? LIMIT 1;
+		var member models.GroupMember
+		if err := tx.Where("group_id = ? AND user_id = ?", group.ID, currentUser.Id).First(&member).Error; err != nil {
+			return err
+		}
+
+		// The owner and the recipient swap places when the recipient was an
+		// admin; otherwise the previous owner only stays an admin if there is room
+		stepDownTo := models.RoleAdmin
+		if member.Role != models.RoleAdmin && !canAddAdminTx(tx, group.ID) {
+			stepDownTo = models.RoleMember
+		}
+
+		// SQL: UPDATE group_members SET role = ? WHERE group_id = ? AND user_id = ?;
+		err := tx.Model(&models.GroupMember{}).
+			Where("group_id = ? AND user_id = ?", group.ID, previousOwner.Id).
+			Update("role", stepDownTo).Error
+		if err != nil {
+			return err
+		}
+		if stepDownTo == models.RoleMember {
+			if err := recordMembership(tx, group.ID, previousOwner.Id, currentUser.Id, models.MembershipDemoted); err != nil {
+				return err
+			}
+		}
+
+		// SQL: UPDATE group_members SET role = 'owner' WHERE id = ?;
+		if err := tx.Model(&member).Update("role", models.RoleOwner).Error; err != nil {
+			return err
+		}
+		if err := respondToTransfer(tx, &transfer, models.TransferAccepted); err != nil {
+			return err
+		}
+		if err := cancelPendingTransfers(tx, group.ID, previousOwner.Id); err != nil {
+			return err
+		}
+		if err := recordMembership(tx, group.ID, currentUser.Id, currentUser.Id, models.MembershipTransferAccepted); err != nil {
+			return err
+		}
+		err = recordAudit(tx, group.ID, currentUser.Id, models.AuditOwnerChanged, auditUser(currentUser.Id),
+			gin.H{"owner": previousOwner.Id}, gin.H{"owner": currentUser.Id, "previous_owner_role": stepDownTo})
+		if err != nil {
+			return err
+		}
+
+		conv, err := groupConversationTx(tx, group.ID)
+		if err != nil {
+			return err
+		}
+		_, err = PostSystemMessage(tx, conv.ID, currentUser.Id, ownerChangedEvent(currentUser, currentUser, &previousOwner))
+		return err
+	})
+	if errors.Is(err, errStaleTransfer) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This transfer is no longer valid"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not accept transfer"})
+		return
+	}
+
+	realtime.Publish(realtime.GroupTopic(group.ID), realtime.Event{Type: "owner.changed", Data: gin.H{"group_id": group.ID, "user_id": currentUser.Id}})
+	c.JSON(http.StatusOK, gin.H{"message": "You are now the owner of the group"})
+}
+
+// findPendingTransfer loads the pending transfer matching the condition,
+// writing an error response and returning false if there is none.
+func findPendingTransfer(c *gin.Context, query string, args ...any) (models.OwnershipTransfer, bool) {
+	var transfer models.OwnershipTransfer
+
+	// SQL: SELECT * FROM ownership_transfers WHERE <query> AND status = 'pending' LIMIT 1;
+	err := initializers.DB.Where(query, args...).Where("status = ?", models.TransferPending).First(&transfer).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No pending ownership transfer"})
+		return transfer, false
+	}
+
+	return transfer, true
+}
+
+// lockPendingTransfer reloads a transfer and locks it until the transaction
+// ends, returning errStaleTransfer if it was answered in the meantime.
+func lockPendingTransfer(tx *gorm.DB, transfer *models.OwnershipTransfer) error {
+	// SQL: SELECT * FROM ownership_transfers WHERE id = ? FOR UPDATE;
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(transfer, transfer.ID).Error; err != nil {
+		return err
+	}
+	if transfer.Status != models.TransferPending {
+		return errStaleTransfer
+	}
+	return nil
+}
+
+// respondToTransfer closes a pending transfer with the given status.
+func respondToTransfer(tx *gorm.DB, transfer *models.OwnershipTransfer, status string) error {
+	now := time.Now()
+	transfer.Status = status
+	transfer.RespondedAt = &now
+	// SQL: UPDATE ownership_transfers SET status = ?, responded_at = ? WHERE id = ?;
+	return tx.Model(transfer).Updates(map[string]any{"status": status, "responded_at": now}).Error
+}
+
+// cancelPendingTransfers withdraws every pending offer made by a user in a group.
+func cancelPendingTransfers(tx *gorm.DB, groupID uint, fromUserID uint) error {
+	// SQL: UPDATE ownership_transfers SET status = 'cancelled', responded_at = ? WHERE group_id = ? AND from_user_id = ? AND status = 'pending';
+	return tx.Model(&models.OwnershipTransfer{}).
+		Where("group_id = ? AND from_user_id = ? AND status = ?", groupID, fromUserID, models.TransferPending).
+		Updates(map[string]any{"status": models.TransferCancelled, "responded_at": time.Now()}).Error
+}
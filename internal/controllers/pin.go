@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"MessagingSystemBackend/internal/realtime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PinMessage pins a message in its group.
+func PinMessage(c *gin.Context) {
+	setPinned(c, true)
+}
+
+// UnpinMessage removes a pinned message from its group's pins.
+func UnpinMessage(c *gin.Context) {
+	setPinned(c, false)
+}
+
+// setPinned pins or unpins the group message addressed by the URL.
+func setPinned(c *gin.Context, pinned bool) {
+	user := c.MustGet("user").(models.User)
+
+	msg, ok := findGroupMessage(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, msg.GroupID, user.Id, models.CapPin, "You cannot pin messages in this group") {
+		return
+	}
+
+	updates := map[string]any{"pinned_at": nil, "pinned_by": nil}
+	eventType := "message.unpinned"
+	action := models.AuditMessageUnpinned
+	if pinned {
+		updates = map[string]any{"pinned_at": time.Now(), "pinned_by": user.Id}
+		eventType = "message.pinned"
+		action = models.AuditMessagePinned
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// SQL: UPDATE messages SET pinned_at = ?, pinned_by = ? WHERE id = ?;
+		if err := tx.Model(&models.Message{}).Where("id = ?", msg.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		err := recordAudit(tx, msg.GroupID, user.Id, action, auditMessage(msg.ID, msg.SenderID),
+			gin.H{"pinned_at": msg.PinnedAt, "pinned_by": msg.PinnedBy}, updates)
+		if err != nil {
+			return err
+		}
+		_, err = PostSystemMessage(tx, msg.ConversationID, user.Id, messagePinnedEvent(user, msg.ID, pinned))
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update pin"})
+		return
+	}
+
+	realtime.Publish(realtime.GroupTopic(msg.GroupID), realtime.Event{Type: eventType, Data: gin.H{"group_id": msg.GroupID, "message_id": msg.ID}})
+	if pinned {
+		c.JSON(http.StatusOK, gin.H{"message": "Message pinned"})
+	} else {
+		c.JSON(http.StatusOK, gin.H{"message": "Message unpinned"})
+	}
+}
+
+// ListPinnedMessages returns the pinned messages of a group, most recently pinned first.
+func ListPinnedMessages(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	group, ok := findGroup(c)
+	if !ok {
+		return
+	}
+
+	if !IsGroupMember(group.ID, user.Id) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	conv, err := GroupConversation(group.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load pinned messages"})
+		return
+	}
+
+	// SQL: SELECT * FROM messages WHERE conversation_id = ? AND pinned_at IS NOT NULL ORDER BY pinned_at DESC;
+	var messages []models.Message
+	initializers.DB.Where("conversation_id = ? AND pinned_at IS NOT NULL", conv.ID).Order("pinned_at DESC").Find(&messages)
+
+	resp := []gin.H{}
+	for _, msg := range messages {
+		resp = append(resp, MessageResponse(msg))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// groupMessage is a message together with the group it was posted in.
+type groupMessage struct {
+	models.Message
+	GroupID uint
+}
+
+// findGroupMessage loads the message addressed by the :id and :messageId URL
+// params, writing an error response and returning false unless it was posted
+// in that group.
+func findGroupMessage(c *gin.Context) (groupMessage, bool) {
+	var msg models.Message
+
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return groupMessage{}, false
+	}
+
+	messageID, err := strconv.Atoi(c.Param("messageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return groupMessage{}, false
+	}
+
+	// SQL: SELECT messages.* FROM messages JOIN conversations ON conversations.id = messages.conversation_id
+	//        WHERE messages.id = ? AND conversations.group_id = ? LIMIT 1;
+	err = initializers.DB.Joins("JOIN conversations ON conversations.id = messages.conversation_id").
+		Where("conversations.group_id = ?", groupID).
+		First(&msg, messageID).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return groupMessage{}, false
+	}
+
+	return groupMessage{Message: msg, GroupID: uint(groupID)}, true
+}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// denial is why the policy refused a capability.
+type denial int
+
+const (
+	granted         denial = iota
+	deniedNotMember        // The user is not in the group
+	deniedReadOnly         // The group is archived or awaiting deletion
+	deniedMuted            // The user is muted and the capability is post or vote
+	deniedRole             // The user's role lacks the capability
+)
+
+// authOption relaxes a capability check.
+type authOption int
+
+const (
+	// evenIfReadOnly allows the capability in archived groups and groups
+	// awaiting deletion, for actions that read or undo that state.
+	evenIfReadOnly authOption = iota + 1
+)
+
+// Authorize reports whether a user may use a capability in a group. Every
+// group permission check goes through here: the user's role is looked up and
+// the group's overrides for that role are applied on top of the defaults.
+func Authorize(groupID uint, userID uint, capability string, options ...authOption) bool {
+	return authorizeTx(initializers.DB, groupID, userID, capability, options...) == granted
+}
+
+// authorizeTx is Authorize inside a transaction, returning why the capability
+// was refused. Muted members may not post or vote.
+func authorizeTx(tx *gorm.DB, groupID uint, userID uint, capability string, options ...authOption) denial {
+	role, ok := groupRoleTx(tx, groupID, userID)
+	if !ok {
+		return deniedNotMember
+	}
+	if !hasOption(options, evenIfReadOnly) && groupReadOnlyTx(tx, groupID) {
+		return deniedReadOnly
+	}
+	if (capability == models.CapPost || capability == models.CapVote) && mutedUntilTx(tx, groupID, userID) != nil {
+		return deniedMuted
+	}
+	if !roleAllows(tx, groupID, role, capability) {
+		return deniedRole
+	}
+	return granted
+}
+
+// hasOption reports whether option is among options.
+func hasOption(options []authOption, option authOption) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// groupReadOnlyTx reports whether a group is archived or awaiting deletion.
+// Every capability is denied in such groups.
+func groupReadOnlyTx(tx *gorm.DB, groupID uint) bool {
+	var group models.Group
+	// SQL: SELECT * FROM groups WHERE id = ? LIMIT 1;
+	if err := tx.First(&group, groupID).Error; err != nil {
+		return true
+	}
+	return group.IsReadOnly()
+}
+
+// GroupRole returns a user's role in a group, and false if they are not a member.
+func GroupRole(groupID uint, userID uint) (string, bool) {
+	return groupRoleTx(initializers.DB, groupID, userID)
+}
+
+// groupRoleTx is GroupRole inside a transaction.
+func groupRoleTx(tx *gorm.DB, groupID uint, userID uint) (string, bool) {
+	var member models.GroupMember
+	// SQL: SELECT * FROM group_members WHERE group_id = ? AND user_id = ? LIMIT 1;
+	if err := tx.Where("group_id = ? AND user_id = ?", groupID, userID).First(&member).Error; err != nil {
+		return "", false
+	}
+	return member.Role, true
+}
+
+// roleAllows reports whether a role may use a capability in a group. The
+// owner always keeps every capability, and capabilities that cannot be
+// configured are never overridden. Posting is further limited by the group's
+// posting policy.
+func roleAllows(tx *gorm.DB, groupID uint, role string, capability string) bool {
+	allowed := models.DefaultCapabilities[role][capability]
+	if role == models.RoleOwner || !models.IsConfigurableCapability(capability) {
+		return allowed
+	}
+
+	if capability == models.CapPost {
+		var group models.Group
+		// SQL: SELECT * FROM groups WHERE id = ? LIMIT 1;
+		if err := tx.First(&group, groupID).Error; err != nil || !group.PostingAllows(role) {
+			return false
+		}
+	}
+
+	var override models.GroupPermissionOverride
+	// SQL: SELECT * FROM group_permission_overrides WHERE group_id = ? AND role = ? AND capability = ? LIMIT 1;
+	err := tx.Where("group_id = ? AND role = ? AND capability = ?", groupID, role, capability).First(&override).Error
+	if err == nil {
+		return override.Allowed
+	}
+	return allowed
+}
+
+// EffectivePermissions returns what every role may do in a group once its
+// overrides and posting policy are applied.
+func EffectivePermissions(groupID uint) map[string]map[string]bool {
+	permissions := map[string]map[string]bool{}
+	for _, role := range models.Roles {
+		permissions[role] = map[string]bool{}
+		for _, capability := range models.Capabilities {
+			permissions[role][capability] = models.DefaultCapabilities[role][capability]
+		}
+	}
+
+	// SQL: SELECT * FROM group_permission_overrides WHERE group_id = ?;
+	var overrides []models.GroupPermissionOverride
+	initializers.DB.Where("group_id = ?", groupID).Find(&overrides)
+	for _, o := range overrides {
+		if _, known := permissions[o.Role]; known && o.Role != models.RoleOwner {
+			permissions[o.Role][o.Capability] = o.Allowed
+		}
+	}
+
+	// SQL: SELECT * FROM groups WHERE id = ? LIMIT 1;
+	var group models.Group
+	if err := initializers.DB.First(&group, groupID).Error; err == nil {
+		for role := range permissions {
+			if !group.PostingAllows(role) {
+				permissions[role][models.CapPost] = false
+			}
+		}
+	}
+
+	return permissions
+}
+
+// requireCapability writes an error response and returns false unless the
+// user may use the capability in the group. denied is the error shown to
+// members who lack it; read-only groups and muted members get their own error.
+func requireCapability(c *gin.Context, groupID uint, userID uint, capability string, denied string, options ...authOption) bool {
+	switch authorizeTx(initializers.DB, groupID, userID, capability, options...) {
+	case granted:
+		return true
+	case deniedNotMember:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this group"})
+	case deniedReadOnly:
+		c.JSON(http.StatusForbidden, gin.H{"error": "This group is archived or scheduled for deletion"})
+	case deniedMuted:
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are muted in this group", "muted_until": mutedUntilTx(initializers.DB, groupID, userID)})
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": denied})
+	}
+	return false
+}
+
+// outranks reports whether a member with role actor may manage a member with
+// role target: nobody manages the owner, and nobody manages a higher role.
+func outranks(actor string, target string) bool {
+	return target != models.RoleOwner && models.RoleRank(actor) >= models.RoleRank(target)
+}
+
+// grantableRoleTx returns role if grantorID may still hand it out in the
+// group, or member otherwise. Invitations and invite links carry a role chosen
+// when they were made; the grantor may since have been demoted or have left.
+func grantableRoleTx(tx *gorm.DB, groupID uint, grantorID uint, role string) string {
+	if models.RoleRank(role) <= models.RoleRank(models.RoleMember) {
+		return role
+	}
+	grantorRole, _ := groupRoleTx(tx, groupID, grantorID)
+	if authorizeTx(tx, groupID, grantorID, models.CapManageRoles) != granted || !outranks(grantorRole, role) {
+		return models.RoleMember
+	}
+	return role
+}
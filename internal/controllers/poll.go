@@ -0,0 +1,481 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"MessagingSystemBackend/internal/realtime"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	minPollOptions = 2
+	maxPollOptions = 10
+)
+
+// CreatePoll posts a new poll message to a group.
+// Expects the question, options and poll settings in the JSON body.
+func CreatePoll(c *gin.Context) {
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// SQL: SELECT * FROM groups WHERE id = ? LIMIT 1;
+	var group models.Group
+	if err := initializers.DB.First(&group, groupID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	if !requireCapability(c, group.ID, user.Id, models.CapPost, "You cannot post in this group") {
+		return
+	}
+
+	var body struct {
+		Question       string     `json:"question"`
+		Options        []string   `json:"options"`
+		MultipleChoice bool       `json:"multiple_choice"`
+		Anonymous      bool       `json:"anonymous"`
+		ClosesAt       *time.Time `json:"closes_at"`
+	}
+	if err := c.Bind(&body); err != nil || strings.TrimSpace(body.Question) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid poll question"})
+		return
+	}
+
+	if len(body.Options) < minPollOptions || len(body.Options) > maxPollOptions {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A poll needs between 2 and 10 options"})
+		return
+	}
+
+	// Reject blank and duplicate options
+	seen := map[string]struct{}{}
+	for _, opt := range body.Options {
+		key := strings.ToLower(strings.TrimSpace(opt))
+		if key == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Poll options cannot be empty"})
+			return
+		}
+		if _, ok := seen[key]; ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Poll options must be unique"})
+			return
+		}
+		seen[key] = struct{}{}
+	}
+
+	if body.ClosesAt != nil && !body.ClosesAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Close time must be in the future"})
+		return
+	}
+
+	poll := models.Poll{
+		GroupID:        group.ID,
+		CreatedBy:      user.Id,
+		Question:       strings.TrimSpace(body.Question),
+		MultipleChoice: body.MultipleChoice,
+		Anonymous:      body.Anonymous,
+		ClosesAt:       body.ClosesAt,
+		CreatedAt:      time.Now(),
+	}
+	for i, opt := range body.Options {
+		poll.Options = append(poll.Options, models.PollOption{Position: i, Text: strings.TrimSpace(opt)})
+	}
+
+	conv, err := GroupConversation(group.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create poll"})
+		return
+	}
+
+	// The poll is carried by a regular group message so it shows up in the
+	// chat, and counts as one under slow mode
+	var retryAt time.Time
+	err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if retryAt, err = slowModeRetryAtTx(tx, group.ID, conv.ID, user.Id); err != nil {
+			return err
+		}
+		if time.Now().Before(retryAt) {
+			return errSlowMode
+		}
+		msg, err := postMessageTx(tx, conv.ID, user.Id, poll.Question)
+		if err != nil {
+			return err
+		}
+
+		poll.MessageID = msg.ID
+		poll.CreatedAt = msg.CreatedAt
+		// SQL: INSERT INTO polls (...) VALUES (...); INSERT INTO poll_options (...) VALUES (...);
+		return tx.Create(&poll).Error
+	})
+	if errors.Is(err, errSlowMode) {
+		slowModeError(c, retryAt)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create poll"})
+		return
+	}
+
+	results := PollResults(poll, false)
+	realtime.Publish(realtime.GroupTopic(group.ID), realtime.Event{Type: "poll.created", Data: results})
+
+	c.JSON(http.StatusOK, results)
+}
+
+// ListPolls returns the polls of a group, newest first.
+func ListPolls(c *gin.Context) {
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+
+	if !IsGroupMember(uint(groupID), user.Id) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// SQL: SELECT * FROM polls WHERE group_id = ? ORDER BY created_at DESC;
+	//      SELECT * FROM poll_options WHERE poll_id IN (...) ORDER BY position;
+	var polls []models.Poll
+	initializers.DB.
+		Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Where("group_id = ?", groupID).
+		Order("created_at DESC").
+		Find(&polls)
+
+	resp := []gin.H{}
+	for _, poll := range polls {
+		resp = append(resp, PollResults(poll, false))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetPoll returns the current results of a poll together with its vote history.
+func GetPoll(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	poll, ok := findGroupPoll(c)
+	if !ok {
+		return
+	}
+
+	if !IsGroupMember(poll.GroupID, user.Id) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	results := PollResults(poll, true)
+
+	// SQL: SELECT option_id FROM poll_votes WHERE poll_id = ? AND user_id = ?;
+	var myVotes []uint
+	initializers.DB.Model(&models.PollVote{}).
+		Where("poll_id = ? AND user_id = ?", poll.ID, user.Id).
+		Pluck("option_id", &myVotes)
+	results["my_votes"] = myVotes
+
+	c.JSON(http.StatusOK, results)
+}
+
+// VotePoll records the caller's ballot, replacing any previous choice.
+// An empty option list retracts the caller's votes.
+func VotePoll(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	poll, ok := findGroupPoll(c)
+	if !ok {
+		return
+	}
+
+	if !requireCapability(c, poll.GroupID, user.Id, models.CapVote, "You cannot vote in this group") {
+		return
+	}
+
+	var body struct {
+		OptionIDs []uint `json:"option_ids"`
+	}
+	if err := c.Bind(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vote"})
+		return
+	}
+
+	if !poll.MultipleChoice && len(body.OptionIDs) > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This poll allows a single choice"})
+		return
+	}
+
+	// Every chosen option must belong to this poll
+	validOptions := map[uint]struct{}{}
+	for _, opt := range poll.Options {
+		validOptions[opt.ID] = struct{}{}
+	}
+	chosen := map[uint]struct{}{}
+	for _, id := range body.OptionIDs {
+		if _, ok := validOptions[id]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown poll option"})
+			return
+		}
+		chosen[id] = struct{}{}
+	}
+
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the poll so concurrent ballots from the same user cannot interleave
+		// SQL: SELECT * FROM polls WHERE id = ? FOR UPDATE;
+		var locked models.Poll
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, poll.ID).Error; err != nil {
+			return err
+		}
+		if locked.IsClosed(time.Now()) {
+			return errPollClosed
+		}
+
+		// SQL: SELECT * FROM poll_votes WHERE poll_id = ? AND user_id = ?;
+		var existing []models.PollVote
+		if err := tx.Where("poll_id = ? AND user_id = ?", poll.ID, user.Id).Find(&existing).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		current := map[uint]struct{}{}
+		for _, vote := range existing {
+			current[vote.OptionID] = struct{}{}
+			if _, keep := chosen[vote.OptionID]; keep {
+				continue
+			}
+			// SQL: DELETE FROM poll_votes WHERE id = ?;
+			if err := tx.Delete(&vote).Error; err != nil {
+				return err
+			}
+			history := models.PollVoteHistory{PollID: poll.ID, OptionID: vote.OptionID, UserID: user.Id, Action: "retracted", CreatedAt: now}
+			if err := tx.Create(&history).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, id := range body.OptionIDs {
+			if _, already := current[id]; already {
+				continue
+			}
+			// SQL: INSERT INTO poll_votes (poll_id, option_id, user_id, created_at) VALUES (?, ?, ?, ?);
+			vote := models.PollVote{PollID: poll.ID, OptionID: id, UserID: user.Id, CreatedAt: now}
+			if err := tx.Create(&vote).Error; err != nil {
+				return err
+			}
+			history := models.PollVoteHistory{PollID: poll.ID, OptionID: id, UserID: user.Id, Action: "voted", CreatedAt: now}
+			if err := tx.Create(&history).Error; err != nil {
+				return err
+			}
+			current[id] = struct{}{}
+		}
+		return nil
+	})
+	if errors.Is(err, errPollClosed) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Poll is closed"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vote"})
+		return
+	}
+
+	results := PollResults(poll, false)
+	realtime.Publish(realtime.GroupTopic(poll.GroupID), realtime.Event{Type: "poll.updated", Data: results})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Vote recorded", "poll": results})
+}
+
+// ClosePoll closes a poll before its close time. Only the poll's creator, while
+// they may still post, and members who may moderate others' messages may close it.
+func ClosePoll(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	poll, ok := findGroupPoll(c)
+	if !ok {
+		return
+	}
+
+	// The creator closes their poll as they would post in the group, so
+	// archived groups, mutes and the posting policy apply to them too
+	capability, denied := models.CapDeleteMessages, "Only an admin can close this poll"
+	if poll.CreatedBy == user.Id {
+		capability, denied = models.CapPost, "You can no longer post in this group"
+	}
+	if !requireCapability(c, poll.GroupID, user.Id, capability, denied) {
+		return
+	}
+
+	if poll.IsClosed(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Poll is already closed"})
+		return
+	}
+
+	now := time.Now()
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// SQL: UPDATE polls SET closed_at = ?, closed_by = ? WHERE id = ? AND closed_at IS NULL;
+		result := tx.Model(&models.Poll{}).
+			Where("id = ? AND closed_at IS NULL", poll.ID).
+			Updates(map[string]any{"closed_at": now, "closed_by": user.Id})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errPollClosed
+		}
+		return recordAudit(tx, poll.GroupID, user.Id, models.AuditPollClosed, auditMessage(poll.MessageID, poll.CreatedBy),
+			gin.H{"poll_id": poll.ID, "closed_at": nil}, gin.H{"poll_id": poll.ID, "closed_at": now})
+	})
+	if errors.Is(err, errPollClosed) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Poll is already closed"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close poll"})
+		return
+	}
+
+	poll.ClosedAt = &now
+	poll.ClosedBy = &user.Id
+
+	results := PollResults(poll, false)
+	realtime.Publish(realtime.GroupTopic(poll.GroupID), realtime.Event{Type: "poll.closed", Data: results})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Poll closed", "poll": results})
+}
+
+var errPollClosed = errors.New("poll is closed")
+
+// findGroupPoll loads the poll addressed by the :id and :pollId URL params,
+// writing an error response and returning false if it does not exist.
+func findGroupPoll(c *gin.Context) (models.Poll, bool) {
+	var poll models.Poll
+
+	groupID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return poll, false
+	}
+
+	pollID, err := strconv.Atoi(c.Param("pollId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid poll ID"})
+		return poll, false
+	}
+
+	// SQL: SELECT * FROM polls WHERE id = ? AND group_id = ? LIMIT 1;
+	//      SELECT * FROM poll_options WHERE poll_id = ? ORDER BY position;
+	err = initializers.DB.
+		Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Where("group_id = ?", groupID).
+		First(&poll, pollID).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Poll not found"})
+		return poll, false
+	}
+
+	return poll, true
+}
+
+// PollResults builds the tally of a poll. Voter names and history entries are
+// only revealed for polls that are not anonymous.
+func PollResults(poll models.Poll, withHistory bool) gin.H {
+	var votes []struct {
+		OptionID uint
+		UserID   uint
+		Username string
+	}
+	// SQL: SELECT v.option_id, v.user_id, u.username FROM poll_votes v
+	//      JOIN users u ON u.id = v.user_id WHERE v.poll_id = ? ORDER BY v.created_at;
+	initializers.DB.Raw(`
+		SELECT v.option_id, v.user_id, u.username
+		FROM poll_votes v
+		JOIN users u ON u.id = v.user_id
+		WHERE v.poll_id = ?
+		ORDER BY v.created_at
+	`, poll.ID).Scan(&votes)
+
+	counts := map[uint]int{}
+	voters := map[uint][]string{}
+	distinctVoters := map[uint]struct{}{}
+	for _, v := range votes {
+		counts[v.OptionID]++
+		voters[v.OptionID] = append(voters[v.OptionID], v.Username)
+		distinctVoters[v.UserID] = struct{}{}
+	}
+
+	options := []gin.H{}
+	for _, opt := range poll.Options {
+		entry := gin.H{
+			"id":    opt.ID,
+			"text":  opt.Text,
+			"votes": counts[opt.ID],
+		}
+		if !poll.Anonymous {
+			entry["voters"] = voters[opt.ID]
+		}
+		options = append(options, entry)
+	}
+
+	results := gin.H{
+		"id":              poll.ID,
+		"group_id":        poll.GroupID,
+		"message_id":      poll.MessageID,
+		"created_by":      poll.CreatedBy,
+		"question":        poll.Question,
+		"multiple_choice": poll.MultipleChoice,
+		"anonymous":       poll.Anonymous,
+		"closes_at":       poll.ClosesAt,
+		"closed":          poll.IsClosed(time.Now()),
+		"closed_at":       poll.ClosedAt,
+		"options":         options,
+		"total_voters":    len(distinctVoters),
+	}
+
+	if withHistory {
+		var history []struct {
+			OptionID  uint
+			Username  string
+			Action    string
+			CreatedAt time.Time
+		}
+		// SQL: SELECT h.option_id, u.username, h.action, h.created_at FROM poll_vote_histories h
+		//      JOIN users u ON u.id = h.user_id WHERE h.poll_id = ? ORDER BY h.created_at, h.id;
+		initializers.DB.Raw(`
+			SELECT h.option_id, u.username, h.action, h.created_at
+			FROM poll_vote_histories h
+			JOIN users u ON u.id = h.user_id
+			WHERE h.poll_id = ?
+			ORDER BY h.created_at, h.id
+		`, poll.ID).Scan(&history)
+
+		entries := []gin.H{}
+		for _, h := range history {
+			entry := gin.H{
+				"option_id":  h.OptionID,
+				"action":     h.Action,
+				"created_at": h.CreatedAt,
+			}
+			if !poll.Anonymous {
+				entry["username"] = h.Username
+			}
+			entries = append(entries, entry)
+		}
+		results["history"] = entries
+	}
+
+	return results
+}
@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+
+	// Sentinels wrapped around matches by ts_headline. They are swapped for
+	// <mark> tags after the snippet has been HTML-escaped.
+	highlightStart = "\x01"
+	highlightStop  = "\x02"
+)
+
+// SearchMessages runs a full-text search over the conversations the user
+// takes part in.
+// Query params: q (required), type (dm|group), conversation_id, community_id
+// (the channels of one community), sender_id, from, to (RFC3339 or
+// YYYY-MM-DD), page and limit.
+func SearchMessages(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query required"})
+		return
+	}
+
+	chatType := c.Query("type")
+	if chatType != "" && chatType != "dm" && chatType != "group" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat type"})
+		return
+	}
+
+	var conversationID, communityID, senderID int
+	var err error
+	if v := c.Query("conversation_id"); v != "" {
+		if conversationID, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+			return
+		}
+	}
+	if v := c.Query("community_id"); v != "" {
+		if communityID, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid community ID"})
+			return
+		}
+	}
+	if v := c.Query("sender_id"); v != "" {
+		if senderID, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sender ID"})
+			return
+		}
+	}
+
+	from, err := parseSearchDate(c.Query("from"), false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date"})
+		return
+	}
+	to, err := parseSearchDate(c.Query("to"), true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date"})
+		return
+	}
+
+	page, limit := parsePagination(c, defaultSearchLimit, maxSearchLimit)
+
+	// Only rows of conversations the user is a member of are touched
+	sql := `
+		SELECT CASE c.kind WHEN 'direct' THEN 'dm' ELSE 'group' END AS type,
+			msg.id AS message_id, msg.conversation_id,
+			c.group_id, g.name AS group_name, g.community_id, co.name AS community_name,
+			msg.sender_id, u.username AS sender_username, msg.content,
+			ts_rank(msg.content_tsv, query.q) AS rank, msg.created_at
+		FROM messages msg
+		CROSS JOIN query
+		JOIN conversations c ON c.id = msg.conversation_id
+		JOIN conversation_members me ON me.conversation_id = msg.conversation_id AND me.user_id = ?
+		JOIN users u ON u.id = msg.sender_id
+		LEFT JOIN groups g ON g.id = c.group_id
+		LEFT JOIN communities co ON co.id = g.community_id
+		WHERE msg.content_tsv @@ query.q AND msg.kind = 'user' AND g.purge_at IS NULL`
+	args := []any{user.Id}
+
+	switch chatType {
+	case "dm":
+		sql += ` AND c.kind = ?`
+		args = append(args, models.ConversationDirect)
+	case "group":
+		sql += ` AND c.kind = ?`
+		args = append(args, models.ConversationGroup)
+	}
+	if conversationID != 0 {
+		sql += ` AND msg.conversation_id = ?`
+		args = append(args, conversationID)
+	}
+	if communityID != 0 {
+		sql += ` AND g.community_id = ?`
+		args = append(args, communityID)
+	}
+	sql, args = appendSearchFilters(sql, args, "msg", senderID, from, to)
+
+	var results []struct {
+		Type           string
+		MessageID      uint
+		ConversationID uint
+		GroupID        *uint
+		GroupName      *string
+		CommunityID    *uint
+		CommunityName  *string
+		SenderID       uint
+		SenderUsername string
+		Snippet        string
+		Rank           float64
+		CreatedAt      time.Time
+		Total          int64
+	}
+
+	// SQL:
+	// WITH query AS (SELECT websearch_to_tsquery('english', {q}) AS q)
+	// SELECT ..., ts_headline(content, q) AS snippet
+	// FROM (SELECT *, COUNT(*) OVER () AS total FROM ({matches}) ORDER BY rank DESC LIMIT ? OFFSET ?)
+	// ORDER BY rank DESC, created_at DESC;
+	query := `
+		WITH query AS (SELECT websearch_to_tsquery('english', ?) AS q)
+		SELECT r.type, r.message_id, r.conversation_id, r.group_id, r.group_name, r.community_id, r.community_name,
+			r.sender_id, r.sender_username,
+			ts_headline('english', r.content, (SELECT q FROM query), ?) AS snippet,
+			r.rank, r.created_at, r.total
+		FROM (
+			SELECT s.*, COUNT(*) OVER () AS total
+			FROM (` + sql + `) s
+			ORDER BY s.rank DESC, s.created_at DESC
+			LIMIT ? OFFSET ?
+		) r
+		ORDER BY r.rank DESC, r.created_at DESC`
+
+	headlineOptions := "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", MaxFragments=2, MaxWords=20, MinWords=5"
+	args = append([]any{q, headlineOptions}, args...)
+	args = append(args, limit, (page-1)*limit)
+
+	if err := initializers.DB.Raw(query, args...).Scan(&results).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		return
+	}
+
+	var total int64
+	resp := []gin.H{}
+	for _, r := range results {
+		total = r.Total
+		entry := gin.H{
+			"type":            r.Type,
+			"message_id":      r.MessageID,
+			"conversation_id": r.ConversationID,
+			"sender_id":       r.SenderID,
+			"sender_username": r.SenderUsername,
+			"snippet":         highlightSnippet(r.Snippet),
+			"rank":            r.Rank,
+			"created_at":      r.CreatedAt,
+		}
+		if r.GroupID != nil {
+			entry["group_id"] = r.GroupID
+			entry["group_name"] = r.GroupName
+		}
+		if r.CommunityID != nil {
+			entry["community_id"] = r.CommunityID
+			entry["community_name"] = r.CommunityName
+		}
+		resp = append(resp, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": resp,
+		"page":    page,
+		"limit":   limit,
+		"total":   total,
+	})
+}
+
+// appendSearchFilters adds the optional sender and date range conditions for
+// the table aliased as alias.
+func appendSearchFilters(sql string, args []any, alias string, senderID int, from, to *time.Time) (string, []any) {
+	if senderID != 0 {
+		sql += ` AND ` + alias + `.sender_id = ?`
+		args = append(args, senderID)
+	}
+	if from != nil {
+		sql += ` AND ` + alias + `.created_at >= ?`
+		args = append(args, *from)
+	}
+	if to != nil {
+		sql += ` AND ` + alias + `.created_at < ?`
+		args = append(args, *to)
+	}
+	return sql, args
+}
+
+// parseSearchDate accepts RFC3339 timestamps or plain dates. A plain date used
+// as an upper bound covers the whole day.
+func parseSearchDate(value string, endOfRange bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfRange {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// parsePagination reads the page and limit query params, falling back to
+// defaults for missing or out-of-range values.
+func parsePagination(c *gin.Context, defaultLimit, maxLimit int) (int, int) {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	return page, limit
+}
+
+// highlightSnippet escapes a snippet for safe display and marks the matched terms.
+func highlightSnippet(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, highlightStop, "</mark>")
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxSlowModeSeconds is the longest interval slow mode can require between
+// two messages of a member.
+const maxSlowModeSeconds = 6 * 60 * 60
+
+// errSlowMode is returned when a member posts again before slow mode allows it.
+var errSlowMode = errors.New("posting too soon in slow mode")
+
+// postGroupMessage stores a member's message in a group conversation,
+// enforcing the group's slow mode. It writes an error response and returns
+// false if the message was not stored; members who post too soon get a 429
+// telling them when they may post again.
+func postGroupMessage(c *gin.Context, groupID uint, conversationID uint, userID uint, content string) (models.Message, bool) {
+	var msg models.Message
+	var retryAt time.Time
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if retryAt, err = slowModeRetryAtTx(tx, groupID, conversationID, userID); err != nil {
+			return err
+		}
+		if time.Now().Before(retryAt) {
+			return errSlowMode
+		}
+		msg, err = postMessageTx(tx, conversationID, userID, content)
+		return err
+	})
+	if errors.Is(err, errSlowMode) {
+		slowModeError(c, retryAt)
+		return msg, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+		return msg, false
+	}
+	return msg, true
+}
+
+// slowModeError writes the 429 response for a member who may only post again at retryAt.
+func slowModeError(c *gin.Context, retryAt time.Time) {
+	wait := int(math.Ceil(time.Until(retryAt).Seconds()))
+	c.Header("Retry-After", strconv.Itoa(wait))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":               fmt.Sprintf("Slow mode is on; you can post again in %d seconds", wait),
+		"retry_at":            retryAt,
+		"retry_after_seconds": wait,
+	})
+}
+
+// slowModeRetryAtTx returns when a member may next post in a group, which is
+// in the past unless slow mode holds them back. The owner and admins are
+// exempt. The member's row is locked so that concurrent posts of the same
+// member are checked one after another.
+func slowModeRetryAtTx(tx *gorm.DB, groupID uint, conversationID uint, userID uint) (time.Time, error) {
+	var group models.Group
+	// SQL: SELECT * FROM groups WHERE id = ? LIMIT 1;
+	if err := tx.First(&group, groupID).Error; err != nil {
+		return time.Time{}, err
+	}
+	if group.SlowModeSeconds == 0 {
+		return time.Time{}, nil
+	}
+
+	var member models.GroupMember
+	// SQL: SELECT * FROM group_members WHERE group_id = ? AND user_id = ? LIMIT 1 FOR UPDATE;
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("group_id = ? AND user_id = ?", groupID, userID).First(&member).Error
+	if err != nil {
+		return time.Time{}, err
+	}
+	if member.Role == models.RoleOwner || member.Role == models.RoleAdmin {
+		return time.Time{}, nil
+	}
+
+	var last models.Message
+	// SQL: SELECT * FROM messages WHERE conversation_id = ? AND sender_id = ? AND kind = 'user' ORDER BY created_at DESC LIMIT 1;
+	err = tx.Where("conversation_id = ? AND sender_id = ? AND kind = ?", conversationID, userID, models.MessageUser).
+		Order("created_at DESC").First(&last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return last.CreatedAt.Add(time.Duration(group.SlowModeSeconds) * time.Second), nil
+}
@@ -31,9 +31,12 @@ func SummarizeGroupMessages(c *gin.Context) {
 		return
 	}
 
-	// SQL: SELECT * FROM group_messages WHERE group_id = ? ORDER BY created_at DESC LIMIT 50;
-	var messages []models.GroupMessage
-	initializers.DB.Where("group_id = ?", group.ID).Order("created_at DESC").Limit(50).Find(&messages)
+	conv, err := GroupConversation(group.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+	messages := ConversationHistory(conv.ID, 50, nil)
 
 	if len(messages) == 0 {
 		c.JSON(http.StatusOK, gin.H{"summary": "No messages to summarize."})
@@ -49,8 +52,13 @@ func SummarizeGroupMessages(c *gin.Context) {
 	var msgInputs []MsgInput
 	participantsSet := map[string]struct{}{}
 
-	// Resolve sender usernames and build input list
+	// Resolve sender usernames and build input list. System messages are
+	// left out since nobody wrote them.
 	for _, msg := range messages {
+		if msg.Kind == models.MessageSystem {
+			continue
+		}
+
 		// SQL: SELECT * FROM users WHERE id = ? LIMIT 1;
 		var user models.User
 		initializers.DB.First(&user, msg.SenderID)
@@ -62,6 +70,10 @@ func SummarizeGroupMessages(c *gin.Context) {
 
 		participantsSet[user.Username] = struct{}{}
 	}
+	if len(msgInputs) == 0 {
+		c.JSON(http.StatusOK, gin.H{"summary": "No messages to summarize."})
+		return
+	}
 
 	// Construct prompt for LLM
 	prompt := "You are an assistant that summarizes group conversations. Given a list of user messages, return a concise summary.\n\nMessages:\n"
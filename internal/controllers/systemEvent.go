@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/models"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// systemEvent is an announcement for a group's timeline: its type (one of
+// the models.System* constants), the details clients need to render it in
+// their own language, and the English text for clients that do not.
+type systemEvent struct {
+	Type    string
+	Data    gin.H
+	Content string
+}
+
+// eventUser returns a user as referenced in the details of a system event.
+func eventUser(user models.User) gin.H {
+	return gin.H{"id": user.Id, "username": user.Username}
+}
+
+// groupCreatedEvent announces a new group or channel.
+func groupCreatedEvent(actor models.User, name string) systemEvent {
+	return systemEvent{
+		Type:    models.SystemGroupCreated,
+		Data:    gin.H{"actor": eventUser(actor), "name": name},
+		Content: fmt.Sprintf("%s created the group %q", actor.Username, name),
+	}
+}
+
+// memberAddedEvent announces a member added by someone else.
+func memberAddedEvent(actor models.User, member models.User, role string) systemEvent {
+	content := fmt.Sprintf("%s added %s", actor.Username, member.Username)
+	if role != models.RoleMember {
+		content = fmt.Sprintf("%s added %s as %s", actor.Username, member.Username, roleTitle(role))
+	}
+	return systemEvent{
+		Type:    models.SystemMemberAdded,
+		Data:    gin.H{"actor": eventUser(actor), "member": eventUser(member), "role": role},
+		Content: content,
+	}
+}
+
+// memberJoinedEvent announces a member who joined by themselves; via is the
+// membership action recording how. For an approved join request, actor is
+// the reviewer.
+func memberJoinedEvent(actor models.User, member models.User, via string) systemEvent {
+	var content string
+	switch via {
+	case models.MembershipJoinedByInvite:
+		content = fmt.Sprintf("%s joined using an invite link", member.Username)
+	case models.MembershipInviteAccepted:
+		content = fmt.Sprintf("%s accepted an invitation and joined", member.Username)
+	case models.MembershipJoinApproved:
+		content = fmt.Sprintf("%s approved %s's request to join", actor.Username, member.Username)
+	case models.MembershipJoinedCommunity:
+		content = fmt.Sprintf("%s joined the community", member.Username)
+	case models.MembershipJoinedChannel:
+		content = fmt.Sprintf("%s joined the channel", member.Username)
+	default:
+		content = fmt.Sprintf("%s joined the group", member.Username)
+	}
+	return systemEvent{
+		Type:    models.SystemMemberJoined,
+		Data:    gin.H{"actor": eventUser(actor), "member": eventUser(member), "via": via},
+		Content: content,
+	}
+}
+
+// memberDepartedEvent announces a member leaving, removed or banned, as told
+// by the membership action. A departure from the whole community is marked
+// as such.
+func memberDepartedEvent(actor models.User, member models.User, action string, fromCommunity bool) systemEvent {
+	event := systemEvent{
+		Type: models.SystemMemberRemoved,
+		Data: gin.H{"actor": eventUser(actor), "member": eventUser(member), "community": fromCommunity},
+	}
+	scope := "group"
+	if fromCommunity {
+		scope = "community"
+	}
+	switch action {
+	case models.MembershipLeft:
+		event.Type = models.SystemMemberLeft
+		event.Content = fmt.Sprintf("%s left the %s", member.Username, scope)
+	case models.MembershipBanned:
+		event.Type = models.SystemMemberBanned
+		event.Content = fmt.Sprintf("%s banned %s", actor.Username, member.Username)
+	default:
+		event.Content = fmt.Sprintf("%s removed %s", actor.Username, member.Username)
+		if fromCommunity {
+			event.Content += " from the community"
+		}
+	}
+	return event
+}
+
+// roleChangedEvent announces a member's new role, including promotions that
+// happen on their own when the last admin leaves.
+func roleChangedEvent(actor models.User, member models.User, previous string, role string) systemEvent {
+	content := fmt.Sprintf("%s is now %s", member.Username, roleTitle(role))
+	if previous == models.RoleAdmin && role == models.RoleMember {
+		content = fmt.Sprintf("%s is no longer an admin", member.Username)
+	}
+	return systemEvent{
+		Type:    models.SystemRoleChanged,
+		Data:    gin.H{"actor": eventUser(actor), "member": eventUser(member), "previous_role": previous, "role": role},
+		Content: content,
+	}
+}
+
+// ownerChangedEvent announces a new owner. previous is nil when the owner
+// left and the group passed on by itself.
+func ownerChangedEvent(actor models.User, owner models.User, previous *models.User) systemEvent {
+	event := systemEvent{
+		Type:    models.SystemOwnerChanged,
+		Data:    gin.H{"actor": eventUser(actor), "owner": eventUser(owner), "previous_owner": nil},
+		Content: fmt.Sprintf("%s is now the owner of the group", owner.Username),
+	}
+	if previous != nil {
+		event.Data["previous_owner"] = eventUser(*previous)
+		event.Content = fmt.Sprintf("%s transferred ownership of the group to %s", previous.Username, owner.Username)
+	}
+	return event
+}
+
+// profileChangedEvents announces each profile field that differs between
+// before and after.
+func profileChangedEvents(actor models.User, before models.Group, after models.Group) []systemEvent {
+	var events []systemEvent
+	add := func(field string, old string, value string, content string) {
+		events = append(events, systemEvent{
+			Type:    models.SystemProfileChanged,
+			Data:    gin.H{"actor": eventUser(actor), "field": field, "before": old, "after": value},
+			Content: content,
+		})
+	}
+
+	name := actor.Username
+	if before.Name != after.Name {
+		add("name", before.Name, after.Name, fmt.Sprintf("%s renamed the group from %q to %q", name, before.Name, after.Name))
+	}
+	if before.Description != after.Description {
+		add("description", before.Description, after.Description, fmt.Sprintf("%s changed the group description", name))
+	}
+	if before.Topic != after.Topic {
+		if after.Topic == "" {
+			add("topic", before.Topic, after.Topic, fmt.Sprintf("%s cleared the topic", name))
+		} else {
+			add("topic", before.Topic, after.Topic, fmt.Sprintf("%s changed the topic to %q", name, after.Topic))
+		}
+	}
+	if before.Visibility != after.Visibility {
+		add("visibility", before.Visibility, after.Visibility, fmt.Sprintf("%s made the group %s", name, after.Visibility))
+	}
+	if before.Color != after.Color {
+		add("color", before.Color, after.Color, fmt.Sprintf("%s changed the group color", name))
+	}
+	return events
+}
+
+// groupEvent announces something done to the group as a whole, such as
+// archiving it or changing its picture.
+func groupEvent(actor models.User, eventType string) systemEvent {
+	verbs := map[string]string{
+		models.SystemAvatarChanged:   "changed the group picture",
+		models.SystemAvatarRemoved:   "removed the group picture",
+		models.SystemGroupArchived:   "archived the group",
+		models.SystemGroupUnarchived: "unarchived the group",
+		models.SystemGroupDeleted:    "deleted the group",
+		models.SystemGroupRestored:   "restored the group",
+	}
+	return systemEvent{
+		Type:    eventType,
+		Data:    gin.H{"actor": eventUser(actor)},
+		Content: fmt.Sprintf("%s %s", actor.Username, verbs[eventType]),
+	}
+}
+
+// messagePinnedEvent announces a message being pinned or unpinned.
+func messagePinnedEvent(actor models.User, messageID uint, pinned bool) systemEvent {
+	event := systemEvent{
+		Type:    models.SystemMessageUnpinned,
+		Data:    gin.H{"actor": eventUser(actor), "message_id": messageID},
+		Content: fmt.Sprintf("%s unpinned a message", actor.Username),
+	}
+	if pinned {
+		event.Type = models.SystemMessagePinned
+		event.Content = fmt.Sprintf("%s pinned a message", actor.Username)
+	}
+	return event
+}
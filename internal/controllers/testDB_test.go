@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// These tests run against a real Postgres database, since the limits they
+// check rely on its row locks. They are skipped unless DATABASE_URL is set;
+// the schema is migrated on first use and every test creates its own rows.
+
+var (
+	testDBOnce sync.Once
+	testDBErr  error
+	testSeq    atomic.Int64
+)
+
+// requireTestDB points initializers.DB at DATABASE_URL, skipping the test if
+// it is unset.
+func requireTestDB(t *testing.T) {
+	t.Helper()
+
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+
+	testDBOnce.Do(func() {
+		gin.SetMode(gin.TestMode)
+		initializers.DB, testDBErr = gorm.Open(postgres.Open(dsn), &gorm.Config{
+			TranslateError: true,
+		})
+		if testDBErr == nil {
+			initializers.SyncDatabase()
+		}
+	})
+	if testDBErr != nil {
+		t.Fatalf("connecting to test database: %v", testDBErr)
+	}
+}
+
+// uniqueName returns a name no other test run has used.
+func uniqueName(prefix string) string {
+	return fmt.Sprintf("%s_%d_%d", prefix, time.Now().UnixNano(), testSeq.Add(1))
+}
+
+// createTestUser stores a user who may be added to groups directly.
+func createTestUser(t *testing.T) models.User {
+	t.Helper()
+
+	user := models.User{Username: uniqueName("user"), Password: "x", CreatedAt: time.Now(), GroupAddPolicy: models.GroupAddAnyone}
+	if err := initializers.DB.Create(&user).Error; err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	return user
+}
+
+// createTestGroup stores a group owned by owner with the given limits.
+func createTestGroup(t *testing.T, owner models.User, maxMembers int, maxAdmins int) models.Group {
+	t.Helper()
+
+	group := models.Group{Name: uniqueName("group"), CreatedBy: owner.Id, CreatedAt: time.Now(), MaxMembers: &maxMembers, MaxAdmins: &maxAdmins}
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&group).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.GroupMember{GroupID: group.ID, UserID: owner.Id, Role: models.RoleOwner, JoinedAt: time.Now()}).Error
+	})
+	if err != nil {
+		t.Fatalf("creating group: %v", err)
+	}
+	return group
+}
+
+// addTestMember stores a plain membership, bypassing the handlers.
+func addTestMember(t *testing.T, group models.Group, user models.User) {
+	t.Helper()
+
+	member := models.GroupMember{GroupID: group.ID, UserID: user.Id, Role: models.RoleMember, JoinedAt: time.Now()}
+	if err := initializers.DB.Create(&member).Error; err != nil {
+		t.Fatalf("adding member: %v", err)
+	}
+}
+
+// callHandler runs a handler as user with the given URL params and JSON body.
+func callHandler(handler gin.HandlerFunc, user models.User, params gin.Params, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = params
+	c.Set("user", user)
+	handler(c)
+	return w
+}
+
+// countMembers counts the members of a group holding one of roles, or all of
+// them if no role is given. It may be called from any goroutine.
+func countMembers(t *testing.T, groupID uint, roles ...string) int64 {
+	t.Helper()
+
+	query := initializers.DB.Model(&models.GroupMember{}).Where("group_id = ?", groupID)
+	if len(roles) > 0 {
+		query = query.Where("role IN ?", roles)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		t.Errorf("counting members: %v", err)
+	}
+	return count
+}
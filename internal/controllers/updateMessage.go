@@ -7,89 +7,76 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// EditMessage handles editing of a message in any conversation by its sender
+func EditMessage(c *gin.Context) {
+	editMessage(c, "")
+}
+
 // EditGroupMessage handles editing of a group message by its sender
 func EditGroupMessage(c *gin.Context) {
+	editMessage(c, models.ConversationGroup)
+}
+
+// EditDirectMessage handles editing of a direct (private) message
+func EditDirectMessage(c *gin.Context) {
+	editMessage(c, models.ConversationDirect)
+}
+
+// editMessage handles editing of a message by its sender. When kind is set,
+// only messages in conversations of that kind are found, and IDs from before
+// conversations are resolved to the messages they were copied to.
+func editMessage(c *gin.Context, kind string) {
 	// Get the authenticated user from context
 	user := c.MustGet("user").(models.User)
 
 	// Get message ID from the URL path
 	msgID := c.Param("id")
+	if kind != "" {
+		msgID = legacyMessageID(kind, msgID)
+	}
 
-	var msg models.GroupMessage
+	var msg models.Message
 
-	// Retrieve the group message from the database by ID
-	// SQL equivalent: SELECT * FROM group_messages WHERE id = ? LIMIT 1;
-	if err := initializers.DB.First(&msg, msgID).Error; err != nil {
+	// Retrieve the message and its conversation from the database by ID
+	// SQL equivalent: SELECT * FROM messages WHERE id = ? LIMIT 1;
+	//                 SELECT * FROM conversations WHERE id = ?;
+	if err := initializers.DB.Preload("Conversation").First(&msg, msgID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
 		return
 	}
-
-	// Ensure that only the sender of the message can edit it
-	if msg.SenderID != user.Id {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to edit this message since you are not the user"})
-		return
-	}
-
-	// Enforce 1-hour time limit for editing messages
-	if time.Since(msg.CreatedAt) > time.Hour {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to edit this message since the time limit has been exceeded"})
+	if kind != "" && msg.Conversation.Kind != kind {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
 		return
 	}
 
-	// Struct to bind request body
-	var body struct {
-		Content   string    `json:"content"`
-		UpdatedAt time.Time `json:"updated_at"`
-	}
-
-	// Parse and validate JSON body from request
-	if err := c.BindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+	// System messages are generated and never edited
+	if msg.Kind == models.MessageSystem {
+		c.JSON(http.StatusForbidden, gin.H{"error": "System messages cannot be edited"})
 		return
 	}
 
-	// Check optimistic locking: compare if the message has changed since last fetch
-	if !msg.UpdatedAt.Equal(body.UpdatedAt) {
-		c.JSON(http.StatusConflict, gin.H{"error": "Message has been updated elsewhere. Please refresh and try again."})
+	// Ensure that only the sender of the message can edit it
+	if msg.SenderID != user.Id {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to edit this message since you are not the user"})
 		return
 	}
 
-	// Update message content
-	msg.Content = body.Content
-
-	// Save the updated message to the database
-	// SQL equivalent: UPDATE group_messages SET content = ? WHERE id = ?;
-	if err := initializers.DB.Save(&msg).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update message"})
+	// Members who can no longer post in a group cannot change what they posted
+	if msg.Conversation.GroupID != nil && !Authorize(*msg.Conversation.GroupID, user.Id, models.CapPost) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot post in this group"})
 		return
 	}
 
-	// Return success response
-	c.JSON(http.StatusOK, gin.H{"success": "Message updated"})
-}
-
-// EditDirectMessage handles editing of a direct (private) message
-func EditDirectMessage(c *gin.Context) {
-	// Get the authenticated user from context
-	user := c.MustGet("user").(models.User)
-
-	// Get message ID from the URL path
-	msgID := c.Param("id")
-
-	var msg models.DirectMessage
-
-	// Retrieve the direct message from the database by ID
-	// SQL equivalent: SELECT * FROM direct_messages WHERE id = ? LIMIT 1;
-	if err := initializers.DB.First(&msg, msgID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
-		return
+	// Enforce the edit window: the group's own, or the server default for direct messages
+	window := initializers.DefaultGroupLimits.EditWindow
+	if msg.Conversation.GroupID != nil {
+		window = groupLimitsTx(initializers.DB, *msg.Conversation.GroupID).EditWindow
 	}
-
-	// Check if the user is the sender and within 1 hour time window
-	if msg.SenderID != user.Id || time.Since(msg.CreatedAt) > time.Hour {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to edit this message"})
+	if time.Since(msg.CreatedAt) > window {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to edit this message since the time limit has been exceeded"})
 		return
 	}
 
@@ -105,18 +92,32 @@ func EditDirectMessage(c *gin.Context) {
 		return
 	}
 
-	// Optimistic locking check to avoid editing stale data
+	// Check optimistic locking: compare if the message has changed since last fetch
 	if !msg.UpdatedAt.Equal(body.UpdatedAt) {
 		c.JSON(http.StatusConflict, gin.H{"error": "Message has been updated elsewhere. Please refresh and try again."})
 		return
 	}
 
-	// Apply content update
+	// Update message content
+	previousContent := msg.Content
 	msg.Content = body.Content
 
-	// Save the updated message to the database
-	// SQL equivalent: UPDATE direct_messages SET content = ? WHERE id = ?;
-	if err := initializers.DB.Save(&msg).Error; err != nil {
+	// Save the updated message together with the previous content
+	// SQL equivalent: INSERT INTO message_edits (message_id, editor_id, previous_content, edited_at) VALUES (?, ?, ?, ?);
+	//                 UPDATE messages SET content = ? WHERE id = ?;
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		edit := models.MessageEdit{
+			MessageID:       msg.ID,
+			EditorID:        user.Id,
+			PreviousContent: previousContent,
+			EditedAt:        time.Now(),
+		}
+		if err := tx.Create(&edit).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Conversation").Save(&msg).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update message"})
 		return
 	}
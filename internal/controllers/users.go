@@ -127,3 +127,52 @@ func Logout(c *gin.Context) {
 	// Respond with logout success
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
+
+// GetPreferences returns the current user's preferences
+func GetPreferences(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	c.JSON(http.StatusOK, gin.H{"group_add_policy": user.GroupAddPolicy, "broadcast_policy": user.BroadcastPolicy})
+}
+
+// UpdatePreferences changes who may add the current user to groups without
+// an invitation: anyone, contacts or invitation (nobody), and who may reach
+// them through broadcast lists: anyone, contacts or nobody. Each is optional
+func UpdatePreferences(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var body struct {
+		GroupAddPolicy  *string `json:"group_add_policy"`
+		BroadcastPolicy *string `json:"broadcast_policy"`
+	}
+	if err := c.Bind(&body); err != nil || (body.GroupAddPolicy == nil && body.BroadcastPolicy == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No preferences to update"})
+		return
+	}
+
+	updates := map[string]any{}
+	if body.GroupAddPolicy != nil {
+		if !models.IsValidGroupAddPolicy(*body.GroupAddPolicy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "group_add_policy must be anyone, contacts or invitation"})
+			return
+		}
+		updates["group_add_policy"] = *body.GroupAddPolicy
+		user.GroupAddPolicy = *body.GroupAddPolicy
+	}
+	if body.BroadcastPolicy != nil {
+		if !models.IsValidBroadcastPolicy(*body.BroadcastPolicy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "broadcast_policy must be anyone, contacts or nobody"})
+			return
+		}
+		updates["broadcast_policy"] = *body.BroadcastPolicy
+		user.BroadcastPolicy = *body.BroadcastPolicy
+	}
+
+	// SQL: UPDATE users SET group_add_policy = ?, broadcast_policy = ? WHERE id = ?;
+	if err := initializers.DB.Model(&models.User{}).Where("id = ?", user.Id).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"group_add_policy": user.GroupAddPolicy, "broadcast_policy": user.BroadcastPolicy})
+}
@@ -5,77 +5,137 @@ import (
 	"MessagingSystemBackend/internal/models"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-// ViewDMPreviews returns the latest direct message preview for each unique conversation
+// ViewDMPreviews returns the latest direct message preview for each unique conversation,
+// including unnamed conversations between more than two people
 func ViewDMPreviews(c *gin.Context) {
 	user := c.MustGet("user").(models.User)
 
-	var results []struct {
-		PartnerID uint
-		Username  string
-		Content   string
-		CreatedAt string
+	var rows []struct {
+		ConversationID uint
+		PartnerID      *uint
+		Usernames      string
+		Content        string
+		CreatedAt      string
 	}
 
 	// SQL:
-	// SELECT DISTINCT ON (LEAST(sender_id, receiver_id), GREATEST(sender_id, receiver_id))
-	//   CASE WHEN sender_id = {userId} THEN receiver_id ELSE sender_id END AS partner_id,
-	//   username, content, created_at
-	// FROM direct_messages
-	// JOIN users ON users.id = CASE WHEN sender_id = {userId} THEN receiver_id ELSE sender_id END
-	// WHERE sender_id = {userId} OR receiver_id = {userId}
-	// ORDER BY conversation_id, created_at DESC
+	// SELECT c.id, <partner_id for one-to-one DMs>, <other members' usernames>, last.content, last.created_at
+	// FROM conversation_members me
+	// JOIN conversations c ON c.id = me.conversation_id AND c.kind = 'direct'
+	// LEFT JOIN LATERAL (<the other members>) others ON true
+	// JOIN LATERAL (SELECT * FROM messages WHERE conversation_id = c.id ORDER BY created_at DESC LIMIT 1) last ON true
+	// WHERE me.user_id = {userId}
+	// ORDER BY last.created_at DESC
+	// LIMIT 10;
 	initializers.DB.Raw(`
-		SELECT DISTINCT ON (LEAST(sender_id, receiver_id), GREATEST(sender_id, receiver_id))
-			CASE WHEN sender_id = ? THEN receiver_id ELSE sender_id END AS partner_id,
-			u.username,
-			dm.content,
-			dm.created_at
-		FROM direct_messages dm
-		JOIN users u ON u.id = CASE WHEN sender_id = ? THEN receiver_id ELSE sender_id END
-		WHERE sender_id = ? OR receiver_id = ?
-		ORDER BY LEAST(sender_id, receiver_id), GREATEST(sender_id, receiver_id), dm.created_at DESC
+		SELECT c.id AS conversation_id,
+			CASE WHEN others.n <= 1 THEN COALESCE(others.user_id, me.user_id) END AS partner_id,
+			COALESCE(others.usernames, self.username) AS usernames,
+			last.content,
+			last.created_at
+		FROM conversation_members me
+		JOIN users self ON self.id = me.user_id
+		JOIN conversations c ON c.id = me.conversation_id AND c.kind = 'direct'
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS n, MIN(cm.user_id) AS user_id, string_agg(u.username, ',' ORDER BY u.username) AS usernames
+			FROM conversation_members cm
+			JOIN users u ON u.id = cm.user_id
+			WHERE cm.conversation_id = c.id AND cm.user_id <> me.user_id
+		) others ON true
+		JOIN LATERAL (
+			SELECT m.content, m.created_at FROM messages m
+			WHERE m.conversation_id = c.id ORDER BY m.created_at DESC LIMIT 1
+		) last ON true
+		WHERE me.user_id = ?
+		ORDER BY last.created_at DESC
 		LIMIT 10
-	`, user.Id, user.Id, user.Id, user.Id).Scan(&results)
+	`, user.Id).Scan(&rows)
+
+	// PartnerID and Username describe one-to-one DMs; multi-person
+	// conversations list everyone else in Participants
+	type preview struct {
+		ConversationID uint
+		PartnerID      *uint
+		Username       string
+		Participants   []string
+		Content        string
+		CreatedAt      string
+	}
+
+	var results []preview
+	for _, r := range rows {
+		participants := strings.Split(r.Usernames, ",")
+		results = append(results, preview{
+			ConversationID: r.ConversationID,
+			PartnerID:      r.PartnerID,
+			Username:       strings.Join(participants, ", "),
+			Participants:   participants,
+			Content:        r.Content,
+			CreatedAt:      r.CreatedAt,
+		})
+	}
 
 	c.JSON(http.StatusOK, results)
 }
 
-// ViewGroupPreviews returns the latest message previews from the groups the user is a member of
+// ViewGroupPreviews returns the latest message previews from the groups the user is a member of.
+// Archived groups are left out unless include_archived=true. Channels carry the community they
+// belong to, and community_id limits the previews to the channels of one community.
 func ViewGroupPreviews(c *gin.Context) {
 	user := c.MustGet("user").(models.User)
+	includeArchived := c.Query("include_archived") == "true"
+
+	var communityID int
+	if v := c.Query("community_id"); v != "" {
+		var err error
+		if communityID, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid community ID"})
+			return
+		}
+	}
 
 	var results []struct {
-		GroupID   uint
-		GroupName string
-		Content   string
-		CreatedAt string
+		GroupID       uint
+		GroupName     string
+		CommunityID   *uint
+		CommunityName *string
+		Content       string
+		CreatedAt     string
 	}
 
 	// SQL:
-	// SELECT g.id, g.name, gm.content, gm.created_at
+	// SELECT g.id, g.name, g.community_id, co.name, gm.content, gm.created_at
 	// FROM group_members
 	// JOIN groups ON group_members.group_id = groups.id
+	// LEFT JOIN communities co ON co.id = groups.community_id
+	// JOIN conversations ON conversations.group_id = groups.id
 	// LEFT JOIN LATERAL (
-	//     SELECT * FROM group_messages WHERE group_id = groups.id ORDER BY created_at DESC LIMIT 1
+	//     SELECT * FROM messages WHERE conversation_id = conversations.id ORDER BY created_at DESC LIMIT 1
 	// ) gm ON true
-	// WHERE group_members.user_id = {userId}
+	// WHERE group_members.user_id = {userId} AND groups.purge_at IS NULL
+	//   AND ({includeArchived} OR groups.archived_at IS NULL)
+	//   AND ({communityId} = 0 OR groups.community_id = {communityId})
 	// ORDER BY gm.created_at DESC
 	// LIMIT 10;
 	initializers.DB.Raw(`
-		SELECT g.id as group_id, g.name as group_name, gm.content, gm.created_at
+		SELECT g.id as group_id, g.name as group_name, g.community_id, co.name as community_name, gm.content, gm.created_at
 		FROM group_members m
 		JOIN groups g ON m.group_id = g.id
+		LEFT JOIN communities co ON co.id = g.community_id
+		JOIN conversations c ON c.group_id = g.id
 		LEFT JOIN LATERAL (
-			SELECT * FROM group_messages gm2 WHERE gm2.group_id = g.id ORDER BY created_at DESC LIMIT 1
+			SELECT * FROM messages gm2 WHERE gm2.conversation_id = c.id ORDER BY created_at DESC LIMIT 1
 		) gm ON true
-		WHERE m.user_id = ?
+		WHERE m.user_id = ? AND g.purge_at IS NULL AND (? OR g.archived_at IS NULL)
+			AND (? = 0 OR g.community_id = ?)
 		ORDER BY gm.created_at DESC
 		LIMIT 10
-	`, user.Id).Scan(&results)
+	`, user.Id, includeArchived, communityID, communityID).Scan(&results)
 
 	c.JSON(http.StatusOK, results)
 }
@@ -101,27 +161,26 @@ func ViewChatHistory(c *gin.Context) {
 			return
 		}
 
-		var messages []models.DirectMessage
+		// SQL: SELECT * FROM conversations WHERE direct_key = ? LIMIT 1;
+		var conv models.Conversation
+		initializers.DB.Where("direct_key = ?", models.DirectKey([]uint{user.Id, partner.Id})).First(&conv)
 
-		// SQL:
-		// SELECT * FROM direct_messages
-		// WHERE (sender_id = {user.Id} AND receiver_id = {partner.Id})
-		//    OR (sender_id = {partner.Id} AND receiver_id = {user.Id})
-		// ORDER BY created_at DESC LIMIT 10;
-		initializers.DB.
-			Where(`(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)`,
-				user.Id, partner.Id, partner.Id, user.Id).
-			Order("created_at DESC").
-			Limit(10).
-			Find(&messages)
+		var messages []models.Message
+		if conv.ID != 0 {
+			messages = ConversationHistory(conv.ID, 10, nil)
+		}
 
 		// Return only necessary fields
 		var resp []gin.H
 		for _, msg := range messages {
+			receiverID := partner.Id
+			if msg.SenderID == partner.Id {
+				receiverID = user.Id
+			}
 			resp = append(resp, gin.H{
 				"id":          msg.ID,
 				"sender_id":   msg.SenderID,
-				"receiver_id": msg.ReceiverID,
+				"receiver_id": receiverID,
 				"content":     msg.Content,
 				"created_at":  msg.CreatedAt,
 			})
@@ -139,28 +198,34 @@ func ViewChatHistory(c *gin.Context) {
 			return
 		}
 
-		var messages []models.GroupMessage
+		if !IsGroupMember(group.ID, user.Id) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "You are not a member of this group"})
+			return
+		}
 
-		// SQL:
-		// SELECT * FROM group_messages
-		// WHERE group_id = {group.ID}
-		// ORDER BY created_at DESC LIMIT 10;
-		initializers.DB.
-			Where("group_id = ?", group.ID).
-			Order("created_at DESC").
-			Limit(10).
-			Find(&messages)
+		var messages []models.Message
+		if conv, err := GroupConversation(group.ID); err == nil {
+			messages = ConversationHistory(conv.ID, 10, nil)
+		}
 
 		// Return only necessary fields
 		var resp []gin.H
 		for _, msg := range messages {
-			resp = append(resp, gin.H{
+			item := gin.H{
 				"id":         msg.ID,
 				"sender_id":  msg.SenderID,
-				"group_id":   msg.GroupID,
+				"group_id":   group.ID,
+				"kind":       msg.Kind,
 				"content":    msg.Content,
 				"created_at": msg.CreatedAt,
-			})
+			}
+			// System messages carry their event so clients can render them
+			// in their own language instead of using content
+			if msg.Kind == models.MessageSystem {
+				item["event"] = msg.Event
+				item["data"] = rawJSON(msg.EventData)
+			}
+			resp = append(resp, item)
 		}
 
 		c.JSON(http.StatusOK, resp)
@@ -0,0 +1,500 @@
+package importer
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// Messages written per transaction. A failed run keeps every completed
+	// batch, and re-running the import skips them.
+	batchSize = 500
+)
+
+// ErrInvalidExport is returned when the uploaded file is not a usable export.
+var ErrInvalidExport = errors.New("invalid export file")
+
+// ErrUnknownUser is returned when Options.Users names a user that does not exist.
+var ErrUnknownUser = errors.New("unknown user")
+
+// Options configures a single import run.
+type Options struct {
+	Source   string // "slack" or "whatsapp"
+	FileName string
+
+	// WhatsApp only
+	GroupName string         // Group to import a group chat into; empty for one-to-one chats
+	DateOrder string         // "dmy", "mdy" or empty to detect from the file
+	Location  *time.Location // Time zone of the timestamps in the export
+
+	// Users maps people in the export (Slack user IDs, WhatsApp contact names)
+	// to the usernames of the existing accounts they are. Everyone else gets a
+	// placeholder account, whatever their name.
+	Users map[string]string
+
+	StartedBy *uint
+}
+
+// Report summarizes what an import run did.
+type Report struct {
+	JobID            uint     `json:"job_id"`
+	Resumed          bool     `json:"resumed"`
+	Groups           int      `json:"groups"`
+	DirectChats      int      `json:"direct_chats"`
+	UsersCreated     int      `json:"users_created"`
+	MessagesImported int      `json:"messages_imported"`
+	MessagesSkipped  int      `json:"messages_skipped"` // Already imported by an earlier run
+	MessagesIgnored  int      `json:"messages_ignored"` // Events that are not chat messages
+	MessagesBlocked  int      `json:"messages_blocked"` // Not written because their group is archived or awaiting deletion
+	MembersSkipped   []string `json:"members_skipped"`  // Not added because of the group's state, a ban or their preferences
+}
+
+// pendingMessage is a parsed message waiting to be written in a batch.
+type pendingMessage struct {
+	ExternalID     string
+	ConversationID uint
+	SenderID       uint
+	Content        string
+	CreatedAt      time.Time
+}
+
+// session holds the state of one import run.
+type session struct {
+	source string
+	job    models.ImportJob
+	report *Report
+
+	users  map[string]uint // external user ID -> users.id
+	mapped map[string]uint // external user ID -> users.id given by Options.Users
+	groups map[string]uint // external channel ID -> groups.id
+}
+
+// Import reads an export file and writes its users, groups and messages.
+// Importing the same file again only adds what an earlier run did not finish.
+func Import(file io.ReaderAt, size int64, opts Options) (*Report, error) {
+	if opts.Source != "slack" && opts.Source != "whatsapp" {
+		return nil, fmt.Errorf("%w: unsupported source %q", ErrInvalidExport, opts.Source)
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+
+	checksum, err := fileChecksum(file, size)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &session{
+		source: opts.Source,
+		report: &Report{MembersSkipped: []string{}},
+		users:  map[string]uint{},
+		mapped: map[string]uint{},
+		groups: map[string]uint{},
+	}
+
+	for externalID, username := range opts.Users {
+		var user models.User
+		// SQL: SELECT * FROM users WHERE username = ? LIMIT 1;
+		if err := initializers.DB.Where("username = ?", username).First(&user).Error; err != nil {
+			return nil, fmt.Errorf("%w %q for %q", ErrUnknownUser, username, externalID)
+		}
+		s.mapped[externalID] = user.Id
+	}
+
+	// Resume an unfinished job for the same file, or start a new one
+	// SQL: SELECT * FROM import_jobs WHERE source = ? AND checksum = ? AND status <> 'completed' ORDER BY id DESC LIMIT 1;
+	err = initializers.DB.
+		Where("source = ? AND checksum = ? AND status <> ?", opts.Source, checksum, "completed").
+		Order("id DESC").
+		First(&s.job).Error
+	if err == nil {
+		s.report.Resumed = true
+		s.job.Status = "running"
+		s.job.Error = ""
+		initializers.DB.Save(&s.job)
+	} else {
+		s.job = models.ImportJob{
+			Source:    opts.Source,
+			Checksum:  checksum,
+			FileName:  opts.FileName,
+			Status:    "running",
+			StartedBy: opts.StartedBy,
+			StartedAt: time.Now(),
+		}
+		if err := initializers.DB.Create(&s.job).Error; err != nil {
+			return nil, fmt.Errorf("could not create import job: %v", err)
+		}
+	}
+	s.report.JobID = s.job.ID
+
+	if opts.Source == "slack" {
+		err = s.importSlack(file, size)
+	} else {
+		err = s.importWhatsApp(file, size, opts)
+	}
+
+	now := time.Now()
+	s.job.FinishedAt = &now
+	s.job.MessagesImported += s.report.MessagesImported
+	s.job.MessagesSkipped += s.report.MessagesSkipped
+	if err != nil {
+		s.job.Status = "failed"
+		s.job.Error = err.Error()
+	} else {
+		s.job.Status = "completed"
+	}
+	initializers.DB.Save(&s.job)
+
+	return s.report, err
+}
+
+// fileChecksum identifies an export file so a re-run can resume its job.
+func fileChecksum(file io.ReaderAt, size int64) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, size)); err != nil {
+		return "", fmt.Errorf("could not read export: %v", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// lookupRecord returns the ID an external entity was imported as, if any.
+func (s *session) lookupRecord(entityType, externalID string) (uint, bool) {
+	var record models.ImportRecord
+	// SQL: SELECT * FROM import_records WHERE source = ? AND entity_type = ? AND external_id = ? LIMIT 1;
+	err := initializers.DB.
+		Where("source = ? AND entity_type = ? AND external_id = ?", s.source, entityType, externalID).
+		First(&record).Error
+	if err != nil {
+		return 0, false
+	}
+	return record.EntityID, true
+}
+
+func (s *session) saveRecord(tx *gorm.DB, entityType, externalID string, entityID uint) error {
+	record := models.ImportRecord{
+		Source:     s.source,
+		EntityType: entityType,
+		ExternalID: externalID,
+		EntityID:   entityID,
+		JobID:      s.job.ID,
+		CreatedAt:  time.Now(),
+	}
+	// SQL: INSERT INTO import_records (source, entity_type, external_id, entity_id, job_id, created_at) VALUES (...);
+	return tx.Create(&record).Error
+}
+
+// userFor maps an external person to a user. People are only matched to
+// existing accounts through Options.Users; everyone else gets a placeholder
+// account named after them, with a numbered suffix if the name is taken.
+func (s *session) userFor(externalID, username string) (uint, error) {
+	if id, ok := s.users[externalID]; ok {
+		return id, nil
+	}
+	if id, ok := s.lookupRecord("user", externalID); ok {
+		s.users[externalID] = id
+		return id, nil
+	}
+
+	if username == "" {
+		username = externalID
+	}
+
+	var user models.User
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		if id, ok := s.mapped[externalID]; ok {
+			user.Id = id
+			return s.saveRecord(tx, "user", externalID, user.Id)
+		}
+
+		candidate := username
+		for i := 2; ; i++ {
+			var count int64
+			// SQL: SELECT COUNT(*) FROM users WHERE username = ?;
+			tx.Model(&models.User{}).Where("username = ?", candidate).Count(&count)
+			if count == 0 {
+				break
+			}
+			candidate = fmt.Sprintf("%s_%d", username, i)
+		}
+
+		password, err := placeholderPassword()
+		if err != nil {
+			return err
+		}
+		user = models.User{Username: candidate, Password: password, Placeholder: true}
+		// SQL: INSERT INTO users (username, password, placeholder) VALUES (?, ?, true);
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		s.report.UsersCreated++
+		return s.saveRecord(tx, "user", externalID, user.Id)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("could not import user %q: %v", username, err)
+	}
+
+	s.users[externalID] = user.Id
+	return user.Id, nil
+}
+
+// placeholderPassword returns a hash of random bytes, so nobody can log in
+// to a placeholder account until it is claimed.
+func placeholderPassword() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(hex.EncodeToString(secret)), 10)
+	return string(hash), err
+}
+
+// groupFor maps an external channel to a group, creating it with the given
+// creator as admin. Names already used by other groups get a numbered suffix.
+func (s *session) groupFor(externalID, name string, creatorID uint, createdAt time.Time) (uint, error) {
+	if id, ok := s.groups[externalID]; ok {
+		return id, nil
+	}
+	if id, ok := s.lookupRecord("group", externalID); ok {
+		s.groups[externalID] = id
+		return id, nil
+	}
+
+	var group models.Group
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		candidate := name
+		for i := 2; ; i++ {
+			var count int64
+			// SQL: SELECT COUNT(*) FROM groups WHERE name = ?;
+			tx.Model(&models.Group{}).Where("name = ?", candidate).Count(&count)
+			if count == 0 {
+				break
+			}
+			candidate = fmt.Sprintf("%s-%d", name, i)
+		}
+
+		group = models.Group{Name: candidate, CreatedBy: creatorID, CreatedAt: createdAt}
+		// SQL: INSERT INTO groups (name, created_by, created_at) VALUES (?, ?, ?);
+		if err := tx.Create(&group).Error; err != nil {
+			return err
+		}
+
+		owner := models.GroupMember{GroupID: group.ID, UserID: creatorID, Role: models.RoleOwner, JoinedAt: createdAt}
+		// SQL: INSERT INTO group_members (group_id, user_id, role, joined_at) VALUES (?, ?, 'owner', ?);
+		if err := tx.Create(&owner).Error; err != nil {
+			return err
+		}
+		return s.saveRecord(tx, "group", externalID, group.ID)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("could not import group %q: %v", name, err)
+	}
+
+	s.report.Groups++
+	s.groups[externalID] = group.ID
+	return group.ID, nil
+}
+
+// addMembers adds users to a group until it reaches its member limit, with the
+// same rules as additions through the API: nobody is added to an archived
+// group or one awaiting deletion, banned users are not added back, and real
+// accounts are only added if their group add preference lets anyone add them.
+// The group row is locked while counting so additions made through the API at
+// the same time cannot push the group past the limit.
+func (s *session) addMembers(groupID uint, groupName string, userIDs []uint, joinedAt time.Time) {
+	for _, userID := range userIDs {
+		var user models.User
+		// SQL: SELECT * FROM users WHERE id = ? LIMIT 1;
+		if err := initializers.DB.First(&user, userID).Error; err != nil {
+			continue
+		}
+		if !user.Placeholder && user.GroupAddPolicy != models.GroupAddAnyone {
+			s.report.MembersSkipped = append(s.report.MembersSkipped, fmt.Sprintf("%s in %s (needs an invitation)", user.Username, groupName))
+			continue
+		}
+
+		skipped := ""
+		initializers.DB.Transaction(func(tx *gorm.DB) error {
+			// SQL: SELECT * FROM groups WHERE id = ? FOR UPDATE;
+			var group models.Group
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&group, groupID).Error; err != nil {
+				return err
+			}
+			if group.IsReadOnly() {
+				skipped = "group is archived or awaiting deletion"
+				return nil
+			}
+
+			var existing int64
+			// SQL: SELECT COUNT(*) FROM group_members WHERE group_id = ? AND user_id = ?;
+			tx.Model(&models.GroupMember{}).Where("group_id = ? AND user_id = ?", groupID, userID).Count(&existing)
+			if existing > 0 {
+				return nil
+			}
+
+			var banned int64
+			// SQL: SELECT COUNT(*) FROM group_bans WHERE group_id = ? AND user_id = ?;
+			tx.Model(&models.GroupBan{}).Where("group_id = ? AND user_id = ?", groupID, userID).Count(&banned)
+			if banned > 0 {
+				skipped = "banned"
+				return nil
+			}
+
+			var count int64
+			// SQL: SELECT COUNT(*) FROM group_members WHERE group_id = ?;
+			tx.Model(&models.GroupMember{}).Where("group_id = ?", groupID).Count(&count)
+			if int(count) >= initializers.GroupLimitsFor(group).MaxMembers {
+				skipped = "group is full"
+				return nil
+			}
+
+			member := models.GroupMember{GroupID: groupID, UserID: userID, Role: models.RoleMember, JoinedAt: joinedAt}
+			// SQL: INSERT INTO group_members (group_id, user_id, role, joined_at) VALUES (?, ?, 'member', ?);
+			return tx.Create(&member).Error
+		})
+
+		if skipped != "" {
+			s.report.MembersSkipped = append(s.report.MembersSkipped, fmt.Sprintf("%s in %s (%s)", user.Username, groupName, skipped))
+		}
+	}
+}
+
+// groupConversation returns the conversation of an imported group.
+func (s *session) groupConversation(groupID uint) (uint, error) {
+	var conv models.Conversation
+	// SQL: SELECT * FROM conversations WHERE group_id = ? LIMIT 1;
+	if err := initializers.DB.Where("group_id = ?", groupID).First(&conv).Error; err != nil {
+		return 0, fmt.Errorf("could not find conversation of group %d: %v", groupID, err)
+	}
+	return conv.ID, nil
+}
+
+// directConversation returns the direct conversation between imported users.
+func (s *session) directConversation(userIDs []uint) (uint, error) {
+	var conv models.Conversation
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		conv, err = models.FindOrCreateDirectConversation(tx, userIDs)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("could not import direct chat: %v", err)
+	}
+	return conv.ID, nil
+}
+
+// writeBatch inserts the messages of a batch that were not imported before,
+// together with their import records, in one transaction.
+func (s *session) writeBatch(batch []pendingMessage) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	externalIDs := make([]string, 0, len(batch))
+	for _, msg := range batch {
+		externalIDs = append(externalIDs, msg.ExternalID)
+	}
+
+	var imported, skipped, blocked int
+	err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+		// SQL: SELECT external_id FROM import_records WHERE source = ? AND entity_type = 'message' AND external_id IN (...);
+		var done []string
+		err := tx.Model(&models.ImportRecord{}).
+			Where("source = ? AND entity_type = ? AND external_id IN ?", s.source, "message", externalIDs).
+			Pluck("external_id", &done).Error
+		if err != nil {
+			return err
+		}
+		skip := map[string]struct{}{}
+		for _, id := range done {
+			skip[id] = struct{}{}
+		}
+
+		// Archived groups and groups awaiting deletion take no new messages.
+		// Their messages get no import record, so a later run retries them.
+		conversationIDs := make([]uint, 0, len(batch))
+		for _, msg := range batch {
+			conversationIDs = append(conversationIDs, msg.ConversationID)
+		}
+		// SQL: SELECT conversations.id FROM conversations JOIN groups ON groups.id = conversations.group_id
+		//        WHERE conversations.id IN (...) AND (groups.archived_at IS NOT NULL OR groups.purge_at IS NOT NULL);
+		var readOnlyIDs []uint
+		err = tx.Model(&models.Conversation{}).
+			Joins("JOIN groups ON groups.id = conversations.group_id").
+			Where("conversations.id IN ? AND (groups.archived_at IS NOT NULL OR groups.purge_at IS NOT NULL)", conversationIDs).
+			Pluck("conversations.id", &readOnlyIDs).Error
+		if err != nil {
+			return err
+		}
+		readOnly := map[uint]struct{}{}
+		for _, id := range readOnlyIDs {
+			readOnly[id] = struct{}{}
+		}
+
+		var messages []models.Message
+		var keys []string
+
+		for _, msg := range batch {
+			if _, ok := skip[msg.ExternalID]; ok {
+				skipped++
+				continue
+			}
+			if _, ok := readOnly[msg.ConversationID]; ok {
+				blocked++
+				continue
+			}
+			// Guard against the same message appearing twice in one batch
+			skip[msg.ExternalID] = struct{}{}
+
+			messages = append(messages, models.Message{
+				ConversationID: msg.ConversationID,
+				SenderID:       msg.SenderID,
+				Content:        msg.Content,
+				CreatedAt:      msg.CreatedAt,
+				UpdatedAt:      msg.CreatedAt,
+			})
+			keys = append(keys, msg.ExternalID)
+		}
+
+		var records []models.ImportRecord
+		now := time.Now()
+
+		if len(messages) > 0 {
+			// SQL: INSERT INTO messages (conversation_id, sender_id, content, created_at, updated_at) VALUES (...), (...);
+			if err := tx.Create(&messages).Error; err != nil {
+				return err
+			}
+			for i, msg := range messages {
+				records = append(records, models.ImportRecord{Source: s.source, EntityType: "message", ExternalID: keys[i], EntityID: msg.ID, JobID: s.job.ID, CreatedAt: now})
+			}
+		}
+
+		if len(records) > 0 {
+			// SQL: INSERT INTO import_records (...) VALUES (...), (...);
+			if err := tx.Create(&records).Error; err != nil {
+				return err
+			}
+		}
+
+		imported = len(records)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.report.MessagesImported += imported
+	s.report.MessagesSkipped += skipped
+	s.report.MessagesBlocked += blocked
+	return nil
+}
@@ -0,0 +1,308 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type slackUser struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Profile struct {
+		DisplayName string `json:"display_name"`
+		RealName    string `json:"real_name"`
+	} `json:"profile"`
+}
+
+type slackChannel struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Created int64    `json:"created"`
+	Creator string   `json:"creator"`
+	Members []string `json:"members"`
+}
+
+type slackMessage struct {
+	Type    string `json:"type"`
+	Subtype string `json:"subtype"`
+	User    string `json:"user"`
+	Text    string `json:"text"`
+	Ts      string `json:"ts"`
+	Files   []struct {
+		Name string `json:"name"`
+	} `json:"files"`
+}
+
+// Message subtypes that carry user-written content. Everything else (joins,
+// topic changes, bot posts) is an event and is not imported.
+var slackContentSubtypes = map[string]bool{
+	"":                 true,
+	"me_message":       true,
+	"thread_broadcast": true,
+	"file_share":       true,
+}
+
+var (
+	slackUserMention    = regexp.MustCompile(`<@([A-Z0-9]+)(?:\|[^>]*)?>`)
+	slackChannelMention = regexp.MustCompile(`<#[A-Z0-9]+\|([^>]*)>`)
+	slackLink           = regexp.MustCompile(`<([^@#!][^|>]*)(?:\|([^>]*))?>`)
+)
+
+// importSlack imports a Slack workspace export ZIP. Public and private
+// channels and multi-person DMs become groups; one-to-one DMs become
+// direct messages.
+func (s *session) importSlack(file io.ReaderAt, size int64) error {
+	archive, err := zip.NewReader(file, size)
+	if err != nil {
+		return fmt.Errorf("%w: not a ZIP archive", ErrInvalidExport)
+	}
+
+	// Exports are sometimes zipped with an enclosing folder
+	files := map[string]*zip.File{}
+	root := ""
+	for _, f := range archive.File {
+		files[f.Name] = f
+		if path.Base(f.Name) == "users.json" {
+			root = path.Dir(f.Name)
+		}
+	}
+	if _, ok := files[path.Join(root, "users.json")]; !ok {
+		return fmt.Errorf("%w: users.json missing from Slack export", ErrInvalidExport)
+	}
+
+	var users []slackUser
+	if err := readZipJSON(files, path.Join(root, "users.json"), &users); err != nil {
+		return err
+	}
+	people := map[string]slackUser{}
+	for _, u := range users {
+		people[u.ID] = u
+	}
+
+	// Users are only created once they are seen in a channel or message
+	resolveUser := func(id string) (uint, error) {
+		u, ok := people[id]
+		if !ok {
+			return s.userFor(id, id)
+		}
+		return s.userFor(id, u.Name)
+	}
+
+	for _, listing := range []string{"channels.json", "groups.json", "mpims.json"} {
+		var channels []slackChannel
+		if _, ok := files[path.Join(root, listing)]; !ok {
+			continue
+		}
+		if err := readZipJSON(files, path.Join(root, listing), &channels); err != nil {
+			return err
+		}
+
+		for _, ch := range channels {
+			if err := s.importSlackChannel(files, root, ch, people, resolveUser); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, ok := files[path.Join(root, "dms.json")]; ok {
+		var dms []slackChannel
+		if err := readZipJSON(files, path.Join(root, "dms.json"), &dms); err != nil {
+			return err
+		}
+
+		for _, dm := range dms {
+			if err := s.importSlackDM(files, root, dm, people, resolveUser); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *session) importSlackChannel(files map[string]*zip.File, root string, ch slackChannel, people map[string]slackUser, resolveUser func(string) (uint, error)) error {
+	creator := ch.Creator
+	if creator == "" && len(ch.Members) > 0 {
+		creator = ch.Members[0]
+	}
+	if creator == "" {
+		return fmt.Errorf("%w: channel %s has no creator or members", ErrInvalidExport, ch.Name)
+	}
+
+	creatorID, err := resolveUser(creator)
+	if err != nil {
+		return err
+	}
+
+	createdAt := time.Unix(ch.Created, 0)
+	groupID, err := s.groupFor(ch.ID, ch.Name, creatorID, createdAt)
+	if err != nil {
+		return err
+	}
+
+	var memberIDs []uint
+	for _, m := range ch.Members {
+		id, err := resolveUser(m)
+		if err != nil {
+			return err
+		}
+		memberIDs = append(memberIDs, id)
+	}
+	s.addMembers(groupID, ch.Name, memberIDs, createdAt)
+
+	conversationID, err := s.groupConversation(groupID)
+	if err != nil {
+		return err
+	}
+	return s.importSlackHistory(files, path.Join(root, ch.Name), ch.ID, people, resolveUser, conversationID)
+}
+
+func (s *session) importSlackDM(files map[string]*zip.File, root string, dm slackChannel, people map[string]slackUser, resolveUser func(string) (uint, error)) error {
+	if len(dm.Members) == 0 {
+		return nil
+	}
+
+	var memberIDs []uint
+	for _, m := range dm.Members {
+		id, err := resolveUser(m)
+		if err != nil {
+			return err
+		}
+		memberIDs = append(memberIDs, id)
+	}
+
+	conversationID, err := s.directConversation(memberIDs)
+	if err != nil {
+		return err
+	}
+	s.report.DirectChats++
+
+	return s.importSlackHistory(files, path.Join(root, dm.ID), dm.ID, people, resolveUser, conversationID)
+}
+
+// importSlackHistory reads the per-day message files of one conversation in
+// date order and writes them in batches.
+func (s *session) importSlackHistory(files map[string]*zip.File, dir, channelID string, people map[string]slackUser, resolveUser func(string) (uint, error), conversationID uint) error {
+	var days []string
+	for name := range files {
+		if path.Dir(name) == dir && strings.HasSuffix(name, ".json") {
+			days = append(days, name)
+		}
+	}
+	sort.Strings(days)
+
+	var batch []pendingMessage
+	for _, day := range days {
+		var messages []slackMessage
+		if err := readZipJSON(files, day, &messages); err != nil {
+			return err
+		}
+
+		for _, m := range messages {
+			if m.Type != "message" || !slackContentSubtypes[m.Subtype] || m.User == "" {
+				s.report.MessagesIgnored++
+				continue
+			}
+
+			createdAt, err := parseSlackTs(m.Ts)
+			if err != nil {
+				s.report.MessagesIgnored++
+				continue
+			}
+
+			content := slackText(m.Text, people)
+			for _, f := range m.Files {
+				content = strings.TrimSpace(content + "\n[file: " + f.Name + "]")
+			}
+			if content == "" {
+				s.report.MessagesIgnored++
+				continue
+			}
+
+			senderID, err := resolveUser(m.User)
+			if err != nil {
+				return err
+			}
+
+			batch = append(batch, pendingMessage{
+				ExternalID:     channelID + ":" + m.Ts,
+				ConversationID: conversationID,
+				SenderID:       senderID,
+				Content:        content,
+				CreatedAt:      createdAt,
+			})
+
+			if len(batch) >= batchSize {
+				if err := s.writeBatch(batch); err != nil {
+					return err
+				}
+				batch = batch[:0]
+			}
+		}
+	}
+
+	return s.writeBatch(batch)
+}
+
+// readZipJSON decodes a JSON file from the export archive.
+func readZipJSON(files map[string]*zip.File, name string, v any) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("%w: %s missing from Slack export", ErrInvalidExport, name)
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: could not open %s", ErrInvalidExport, name)
+	}
+	defer r.Close()
+
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("%w: could not parse %s", ErrInvalidExport, name)
+	}
+	return nil
+}
+
+// parseSlackTs converts a Slack timestamp such as "1503435956.000247".
+func parseSlackTs(ts string) (time.Time, error) {
+	secs, frac, _ := strings.Cut(ts, ".")
+	sec, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var usec int64
+	if frac != "" {
+		usec, _ = strconv.ParseInt((frac + "000000")[:6], 10, 64)
+	}
+	return time.Unix(sec, usec*int64(time.Microsecond)), nil
+}
+
+// slackText turns Slack markup into plain text: mentions become @username,
+// channel links #name and links their label or URL.
+func slackText(text string, people map[string]slackUser) string {
+	text = slackUserMention.ReplaceAllStringFunc(text, func(m string) string {
+		id := slackUserMention.FindStringSubmatch(m)[1]
+		if u, ok := people[id]; ok {
+			return "@" + u.Name
+		}
+		return "@" + id
+	})
+	text = slackChannelMention.ReplaceAllString(text, "#$1")
+	text = slackLink.ReplaceAllStringFunc(text, func(m string) string {
+		parts := slackLink.FindStringSubmatch(m)
+		if parts[2] != "" {
+			return parts[2]
+		}
+		return parts[1]
+	})
+	return strings.TrimSpace(html.UnescapeString(text))
+}
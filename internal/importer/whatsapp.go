@@ -0,0 +1,268 @@
+package importer
+
+import (
+	"archive/zip"
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A WhatsApp message line starts with a timestamp, in either the Android
+// ("31/12/20, 21:41 - ") or iOS ("[31/12/20, 21:41:05] ") layout.
+var (
+	whatsAppAndroidLine = regexp.MustCompile(`^(\d{1,2})[./-](\d{1,2})[./-](\d{2,4}),? (\d{1,2}):(\d{2})(?::(\d{2}))?(?: ?([AaPp]\.? ?[Mm]\.?))? - (.*)$`)
+	whatsAppIOSLine     = regexp.MustCompile(`^\[(\d{1,2})[./-](\d{1,2})[./-](\d{2,4}),? (\d{1,2}):(\d{2})(?::(\d{2}))?(?: ?([AaPp]\.? ?[Mm]\.?))?\] (.*)$`)
+	usernameUnsafe      = regexp.MustCompile(`[^a-z0-9]+`)
+
+	// Direction marks and odd spaces that WhatsApp inserts around timestamps
+	whatsAppInvisible = strings.NewReplacer("\u200e", "", "\u200f", "", "\ufeff", "", "\u202f", " ", "\u00a0", " ")
+)
+
+// whatsAppLine is a message line before its date has been interpreted.
+type whatsAppLine struct {
+	fields [7]string // first, second, year, hour, minute, second, am/pm
+	author string
+	text   string
+}
+
+// importWhatsApp imports a WhatsApp "Export chat" text file, or the ZIP that
+// wraps it when media was included. Chats with exactly two people become
+// direct messages unless a group name is given.
+func (s *session) importWhatsApp(file io.ReaderAt, size int64, opts Options) error {
+	reader, err := whatsAppChatReader(file, size)
+	if err != nil {
+		return err
+	}
+
+	lines, err := parseWhatsAppLines(reader, s.report)
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("%w: no WhatsApp messages found", ErrInvalidExport)
+	}
+
+	dayFirst, err := whatsAppDateOrder(lines, opts.DateOrder)
+	if err != nil {
+		return err
+	}
+
+	// Resolve every participant in order of their first message
+	var authors []string
+	authorIDs := map[string]uint{}
+	for _, l := range lines {
+		if _, ok := authorIDs[l.author]; ok {
+			continue
+		}
+		id, err := s.userFor(l.author, whatsAppUsername(l.author))
+		if err != nil {
+			return err
+		}
+		authorIDs[l.author] = id
+		authors = append(authors, l.author)
+	}
+
+	chatKey := opts.GroupName
+	var conversationID uint
+	switch {
+	case opts.GroupName != "":
+		first, err := whatsAppTime(lines[0], dayFirst, opts.Location)
+		if err != nil {
+			return err
+		}
+		groupID, err := s.groupFor("group:"+opts.GroupName, opts.GroupName, authorIDs[authors[0]], first)
+		if err != nil {
+			return err
+		}
+		var memberIDs []uint
+		for _, a := range authors {
+			memberIDs = append(memberIDs, authorIDs[a])
+		}
+		s.addMembers(groupID, opts.GroupName, memberIDs, first)
+		conversationID, err = s.groupConversation(groupID)
+		if err != nil {
+			return err
+		}
+	case len(authors) == 2:
+		chatKey = "dm:" + strings.Join(authors, "|")
+		var err error
+		conversationID, err = s.directConversation([]uint{authorIDs[authors[0]], authorIDs[authors[1]]})
+		if err != nil {
+			return err
+		}
+		s.report.DirectChats++
+	default:
+		return fmt.Errorf("%w: a group name is required to import a chat with %d participants", ErrInvalidExport, len(authors))
+	}
+
+	// Identical lines (same time, author and text) are told apart by their
+	// position among duplicates, which stays stable between runs.
+	seen := map[string]int{}
+	var batch []pendingMessage
+	for _, l := range lines {
+		createdAt, err := whatsAppTime(l, dayFirst, opts.Location)
+		if err != nil {
+			s.report.MessagesIgnored++
+			continue
+		}
+
+		key := strings.Join([]string{chatKey, createdAt.UTC().Format(time.RFC3339), l.author, l.text}, "\x00")
+		seen[key]++
+		hash := sha1.Sum([]byte(key + "\x00" + strconv.Itoa(seen[key])))
+
+		batch = append(batch, pendingMessage{
+			ExternalID:     hex.EncodeToString(hash[:]),
+			ConversationID: conversationID,
+			SenderID:       authorIDs[l.author],
+			Content:        l.text,
+			CreatedAt:      createdAt,
+		})
+
+		if len(batch) >= batchSize {
+			if err := s.writeBatch(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+
+	return s.writeBatch(batch)
+}
+
+// whatsAppChatReader returns the chat text, unwrapping it from a ZIP export.
+func whatsAppChatReader(file io.ReaderAt, size int64) (io.Reader, error) {
+	archive, err := zip.NewReader(file, size)
+	if err != nil {
+		// Not a ZIP, so it is the plain text export itself
+		return io.NewSectionReader(file, 0, size), nil
+	}
+
+	for _, f := range archive.File {
+		if strings.HasSuffix(strings.ToLower(path.Base(f.Name)), ".txt") {
+			return f.Open()
+		}
+	}
+	return nil, fmt.Errorf("%w: no chat text file in WhatsApp ZIP", ErrInvalidExport)
+}
+
+// parseWhatsAppLines splits the export into messages. Lines without a
+// timestamp continue the previous message; timestamped lines without an
+// author are system notices and are counted as ignored.
+func parseWhatsAppLines(r io.Reader, report *Report) ([]whatsAppLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []whatsAppLine
+	inMessage := false
+	for scanner.Scan() {
+		text := whatsAppInvisible.Replace(scanner.Text())
+
+		match := whatsAppIOSLine.FindStringSubmatch(text)
+		if match == nil {
+			match = whatsAppAndroidLine.FindStringSubmatch(text)
+		}
+
+		if match == nil {
+			if inMessage {
+				lines[len(lines)-1].text += "\n" + text
+			}
+			continue
+		}
+
+		author, body, ok := strings.Cut(match[8], ": ")
+		if !ok {
+			report.MessagesIgnored++
+			inMessage = false
+			continue
+		}
+
+		var l whatsAppLine
+		copy(l.fields[:], match[1:8])
+		l.author = strings.TrimSpace(author)
+		l.text = body
+		lines = append(lines, l)
+		inMessage = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: could not read WhatsApp chat: %v", ErrInvalidExport, err)
+	}
+
+	return lines, nil
+}
+
+// whatsAppDateOrder decides whether dates are day-first. Exports follow the
+// phone's locale, so unless the caller knows, the file itself is checked for
+// a component that can only be a day.
+func whatsAppDateOrder(lines []whatsAppLine, order string) (bool, error) {
+	switch order {
+	case "dmy":
+		return true, nil
+	case "mdy":
+		return false, nil
+	case "":
+	default:
+		return false, fmt.Errorf("%w: date order must be dmy or mdy", ErrInvalidExport)
+	}
+
+	for _, l := range lines {
+		first, _ := strconv.Atoi(l.fields[0])
+		second, _ := strconv.Atoi(l.fields[1])
+		if first > 12 {
+			return true, nil
+		}
+		if second > 12 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// whatsAppTime builds the timestamp of a message line.
+func whatsAppTime(l whatsAppLine, dayFirst bool, loc *time.Location) (time.Time, error) {
+	day, _ := strconv.Atoi(l.fields[0])
+	month, _ := strconv.Atoi(l.fields[1])
+	if !dayFirst {
+		day, month = month, day
+	}
+
+	year, _ := strconv.Atoi(l.fields[2])
+	if year < 100 {
+		year += 2000
+	}
+
+	hour, _ := strconv.Atoi(l.fields[3])
+	minute, _ := strconv.Atoi(l.fields[4])
+	second, _ := strconv.Atoi(l.fields[5])
+
+	meridiem := strings.ToLower(strings.NewReplacer(".", "", " ", "").Replace(l.fields[6]))
+	if meridiem == "pm" && hour < 12 {
+		hour += 12
+	}
+	if meridiem == "am" && hour == 12 {
+		hour = 0
+	}
+
+	t := time.Date(year, time.Month(month), day, hour, minute, second, 0, loc)
+	if t.Day() != day || int(t.Month()) != month {
+		return time.Time{}, fmt.Errorf("invalid date %s/%s/%s", l.fields[0], l.fields[1], l.fields[2])
+	}
+	return t, nil
+}
+
+// whatsAppUsername derives a username from a contact name or phone number,
+// e.g. "Alice Smith" becomes "alice_smith". Different contacts can share one;
+// userFor then numbers the placeholder accounts.
+func whatsAppUsername(name string) string {
+	username := strings.Trim(usernameUnsafe.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if username == "" {
+		username = "whatsapp_user"
+	}
+	return username
+}
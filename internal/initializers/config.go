@@ -1,16 +1,101 @@
 package initializers
 
 import (
+	"MessagingSystemBackend/internal/models"
 	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 var JWT_SECRET string
 
+// GroupLimits caps the size of a group and how long its messages stay editable.
+type GroupLimits struct {
+	MaxMembers int
+	MaxAdmins  int // The owner counts as an admin
+	EditWindow time.Duration
+}
+
+var (
+	// DefaultGroupLimits apply to groups that do not override them.
+	// Configured with GROUP_MAX_MEMBERS, GROUP_MAX_ADMINS and EDIT_WINDOW_MINUTES.
+	DefaultGroupLimits = GroupLimits{MaxMembers: 25, MaxAdmins: 2, EditWindow: time.Hour}
+
+	// PlatformGroupLimits are the largest values any group may use.
+	// Configured with PLATFORM_MAX_GROUP_MEMBERS, PLATFORM_MAX_GROUP_ADMINS and
+	// PLATFORM_MAX_EDIT_WINDOW_MINUTES.
+	PlatformGroupLimits = GroupLimits{MaxMembers: 1000, MaxAdmins: 10, EditWindow: 24 * time.Hour}
+
+	// GroupDeletionGrace is how long a deleted group can still be restored
+	// before its history is purged. Configured with GROUP_DELETION_GRACE_DAYS.
+	GroupDeletionGrace = 7 * 24 * time.Hour
+)
+
 func LoadEnv() {
 	err := godotenv.Load()
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
 }
+
+// LoadGroupLimits reads the group limits and the deletion grace period from
+// the environment, keeping the built-in values for unset variables. Defaults
+// above the platform maximums are lowered to them.
+func LoadGroupLimits() {
+	PlatformGroupLimits.MaxMembers = envInt("PLATFORM_MAX_GROUP_MEMBERS", PlatformGroupLimits.MaxMembers)
+	PlatformGroupLimits.MaxAdmins = envInt("PLATFORM_MAX_GROUP_ADMINS", PlatformGroupLimits.MaxAdmins)
+	PlatformGroupLimits.EditWindow = time.Duration(envInt("PLATFORM_MAX_EDIT_WINDOW_MINUTES", int(PlatformGroupLimits.EditWindow.Minutes()))) * time.Minute
+
+	DefaultGroupLimits.MaxMembers = min(envInt("GROUP_MAX_MEMBERS", DefaultGroupLimits.MaxMembers), PlatformGroupLimits.MaxMembers)
+	DefaultGroupLimits.MaxAdmins = min(envInt("GROUP_MAX_ADMINS", DefaultGroupLimits.MaxAdmins), PlatformGroupLimits.MaxAdmins)
+	DefaultGroupLimits.EditWindow = min(time.Duration(envInt("EDIT_WINDOW_MINUTES", int(DefaultGroupLimits.EditWindow.Minutes())))*time.Minute, PlatformGroupLimits.EditWindow)
+
+	GroupDeletionGrace = time.Duration(envInt("GROUP_DELETION_GRACE_DAYS", int(GroupDeletionGrace.Hours()/24))) * 24 * time.Hour
+}
+
+// GroupLimitsFor returns the limits a group uses: its own overrides where it
+// has them, the server defaults otherwise. Overrides never exceed the platform
+// maximums, even if those were lowered after the group was configured.
+func GroupLimitsFor(group models.Group) GroupLimits {
+	limits := DefaultGroupLimits
+	if group.MaxMembers != nil {
+		limits.MaxMembers = min(*group.MaxMembers, PlatformGroupLimits.MaxMembers)
+	}
+	if group.MaxAdmins != nil {
+		limits.MaxAdmins = min(*group.MaxAdmins, PlatformGroupLimits.MaxAdmins)
+	}
+	if group.EditWindowMinutes != nil {
+		limits.EditWindow = min(time.Duration(*group.EditWindowMinutes)*time.Minute, PlatformGroupLimits.EditWindow)
+	}
+	return limits
+}
+
+// envInt reads a positive integer from the environment, falling back to def
+// if the variable is unset or invalid.
+func envInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Printf("Ignoring invalid %s=%q", name, value)
+		return def
+	}
+	return n
+}
+
+// IsPlatformAdmin reports whether a user may call the /admin routes.
+// Admins are listed by username in ADMIN_USERNAMES, separated by commas.
+func IsPlatformAdmin(username string) bool {
+	for _, admin := range strings.Split(os.Getenv("ADMIN_USERNAMES"), ",") {
+		if strings.TrimSpace(admin) == username && username != "" {
+			return true
+		}
+	}
+	return false
+}
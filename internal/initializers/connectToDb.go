@@ -17,7 +17,9 @@ func ConnectToDb() {
 	dsn := os.Getenv("DB")
 
 	for i := 0; i < 10; i++ {
-		DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
+			TranslateError: true, // Unique violations become gorm.ErrDuplicatedKey
+		})
 		if err == nil {
 			fmt.Println("Connected to database")
 			return
@@ -0,0 +1,210 @@
+package initializers
+
+import (
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// migrateConversations moves data from the old per-type message tables into
+// conversations, conversation_members and messages. It is safe to run on
+// every start: groups are backfilled idempotently, and the old tables are
+// renamed to legacy_* once their rows have been copied. Every copied message
+// keeps its old ID in legacy_message_ids. Group messages keep their IDs when
+// messages is empty, and direct messages get IDs above every old one, so an
+// old ID never names a different message on the routes that still take them.
+func migrateConversations() {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := backfillGroupConversations(tx); err != nil {
+			return err
+		}
+
+		hasDMs := tx.Migrator().HasTable("direct_messages")
+		hasGroupMsgs := tx.Migrator().HasTable("group_messages")
+		if !hasDMs && !hasGroupMsgs {
+			return nil
+		}
+
+		// Remember where each copied message came from until references are updated
+		if err := tx.Exec(`ALTER TABLE messages ADD COLUMN IF NOT EXISTS legacy_ref TEXT`).Error; err != nil {
+			return err
+		}
+
+		if hasGroupMsgs {
+			if err := copyGroupMessages(tx); err != nil {
+				return err
+			}
+		}
+		if hasDMs {
+			if err := copyDirectMessages(tx); err != nil {
+				return err
+			}
+		}
+
+		if err := remapMessageReferences(tx); err != nil {
+			return err
+		}
+		if err := keepLegacyMessageIDs(tx); err != nil {
+			return err
+		}
+
+		statements := []string{`ALTER TABLE messages DROP COLUMN legacy_ref`}
+		if hasDMs {
+			statements = append(statements, `ALTER TABLE direct_messages RENAME TO legacy_direct_messages`)
+		}
+		if hasGroupMsgs {
+			statements = append(statements, `ALTER TABLE group_messages RENAME TO legacy_group_messages`)
+		}
+		for _, sql := range statements {
+			if err := tx.Exec(sql).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalln("Failed to migrate messages to conversations:", err)
+	}
+}
+
+// backfillGroupConversations makes sure every group has a conversation
+// containing all of its members.
+func backfillGroupConversations(tx *gorm.DB) error {
+	// SQL: INSERT INTO conversations (kind, group_id, created_at) SELECT 'group', id, created_at FROM groups WHERE <no conversation yet>;
+	err := tx.Exec(`
+		INSERT INTO conversations (kind, group_id, created_at)
+		SELECT 'group', g.id, g.created_at
+		FROM groups g
+		WHERE NOT EXISTS (SELECT 1 FROM conversations c WHERE c.group_id = g.id)
+	`).Error
+	if err != nil {
+		return err
+	}
+
+	// SQL: INSERT INTO conversation_members (conversation_id, user_id, joined_at) SELECT ... FROM group_members ON CONFLICT DO NOTHING;
+	return tx.Exec(`
+		INSERT INTO conversation_members (conversation_id, user_id, joined_at)
+		SELECT c.id, gm.user_id, gm.joined_at
+		FROM group_members gm
+		JOIN conversations c ON c.group_id = gm.group_id
+		ON CONFLICT (conversation_id, user_id) DO NOTHING
+	`).Error
+}
+
+// copyDirectMessages creates one direct conversation per pair of users and
+// copies their messages into it.
+func copyDirectMessages(tx *gorm.DB) error {
+	statements := []string{
+		`INSERT INTO conversations (kind, direct_key, created_at)
+		SELECT 'direct', ` + legacyDirectKey("sender_id", "receiver_id") + `, MIN(created_at)
+		FROM direct_messages
+		GROUP BY LEAST(sender_id, receiver_id), GREATEST(sender_id, receiver_id)
+		ON CONFLICT (direct_key) DO NOTHING`,
+
+		`INSERT INTO conversation_members (conversation_id, user_id, joined_at)
+		SELECT c.id, p.user_id::bigint, c.created_at
+		FROM conversations c
+		CROSS JOIN LATERAL unnest(string_to_array(c.direct_key, ':')) p(user_id)
+		WHERE c.kind = 'direct'
+		ON CONFLICT (conversation_id, user_id) DO NOTHING`,
+
+		// IDs start above both the messages copied so far and every old
+		// direct message ID
+		`INSERT INTO messages (id, conversation_id, sender_id, content, created_at, updated_at, legacy_ref)
+		SELECT offs.n + dm.id, c.id, dm.sender_id, dm.content, dm.created_at, dm.updated_at, 'dm:' || dm.id
+		FROM direct_messages dm
+		JOIN conversations c ON c.direct_key = ` + legacyDirectKey("dm.sender_id", "dm.receiver_id") + `
+		CROSS JOIN (SELECT GREATEST((SELECT COALESCE(MAX(id), 0) FROM messages), (SELECT COALESCE(MAX(id), 0) FROM direct_messages)) AS n) offs
+		ORDER BY dm.id`,
+	}
+	for _, sql := range statements {
+		if err := tx.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// legacyDirectKey builds the same key as models.DirectKey in SQL. A
+// conversation with yourself has a single member, so its key is one ID.
+func legacyDirectKey(sender, receiver string) string {
+	return fmt.Sprintf("CASE WHEN %[1]s = %[2]s THEN %[1]s::text ELSE LEAST(%[1]s, %[2]s) || ':' || GREATEST(%[1]s, %[2]s) END", sender, receiver)
+}
+
+// copyGroupMessages copies group messages into their group's conversation.
+// They keep their IDs unless messages already has rows, in which case they
+// start above both those rows and every old group message ID.
+func copyGroupMessages(tx *gorm.DB) error {
+	return tx.Exec(`
+		INSERT INTO messages (id, conversation_id, sender_id, content, created_at, updated_at, legacy_ref)
+		SELECT offs.n + gm.id, c.id, gm.sender_id, gm.content, gm.created_at, gm.updated_at, 'group:' || gm.id
+		FROM group_messages gm
+		JOIN conversations c ON c.group_id = gm.group_id
+		CROSS JOIN (
+			SELECT CASE WHEN EXISTS (SELECT 1 FROM messages) THEN
+				GREATEST((SELECT MAX(id) FROM messages), (SELECT COALESCE(MAX(id), 0) FROM group_messages))
+			ELSE 0 END AS n
+		) offs
+		ORDER BY gm.id
+	`).Error
+}
+
+// keepLegacyMessageIDs records the old ID of every copied message and moves
+// the ID sequence past the copied IDs.
+func keepLegacyMessageIDs(tx *gorm.DB) error {
+	statements := []string{
+		`INSERT INTO legacy_message_ids (kind, legacy_id, message_id)
+		SELECT CASE split_part(legacy_ref, ':', 1) WHEN 'dm' THEN 'direct' ELSE 'group' END,
+			split_part(legacy_ref, ':', 2)::bigint, id
+		FROM messages
+		WHERE legacy_ref IS NOT NULL
+		ON CONFLICT DO NOTHING`,
+
+		`SELECT setval(pg_get_serial_sequence('messages', 'id'), GREATEST((SELECT MAX(id) FROM messages), 1))`,
+	}
+	for _, sql := range statements {
+		if err := tx.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remapMessageReferences points polls, edit history and import records at
+// the copied messages instead of the old per-type tables.
+func remapMessageReferences(tx *gorm.DB) error {
+	var statements []string
+
+	if tx.Migrator().HasTable("polls") {
+		statements = append(statements,
+			`ALTER TABLE polls DROP CONSTRAINT IF EXISTS fk_polls_message`,
+			// Negate first so the unique index never sees an old and a new ID collide
+			`UPDATE polls SET message_id = -message_id`,
+			`UPDATE polls p SET message_id = m.id FROM messages m WHERE m.legacy_ref = 'group:' || (-p.message_id)`,
+		)
+	}
+
+	if tx.Migrator().HasColumn("message_edits", "message_type") {
+		statements = append(statements,
+			`UPDATE message_edits e SET message_id = m.id FROM messages m WHERE m.legacy_ref = e.message_type || ':' || e.message_id`,
+			`ALTER TABLE message_edits DROP COLUMN message_type`,
+		)
+	}
+
+	if tx.Migrator().HasTable("import_records") {
+		statements = append(statements,
+			`UPDATE import_records r SET entity_id = m.id, entity_type = 'message'
+			FROM messages m
+			WHERE r.entity_type IN ('direct_message', 'group_message')
+				AND m.legacy_ref = (CASE r.entity_type WHEN 'direct_message' THEN 'dm:' ELSE 'group:' END) || r.entity_id`,
+		)
+	}
+
+	for _, sql := range statements {
+		if err := tx.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package initializers
+
+import "log"
+
+// migrateGroupNames drops the old unique index on groups.name, which covered
+// every group, so AutoMigrate can recreate it for standalone groups only and
+// channels in different communities may share a name. The index is only
+// dropped while it is not yet partial, so this is safe to run on every start.
+func migrateGroupNames() {
+	// SQL: SELECT indexdef LIKE '% WHERE %' FROM pg_indexes WHERE indexname = 'idx_groups_name';
+	var partial bool
+	DB.Raw(`SELECT indexdef LIKE '% WHERE %' FROM pg_indexes WHERE indexname = 'idx_groups_name'`).Scan(&partial)
+	if partial {
+		return
+	}
+
+	// SQL: DROP INDEX IF EXISTS idx_groups_name;
+	if err := DB.Exec(`DROP INDEX IF EXISTS idx_groups_name`).Error; err != nil {
+		log.Fatalln("Failed to migrate group names:", err)
+	}
+}
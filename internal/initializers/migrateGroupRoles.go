@@ -0,0 +1,65 @@
+package initializers
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// migrateGroupRoles replaces the old group_members.is_admin flag and
+// groups.owner_id column with member roles, then makes sure every group has
+// exactly one owner. It is safe to run on every start: the old columns are
+// dropped once converted, and groups that already have an owner are left alone.
+func migrateGroupRoles() {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if tx.Migrator().HasColumn("group_members", "is_admin") {
+			// SQL: UPDATE group_members SET role = 'admin' WHERE is_admin AND role = 'member';
+			if err := tx.Exec(`UPDATE group_members SET role = 'admin' WHERE is_admin AND role = 'member'`).Error; err != nil {
+				return err
+			}
+			// SQL: ALTER TABLE group_members DROP COLUMN is_admin;
+			if err := tx.Exec(`ALTER TABLE group_members DROP COLUMN is_admin`).Error; err != nil {
+				return err
+			}
+		}
+
+		if tx.Migrator().HasColumn("groups", "owner_id") {
+			// SQL: UPDATE group_members m SET role = 'owner' FROM groups g WHERE g.id = m.group_id AND m.user_id = g.owner_id;
+			err := tx.Exec(`
+				UPDATE group_members m SET role = 'owner'
+				FROM groups g
+				WHERE g.id = m.group_id AND m.user_id = g.owner_id
+			`).Error
+			if err != nil {
+				return err
+			}
+			// SQL: ALTER TABLE groups DROP COLUMN owner_id;
+			if err := tx.Exec(`ALTER TABLE groups DROP COLUMN owner_id`).Error; err != nil {
+				return err
+			}
+		}
+
+		// Groups without an owner get one: the creator if they are still an
+		// admin, otherwise the longest-standing admin, otherwise the
+		// longest-standing member
+		// SQL: UPDATE group_members SET role = 'owner' WHERE id IN (<first candidate of each ownerless group>);
+		return tx.Exec(`
+			UPDATE group_members SET role = 'owner'
+			WHERE id IN (
+				SELECT DISTINCT ON (m.group_id) m.id
+				FROM group_members m
+				JOIN groups g ON g.id = m.group_id
+				WHERE NOT EXISTS (
+					SELECT 1 FROM group_members o WHERE o.group_id = m.group_id AND o.role = 'owner'
+				)
+				ORDER BY m.group_id,
+					(m.role = 'admin' AND m.user_id = g.created_by) DESC,
+					(m.role = 'admin') DESC,
+					m.joined_at, m.id
+			)
+		`).Error
+	})
+	if err != nil {
+		log.Fatalln("Failed to migrate group roles:", err)
+	}
+}
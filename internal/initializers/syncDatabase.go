@@ -3,5 +3,42 @@ package initializers
 import "MessagingSystemBackend/internal/models"
 
 func SyncDatabase() {
-	DB.AutoMigrate(&models.User{}, &models.Group{}, &models.GroupMember{}, &models.GroupMessage{}, &models.DirectMessage{})
+	// Runs before groups are migrated so the name index is recreated as a partial one
+	migrateGroupNames()
+
+	DB.AutoMigrate(&models.User{}, &models.Community{}, &models.Group{}, &models.GroupMember{},
+		&models.Conversation{}, &models.ConversationMember{}, &models.Message{}, &models.LegacyMessageID{})
+
+	// Runs before the remaining tables are migrated so their foreign keys to
+	// messages are only created once the old message IDs have been remapped
+	migrateConversations()
+	migrateGroupRoles()
+
+	DB.AutoMigrate(&models.Poll{}, &models.PollOption{}, &models.PollVote{}, &models.PollVoteHistory{},
+		&models.MessageEdit{}, &models.ImportJob{}, &models.ImportRecord{},
+		&models.BroadcastList{}, &models.BroadcastListMember{},
+		&models.GroupMembershipEvent{}, &models.OwnershipTransfer{}, &models.GroupPermissionOverride{},
+		&models.GroupInvite{}, &models.GroupInviteUse{}, &models.GroupJoinRequest{}, &models.GroupInvitation{},
+		&models.GroupAvatar{}, &models.GroupMute{}, &models.GroupBan{},
+		&models.GroupAuditEntry{}, &models.CommunityMember{})
+
+	createSearchIndexes()
+}
+
+// createSearchIndexes adds the generated tsvector columns and GIN indexes used
+// by message search and the group directory. AutoMigrate cannot express
+// generated columns, so they are managed here with idempotent DDL.
+func createSearchIndexes() {
+	// SQL: ALTER TABLE messages ADD COLUMN content_tsv tsvector GENERATED ALWAYS AS (to_tsvector('english', content)) STORED;
+	//      CREATE INDEX idx_messages_content_tsv ON messages USING GIN (content_tsv);
+	DB.Exec(`ALTER TABLE messages ADD COLUMN IF NOT EXISTS content_tsv tsvector
+		GENERATED ALWAYS AS (to_tsvector('english', content)) STORED`)
+	DB.Exec(`CREATE INDEX IF NOT EXISTS idx_messages_content_tsv ON messages USING GIN (content_tsv)`)
+
+	// SQL: ALTER TABLE groups ADD COLUMN profile_tsv tsvector
+	//        GENERATED ALWAYS AS (to_tsvector('english', name || ' ' || coalesce(description, '') || ' ' || coalesce(topic, ''))) STORED;
+	//      CREATE INDEX idx_groups_profile_tsv ON groups USING GIN (profile_tsv);
+	DB.Exec(`ALTER TABLE groups ADD COLUMN IF NOT EXISTS profile_tsv tsvector
+		GENERATED ALWAYS AS (to_tsvector('english', name || ' ' || coalesce(description, '') || ' ' || coalesce(topic, ''))) STORED`)
+	DB.Exec(`CREATE INDEX IF NOT EXISTS idx_groups_profile_tsv ON groups USING GIN (profile_tsv)`)
 }
@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"log"
+	"time"
+)
+
+// How often deleted groups are checked for an ended grace period
+const purgeInterval = time.Hour
+
+// StartGroupPurge runs PurgeDeletedGroups in the background, right away and
+// then every purgeInterval.
+func StartGroupPurge() {
+	go func() {
+		for {
+			if purged, err := PurgeDeletedGroups(time.Now()); err != nil {
+				log.Println("Group purge failed:", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d deleted groups", purged)
+			}
+			time.Sleep(purgeInterval)
+		}
+	}()
+}
+
+// PurgeDeletedGroups permanently deletes the groups whose grace period ended
+// before now. Deleting a group cascades to its conversation, messages,
+// memberships, polls, invites and history. Each group is purged on its own so
+// one failure does not hold back the others.
+func PurgeDeletedGroups(now time.Time) (int, error) {
+	// SQL: SELECT id FROM groups WHERE purge_at <= ?;
+	var ids []uint
+	if err := initializers.DB.Model(&models.Group{}).Where("purge_at <= ?", now).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	var firstErr error
+	for _, id := range ids {
+		// Checked again in the DELETE in case the group was restored meanwhile
+		// SQL: DELETE FROM groups WHERE id = ? AND purge_at <= ?;
+		result := initializers.DB.Where("purge_at <= ?", now).Delete(&models.Group{}, id)
+		if result.Error != nil {
+			if firstErr == nil {
+				firstErr = result.Error
+			}
+			continue
+		}
+		purged += int(result.RowsAffected)
+	}
+	return purged, firstErr
+}
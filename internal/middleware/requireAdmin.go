@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin only lets platform admins through. It must run after RequireAuth.
+func RequireAdmin(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	if !initializers.IsPlatformAdmin(user.Username) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	c.Next()
+}
@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// Audit log actions
+const (
+	AuditMemberAdded         = "member.added"
+	AuditMemberRemoved       = "member.removed"
+	AuditRoleChanged         = "member.role_changed" // Promotions and demotions too; before and after hold the role
+	AuditMemberMuted         = "member.muted"
+	AuditMemberUnmuted       = "member.unmuted"
+	AuditMemberBanned        = "member.banned"
+	AuditMemberUnbanned      = "member.unbanned"
+	AuditSettingsChanged     = "settings.changed"
+	AuditPermissionChanged   = "permission.changed"
+	AuditProfileChanged      = "profile.changed"
+	AuditAvatarChanged       = "avatar.changed"
+	AuditMessageDeleted      = "message.deleted" // Only other members' messages
+	AuditMessagePinned       = "message.pinned"
+	AuditMessageUnpinned     = "message.unpinned"
+	AuditJoinApproved        = "join_request.approved"
+	AuditJoinRejected        = "join_request.rejected"
+	AuditInviteCreated       = "invite.created"
+	AuditInviteRevoked       = "invite.revoked"
+	AuditInvitationSent      = "invitation.sent"
+	AuditInvitationCancelled = "invitation.cancelled"
+	AuditGroupArchived       = "group.archived"
+	AuditGroupUnarchived     = "group.unarchived"
+	AuditGroupDeleted        = "group.deleted"
+	AuditGroupRestored       = "group.restored"
+	AuditTransferOffered     = "ownership.offered"
+	AuditTransferCancelled   = "ownership.cancelled"
+	AuditTransferDeclined    = "ownership.declined"
+	AuditOwnerChanged        = "ownership.accepted" // before and after hold the owner; after also holds the previous owner's new role
+	AuditPollClosed          = "poll.closed"
+)
+
+// GroupAuditEntry records one administrative action in a group: who did it,
+// to whom or what, and the values before and after, as JSON.
+type GroupAuditEntry struct {
+	ID uint `gorm:"primaryKey"`
+
+	GroupID uint  `gorm:"not null;index:idx_audit_group_created,priority:1"`
+	Group   Group `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+
+	ActorID         uint   `gorm:"not null;index"`
+	Action          string `gorm:"not null;index"`
+	TargetUserID    *uint  `gorm:"index"` // Member the action applies to, if any
+	TargetMessageID *uint  // Message the action applies to, if any; it may since have been deleted
+
+	Before *string `gorm:"type:jsonb"` // nil when there was nothing before, e.g. for additions
+	After  *string `gorm:"type:jsonb"` // nil when nothing is left, e.g. for removals
+
+	CreatedAt time.Time `gorm:"index:idx_audit_group_created,priority:2"`
+}
+
+// CREATE TABLE group_audit_entries (
+//     id SERIAL PRIMARY KEY,
+//     group_id INTEGER NOT NULL,
+//     actor_id INTEGER NOT NULL,
+//     action VARCHAR(64) NOT NULL,
+//     target_user_id INTEGER,
+//     target_message_id INTEGER,
+//     before JSONB,
+//     after JSONB,
+//     created_at TIMESTAMP,
+//     FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+// );
+
+// CREATE INDEX idx_audit_group_created ON group_audit_entries(group_id, created_at);
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// BroadcastList is a saved set of recipients that a user can send the same
+// direct message to in one go.
+type BroadcastList struct {
+	ID uint `gorm:"primaryKey"`
+
+	OwnerID uint `gorm:"not null;uniqueIndex:idx_broadcast_owner_name"` // Listing a user's lists
+	Owner   User `gorm:"foreignKey:OwnerID;constraint:OnDelete:CASCADE"`
+
+	Name      string `gorm:"not null;uniqueIndex:idx_broadcast_owner_name"` // Unique per owner
+	CreatedAt time.Time
+
+	Members []BroadcastListMember `gorm:"foreignKey:ListID"`
+}
+
+// CREATE TABLE broadcast_lists (
+//     id SERIAL PRIMARY KEY,
+//     owner_id INTEGER NOT NULL,
+//     name VARCHAR(255) NOT NULL,
+//     created_at TIMESTAMP,
+//     FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE,
+//     UNIQUE (owner_id, name)
+// );
+
+type BroadcastListMember struct {
+	ID uint `gorm:"primaryKey"`
+
+	ListID uint          `gorm:"not null;uniqueIndex:idx_broadcast_list_user"`
+	List   BroadcastList `gorm:"foreignKey:ListID;constraint:OnDelete:CASCADE"`
+
+	UserID uint `gorm:"not null;index;uniqueIndex:idx_broadcast_list_user"`
+	User   User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+
+	AddedAt time.Time
+}
+
+// CREATE TABLE broadcast_list_members (
+//     id SERIAL PRIMARY KEY,
+//     list_id INTEGER NOT NULL,
+//     user_id INTEGER NOT NULL,
+//     added_at TIMESTAMP,
+//     FOREIGN KEY (list_id) REFERENCES broadcast_lists(id) ON DELETE CASCADE,
+//     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+//     UNIQUE (list_id, user_id)
+// );
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Community groups several channels under one shared membership. Channels are
+// groups with CommunityID set: every community member belongs to its public
+// channels with their community role, while private channels only hold the
+// members added to them.
+type Community struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        string `gorm:"uniqueIndex;not null"`
+	Description string
+	CreatedBy   uint `gorm:"not null;index"`
+	Creator     User `gorm:"foreignKey:CreatedBy;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time
+}
+
+// CREATE TABLE communities (
+//     id SERIAL PRIMARY KEY,
+//     name VARCHAR(255) NOT NULL UNIQUE,
+//     description TEXT,
+//     created_by INTEGER NOT NULL,
+//     created_at TIMESTAMP,
+//     FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+// );
+
+// CommunityMember is a user's membership of a community. Role is RoleOwner,
+// RoleAdmin or RoleMember and is copied to the member's channel memberships.
+type CommunityMember struct {
+	ID uint `gorm:"primaryKey"`
+
+	CommunityID uint      `gorm:"not null;uniqueIndex:idx_community_user"`
+	Community   Community `gorm:"foreignKey:CommunityID;constraint:OnDelete:CASCADE"`
+
+	UserID uint `gorm:"not null;uniqueIndex:idx_community_user;index"`
+	User   User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+
+	Role     string `gorm:"not null;default:member"`
+	JoinedAt time.Time
+}
+
+// CREATE TABLE community_members (
+//     id SERIAL PRIMARY KEY,
+//     community_id INTEGER NOT NULL,
+//     user_id INTEGER NOT NULL,
+//     role VARCHAR(16) NOT NULL DEFAULT 'member',
+//     joined_at TIMESTAMP,
+//     FOREIGN KEY (community_id) REFERENCES communities(id) ON DELETE CASCADE,
+//     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+//     UNIQUE (community_id, user_id)
+// );
+
+// CREATE INDEX idx_community_members_user_id ON community_members(user_id);
+
+// IsValidCommunityRole reports whether role can be held in a community.
+func IsValidCommunityRole(role string) bool {
+	return role == RoleOwner || role == RoleAdmin || role == RoleMember
+}
@@ -0,0 +1,203 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Conversation kinds
+const (
+	ConversationDirect = "direct"
+	ConversationGroup  = "group"
+)
+
+// Message kinds
+const (
+	MessageUser   = "user"
+	MessageSystem = "system" // Generated announcement, e.g. a member leaving
+)
+
+// System message events. Their details are stored as JSON in EventData so
+// clients can render them in their own language; Content keeps an English
+// rendering for clients that do not.
+const (
+	SystemGroupCreated    = "group_created"
+	SystemMemberAdded     = "member_added"
+	SystemMemberJoined    = "member_joined" // "via" is the membership action, e.g. joined_by_invite
+	SystemMemberRemoved   = "member_removed"
+	SystemMemberLeft      = "member_left"
+	SystemMemberBanned    = "member_banned"
+	SystemRoleChanged     = "role_changed"
+	SystemOwnerChanged    = "owner_changed"
+	SystemProfileChanged  = "profile_changed" // "field" is name, description, topic, visibility or color
+	SystemAvatarChanged   = "avatar_changed"
+	SystemAvatarRemoved   = "avatar_removed"
+	SystemGroupArchived   = "group_archived"
+	SystemGroupUnarchived = "group_unarchived"
+	SystemGroupDeleted    = "group_deleted"
+	SystemGroupRestored   = "group_restored"
+	SystemMessagePinned   = "message_pinned"
+	SystemMessageUnpinned = "message_unpinned"
+)
+
+// Conversation is a chat between its members. A direct conversation is
+// identified by its participants; a group conversation belongs to a Group.
+type Conversation struct {
+	ID   uint   `gorm:"primaryKey"`
+	Kind string `gorm:"not null;index"` // "direct" or "group"
+
+	GroupID *uint  `gorm:"uniqueIndex"` // Set for group conversations
+	Group   *Group `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+
+	DirectKey *string `gorm:"uniqueIndex"` // Sorted participant IDs ("3:7"), set for direct conversations
+
+	CreatedAt time.Time
+}
+
+// CREATE TABLE conversations (
+//     id SERIAL PRIMARY KEY,
+//     kind VARCHAR(16) NOT NULL,
+//     group_id INTEGER UNIQUE,
+//     direct_key TEXT UNIQUE,
+//     created_at TIMESTAMP,
+//     CONSTRAINT fk_conversations_group FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+// );
+
+type ConversationMember struct {
+	ID uint `gorm:"primaryKey"`
+
+	ConversationID uint         `gorm:"not null;index;uniqueIndex:idx_conversation_user"` // Used in WHERE and JOIN
+	Conversation   Conversation `gorm:"foreignKey:ConversationID;constraint:OnDelete:CASCADE"`
+
+	UserID uint `gorm:"not null;index;uniqueIndex:idx_conversation_user"` // Listing a user's conversations
+	User   User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+
+	JoinedAt time.Time
+}
+
+// CREATE TABLE conversation_members (
+//     id SERIAL PRIMARY KEY,
+//     conversation_id INTEGER NOT NULL,
+//     user_id INTEGER NOT NULL,
+//     joined_at TIMESTAMP,
+//     FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+//     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+//     UNIQUE (conversation_id, user_id)
+// );
+
+type Message struct {
+	ID uint `gorm:"primaryKey"`
+
+	ConversationID uint         `gorm:"not null;index;index:idx_messages_conversation_created,priority:1"` // Chat history lookups
+	Conversation   Conversation `gorm:"foreignKey:ConversationID;constraint:OnDelete:CASCADE"`
+
+	SenderID uint `gorm:"not null;index"` // Filtering by sender; the acting user for system messages
+	Sender   User `gorm:"foreignKey:SenderID;constraint:OnDelete:CASCADE"`
+
+	Kind      string    `gorm:"not null;default:user"` // "user" or "system"
+	Content   string    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"index;index:idx_messages_conversation_created,priority:2"` // Ordering by time
+	UpdatedAt time.Time
+
+	// System messages only; empty for announcements made before events were typed
+	Event     string  // See the System* constants
+	EventData *string `gorm:"type:jsonb"`
+
+	PinnedAt *time.Time // Set while the message is pinned in its group
+	PinnedBy *uint
+}
+
+// CREATE TABLE messages (
+//     id SERIAL PRIMARY KEY,
+//     conversation_id INTEGER NOT NULL,
+//     sender_id INTEGER NOT NULL,
+//     kind VARCHAR(16) DEFAULT 'user' NOT NULL,
+//     content TEXT NOT NULL,
+//     created_at TIMESTAMP,
+//     updated_at TIMESTAMP,
+//     pinned_at TIMESTAMP,
+//     pinned_by INTEGER,
+//     event VARCHAR(32),
+//     event_data JSONB,
+//     CONSTRAINT fk_messages_conversation FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+//     CONSTRAINT fk_messages_sender FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE
+// );
+
+// CREATE INDEX idx_messages_conversation_created ON messages(conversation_id, created_at);
+
+// LegacyMessageID maps the ID a message had in the old group_messages or
+// direct_messages table to its ID in messages, so clients holding old IDs
+// keep working on the routes that predate conversations.
+type LegacyMessageID struct {
+	Kind      string  `gorm:"primaryKey"` // ConversationGroup or ConversationDirect
+	LegacyID  uint    `gorm:"primaryKey;autoIncrement:false"`
+	MessageID uint    `gorm:"not null;index"`
+	Message   Message `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE"`
+}
+
+// CREATE TABLE legacy_message_ids (
+//     kind VARCHAR(16) NOT NULL,
+//     legacy_id INTEGER NOT NULL,
+//     message_id INTEGER NOT NULL,
+//     PRIMARY KEY (kind, legacy_id),
+//     FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+// );
+
+// Full-text search (created in SyncDatabase, not part of the struct):
+// ALTER TABLE messages ADD COLUMN content_tsv tsvector GENERATED ALWAYS AS (to_tsvector('english', content)) STORED;
+// CREATE INDEX idx_messages_content_tsv ON messages USING GIN (content_tsv);
+
+// DirectKey returns the key shared by every direct conversation between the
+// same set of users, regardless of order or duplicates.
+func DirectKey(userIDs []uint) string {
+	unique := map[uint]struct{}{}
+	var ids []uint
+	for _, id := range userIDs {
+		if _, ok := unique[id]; !ok {
+			unique[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+	return strings.Join(parts, ":")
+}
+
+// FindOrCreateDirectConversation returns the direct conversation between the
+// given users, creating it and its members the first time.
+func FindOrCreateDirectConversation(tx *gorm.DB, userIDs []uint) (Conversation, error) {
+	key := DirectKey(userIDs)
+	conv := Conversation{Kind: ConversationDirect, DirectKey: &key, CreatedAt: time.Now()}
+
+	// SQL: INSERT INTO conversations (kind, direct_key, created_at) VALUES ('direct', ?, ?) ON CONFLICT (direct_key) DO NOTHING;
+	result := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "direct_key"}}, DoNothing: true}).Create(&conv)
+	if result.Error != nil {
+		return conv, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		// Another request created it first
+		// SQL: SELECT * FROM conversations WHERE direct_key = ? LIMIT 1;
+		err := tx.Where("direct_key = ?", key).First(&conv).Error
+		return conv, err
+	}
+
+	for _, id := range userIDs {
+		member := ConversationMember{ConversationID: conv.ID, UserID: id, JoinedAt: conv.CreatedAt}
+		// SQL: INSERT INTO conversation_members (conversation_id, user_id, joined_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING;
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&member).Error; err != nil {
+			return conv, err
+		}
+	}
+
+	return conv, nil
+}
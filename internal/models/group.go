@@ -1,24 +1,164 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Group posting policies
+const (
+	PostingEveryone = "everyone" // Every role with the post capability may post
+	PostingAdmins   = "admins"   // Only the owner and admins may post
+	PostingRoles    = "roles"    // Only the owner and the roles in PostingRoles may post
+)
+
+// Group visibilities
+const (
+	GroupPrivate = "private" // Only members can see the group
+	GroupPublic  = "public"  // Anyone can see the group's info
+)
 
 type Group struct {
-	ID        uint      `gorm:"primaryKey"`
-	Name      string    `gorm:"uniqueIndex;not null"` // Fast lookup by name
-	CreatedBy uint      `gorm:"not null;index"`       // For joins
+	ID uint `gorm:"primaryKey"`
+	// Unique among standalone groups, and among the channels of a community
+	Name      string    `gorm:"not null;uniqueIndex:idx_groups_name,where:community_id IS NULL;uniqueIndex:idx_groups_community_name,priority:2"`
+	CreatedBy uint      `gorm:"not null;index"` // For joins
 	Creator   User      `gorm:"foreignKey:CreatedBy;constraint:OnDelete:CASCADE"`
 	CreatedAt time.Time `gorm:"index"` // If sorting or filtering by date
+
+	// Channels belong to a community and take their members from it
+	CommunityID    *uint      `gorm:"uniqueIndex:idx_groups_community_name,priority:1,where:community_id IS NOT NULL"`
+	Community      *Community `gorm:"foreignKey:CommunityID;constraint:OnDelete:CASCADE"`
+	PrivateChannel bool       `gorm:"not null;default:false"` // Only members added to the channel, not the whole community
+
+	// Per-group limits; nil uses the server default
+	MaxMembers        *int
+	MaxAdmins         *int
+	EditWindowMinutes *int
+
+	AllowJoinRequests bool `gorm:"not null;default:false"` // Non-members may ask to join
+
+	// Who may post, on top of the post capability; see the Posting* constants
+	PostingPolicy string `gorm:"not null;default:everyone"`
+	PostingRoles  string // Comma-separated roles, used with PostingRoles
+
+	SlowModeSeconds int `gorm:"not null;default:0"` // Minimum interval between a member's messages; 0 is off
+
+	// Profile shown in the group info
+	Description     string
+	Topic           string
+	Visibility      string     `gorm:"not null;default:private"` // See GroupPrivate and GroupPublic
+	Color           string     // "#rrggbb", empty for the client's default
+	AvatarUpdatedAt *time.Time // nil when the group has no avatar
+
+	// Archived groups are read-only until unarchived
+	ArchivedAt *time.Time
+	ArchivedBy *uint
+
+	// Groups the owner deleted stay restorable until PurgeAt, when their history is erased
+	DeleteRequestedBy *uint
+	PurgeAt           *time.Time `gorm:"index"` // Found by the purge job
 }
 
 // CREATE TABLE groups (
 //     id SERIAL PRIMARY KEY,
-//     name VARCHAR(255) NOT NULL UNIQUE,
-//     created_at TIMESTAMP
+//     name VARCHAR(255) NOT NULL,
+//     created_by INTEGER NOT NULL,
+//     created_at TIMESTAMP,
+//     community_id INTEGER,
+//     private_channel BOOLEAN NOT NULL DEFAULT false,
+//     max_members INTEGER,
+//     max_admins INTEGER,
+//     edit_window_minutes INTEGER,
+//     allow_join_requests BOOLEAN NOT NULL DEFAULT false,
+//     posting_policy VARCHAR(16) NOT NULL DEFAULT 'everyone',
+//     posting_roles TEXT,
+//     slow_mode_seconds INTEGER NOT NULL DEFAULT 0,
+//     description TEXT,
+//     topic TEXT,
+//     visibility VARCHAR(16) NOT NULL DEFAULT 'private',
+//     color VARCHAR(7),
+//     avatar_updated_at TIMESTAMP,
+//     archived_at TIMESTAMP,
+//     archived_by INTEGER,
+//     delete_requested_by INTEGER,
+//     purge_at TIMESTAMP,
+//     FOREIGN KEY (community_id) REFERENCES communities(id) ON DELETE CASCADE
 // );
 
+// Group names are unique among standalone groups, channel names within their community:
+// CREATE UNIQUE INDEX idx_groups_name ON groups(name) WHERE community_id IS NULL;
+// CREATE UNIQUE INDEX idx_groups_community_name ON groups(community_id, name) WHERE community_id IS NOT NULL;
+
 // if you sort/filter by recent groups:
 // CREATE INDEX idx_groups_created_at ON groups(created_at);
 
+// CREATE INDEX idx_groups_purge_at ON groups(purge_at);
+
+// IsReadOnly reports whether the group is archived or awaiting deletion, when
+// nobody may change anything in it.
+func (g Group) IsReadOnly() bool {
+	return g.ArchivedAt != nil || g.PurgeAt != nil
+}
+
+// IsPublic reports whether anyone may see the group and join it by
+// themselves. A private channel never is, whatever its visibility says.
+func (g Group) IsPublic() bool {
+	return g.Visibility == GroupPublic && !g.PrivateChannel
+}
+
+// IsValidPostingPolicy reports whether policy is a known posting policy.
+func IsValidPostingPolicy(policy string) bool {
+	return policy == PostingEveryone || policy == PostingAdmins || policy == PostingRoles
+}
+
+// PostingAllows reports whether the group's posting policy lets a role post.
+// The owner may always post; the post capability is checked separately.
+func (g Group) PostingAllows(role string) bool {
+	switch g.PostingPolicy {
+	case PostingAdmins:
+		return role == RoleOwner || role == RoleAdmin
+	case PostingRoles:
+		if role == RoleOwner {
+			return true
+		}
+		for _, r := range strings.Split(g.PostingRoles, ",") {
+			if r == role {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// AfterCreate gives every new group its conversation.
+func (g *Group) AfterCreate(tx *gorm.DB) error {
+	// SQL: INSERT INTO conversations (kind, group_id, created_at) VALUES ('group', ?, ?);
+	return tx.Create(&Conversation{Kind: ConversationGroup, GroupID: &g.ID, CreatedAt: g.CreatedAt}).Error
+}
+
+// GroupAvatar is the uploaded picture of a group, kept apart from the group
+// row so group queries do not load it.
+type GroupAvatar struct {
+	GroupID uint  `gorm:"primaryKey"`
+	Group   Group `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+
+	ContentType string `gorm:"not null"`
+	Data        []byte `gorm:"not null"`
+	UpdatedAt   time.Time
+}
+
+// CREATE TABLE group_avatars (
+//     group_id INTEGER PRIMARY KEY,
+//     content_type VARCHAR(32) NOT NULL,
+//     data BYTEA NOT NULL,
+//     updated_at TIMESTAMP,
+//     FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+// );
+
 type GroupMember struct {
 	ID uint `gorm:"primaryKey"`
 
@@ -28,48 +168,40 @@ type GroupMember struct {
 	UserID uint `gorm:"index;uniqueIndex:idx_group_user"` // Used in WHERE and JOIN
 	User   User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 
-	IsAdmin  bool      `gorm:"not null;default:false;index"` // Filtering (admin checks)
-	JoinedAt time.Time `gorm:"index"`                        // Optional, for sorting by join time
+	Role     string    `gorm:"not null;default:member;index"` // See Role* constants; exactly one owner per group
+	JoinedAt time.Time `gorm:"index"`                         // Optional, for sorting by join time
 }
 
 // CREATE TABLE group_members (
 //     id SERIAL PRIMARY KEY,
 //     user_id INTEGER NOT NULL,
 //     group_id INTEGER NOT NULL,
-//     is_admin BOOLEAN DEFAULT false,
+//     role VARCHAR(16) NOT NULL DEFAULT 'member',
 //     joined_at TIMESTAMP,
 //     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
 //     FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
 //     UNIQUE (group_id, user_id)  -- Enforce one entry per user per group
 // );
-// -- Index to support admin-count checks:
-// CREATE INDEX idx_group_admin ON group_members(group_id, is_admin);
-
-type GroupMessage struct {
-	ID uint `gorm:"primaryKey"`
-
-	GroupID uint  `gorm:"index"` // Frequently used in WHERE clauses
-	Group   Group `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+// -- Index to support role lookups and admin-count checks:
+// CREATE INDEX idx_group_members_role ON group_members(role);
 
-	SenderID uint `gorm:"index"` // Used to filter messages sent by user
-	Sender   User `gorm:"foreignKey:SenderID;constraint:OnDelete:CASCADE"`
-
-	Content   string    `gorm:"not null"`
-	CreatedAt time.Time `gorm:"index"` // Sorting messages
-	UpdatedAt time.Time
+// AfterCreate adds a new group member to the group's conversation.
+func (m *GroupMember) AfterCreate(tx *gorm.DB) error {
+	// SQL: INSERT INTO conversation_members (conversation_id, user_id, joined_at)
+	//      SELECT id, ?, ? FROM conversations WHERE group_id = ? ON CONFLICT DO NOTHING;
+	return tx.Exec(`
+		INSERT INTO conversation_members (conversation_id, user_id, joined_at)
+		SELECT id, ?, ? FROM conversations WHERE group_id = ?
+		ON CONFLICT DO NOTHING
+	`, m.UserID, m.JoinedAt, m.GroupID).Error
 }
 
-// CREATE TABLE group_messages (
-//     id SERIAL PRIMARY KEY,
-//     group_id INTEGER NOT NULL,
-//     sender_id INTEGER NOT NULL,
-//     content TEXT NOT NULL,
-//     created_at TIMESTAMP,
-//     updated_at TIMESTAMP,
-//     CONSTRAINT fk_group_msg_group FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
-//     CONSTRAINT fk_group_msg_sender FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE
-// );
-
-// CREATE INDEX idx_group_messages_group_id ON group_messages(group_id);
-// CREATE INDEX idx_group_messages_sender_id ON group_messages(sender_id);
-// CREATE INDEX idx_group_messages_created_at ON group_messages(created_at);
+// AfterDelete removes a deleted group member from the group's conversation.
+// Members must be deleted by value for this hook to know who left.
+func (m *GroupMember) AfterDelete(tx *gorm.DB) error {
+	// SQL: DELETE FROM conversation_members WHERE user_id = ? AND conversation_id = (SELECT id FROM conversations WHERE group_id = ?);
+	return tx.Exec(`
+		DELETE FROM conversation_members
+		WHERE user_id = ? AND conversation_id = (SELECT id FROM conversations WHERE group_id = ?)
+	`, m.UserID, m.GroupID).Error
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Group invitation statuses
+const (
+	InvitationPending   = "pending"
+	InvitationAccepted  = "accepted"
+	InvitationDeclined  = "declined"
+	InvitationCancelled = "cancelled"
+)
+
+// GroupInvitation asks a user to join a group. They only become a member once
+// they accept it.
+type GroupInvitation struct {
+	ID uint `gorm:"primaryKey"`
+
+	// At most one pending invitation per user and group
+	GroupID uint  `gorm:"not null;index;uniqueIndex:idx_group_invitation_pending,where:status = 'pending'"`
+	Group   Group `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+
+	UserID uint `gorm:"not null;index;uniqueIndex:idx_group_invitation_pending,where:status = 'pending'"` // The invitee
+	User   User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+
+	InvitedBy uint `gorm:"not null"`
+	Inviter   User `gorm:"foreignKey:InvitedBy;constraint:OnDelete:CASCADE"`
+
+	Role        string `gorm:"not null;default:member"` // Role given on acceptance
+	Status      string `gorm:"not null;default:pending"`
+	CreatedAt   time.Time
+	RespondedAt *time.Time
+}
+
+// CREATE TABLE group_invitations (
+//     id SERIAL PRIMARY KEY,
+//     group_id INTEGER NOT NULL,
+//     user_id INTEGER NOT NULL,
+//     invited_by INTEGER NOT NULL,
+//     role VARCHAR(16) NOT NULL DEFAULT 'member',
+//     status VARCHAR(16) NOT NULL DEFAULT 'pending',
+//     created_at TIMESTAMP,
+//     responded_at TIMESTAMP,
+//     FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+//     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+//     FOREIGN KEY (invited_by) REFERENCES users(id) ON DELETE CASCADE
+// );
+// CREATE UNIQUE INDEX idx_group_invitation_pending ON group_invitations(group_id, user_id) WHERE status = 'pending';
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// ImportJob tracks one run of the chat history importer over an export file.
+// Re-running the same file resumes the unfinished job with the same checksum.
+type ImportJob struct {
+	ID       uint   `gorm:"primaryKey"`
+	Source   string `gorm:"not null;index:idx_import_job_file"` // "slack" or "whatsapp"
+	Checksum string `gorm:"not null;index:idx_import_job_file"` // SHA-256 of the export file
+	FileName string
+
+	Status           string `gorm:"not null;index"` // "running", "completed" or "failed"
+	Error            string
+	MessagesImported int `gorm:"not null;default:0"`
+	MessagesSkipped  int `gorm:"not null;default:0"`
+
+	StartedBy  *uint
+	StartedAt  time.Time
+	FinishedAt *time.Time
+}
+
+// CREATE TABLE import_jobs (
+//     id SERIAL PRIMARY KEY,
+//     source VARCHAR(16) NOT NULL,
+//     checksum VARCHAR(64) NOT NULL,
+//     file_name TEXT,
+//     status VARCHAR(16) NOT NULL,
+//     error TEXT,
+//     messages_imported INTEGER DEFAULT 0 NOT NULL,
+//     messages_skipped INTEGER DEFAULT 0 NOT NULL,
+//     started_by INTEGER,
+//     started_at TIMESTAMP,
+//     finished_at TIMESTAMP
+// );
+
+// ImportRecord maps an entity of an external export (user, channel, message)
+// to the row it was imported as, so imports never create duplicates.
+type ImportRecord struct {
+	ID uint `gorm:"primaryKey"`
+
+	Source     string `gorm:"not null;uniqueIndex:idx_import_record"` // "slack" or "whatsapp"
+	EntityType string `gorm:"not null;uniqueIndex:idx_import_record"` // "user", "group" or "message"
+	ExternalID string `gorm:"not null;uniqueIndex:idx_import_record"`
+	EntityID   uint   `gorm:"not null"`
+
+	JobID     uint `gorm:"index"`
+	CreatedAt time.Time
+}
+
+// CREATE TABLE import_records (
+//     id SERIAL PRIMARY KEY,
+//     source VARCHAR(16) NOT NULL,
+//     entity_type VARCHAR(32) NOT NULL,
+//     external_id TEXT NOT NULL,
+//     entity_id INTEGER NOT NULL,
+//     job_id INTEGER,
+//     created_at TIMESTAMP,
+//     UNIQUE (source, entity_type, external_id)
+// );
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// GroupInvite is a shareable link that lets anyone holding its token join a
+// group with a preset role.
+type GroupInvite struct {
+	ID uint `gorm:"primaryKey"`
+
+	GroupID uint  `gorm:"not null;index"` // Listing a group's invites
+	Group   Group `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+
+	Token     string `gorm:"not null;uniqueIndex"` // Redeemed by token
+	CreatedBy uint   `gorm:"not null"`
+	Creator   User   `gorm:"foreignKey:CreatedBy;constraint:OnDelete:CASCADE"`
+
+	Role      string     `gorm:"not null;default:member"` // Role given to people who join through it
+	MaxUses   *int       // nil for unlimited
+	Uses      int        `gorm:"not null;default:0"`
+	ExpiresAt *time.Time // nil for never
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// CREATE TABLE group_invites (
+//     id SERIAL PRIMARY KEY,
+//     group_id INTEGER NOT NULL,
+//     token VARCHAR(64) NOT NULL UNIQUE,
+//     created_by INTEGER NOT NULL,
+//     role VARCHAR(16) NOT NULL DEFAULT 'member',
+//     max_uses INTEGER,
+//     uses INTEGER NOT NULL DEFAULT 0,
+//     expires_at TIMESTAMP,
+//     revoked_at TIMESTAMP,
+//     created_at TIMESTAMP,
+//     FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+//     FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+// );
+// CREATE INDEX idx_group_invites_group_id ON group_invites(group_id);
+
+// IsActive reports whether the invite can still be redeemed at time t.
+func (i GroupInvite) IsActive(t time.Time) bool {
+	if i.RevokedAt != nil {
+		return false
+	}
+	if i.ExpiresAt != nil && !t.Before(*i.ExpiresAt) {
+		return false
+	}
+	return i.MaxUses == nil || i.Uses < *i.MaxUses
+}
+
+// GroupInviteUse records who joined a group through which invite.
+type GroupInviteUse struct {
+	ID uint `gorm:"primaryKey"`
+
+	InviteID uint        `gorm:"not null;index"` // Audit of one invite
+	Invite   GroupInvite `gorm:"foreignKey:InviteID;constraint:OnDelete:CASCADE"`
+
+	UserID uint `gorm:"not null"`
+	User   User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+
+	JoinedAt time.Time
+}
+
+// CREATE TABLE group_invite_uses (
+//     id SERIAL PRIMARY KEY,
+//     invite_id INTEGER NOT NULL,
+//     user_id INTEGER NOT NULL,
+//     joined_at TIMESTAMP,
+//     FOREIGN KEY (invite_id) REFERENCES group_invites(id) ON DELETE CASCADE,
+//     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+// );
+// CREATE INDEX idx_group_invite_uses_invite_id ON group_invite_uses(invite_id);
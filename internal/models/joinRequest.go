@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Join request statuses
+const (
+	JoinRequestPending   = "pending"
+	JoinRequestApproved  = "approved"
+	JoinRequestRejected  = "rejected"
+	JoinRequestCancelled = "cancelled"
+)
+
+// GroupJoinRequest is a user's request to join a group that accepts them,
+// waiting for a member who may add members to review it.
+type GroupJoinRequest struct {
+	ID uint `gorm:"primaryKey"`
+
+	// At most one pending request per user and group
+	GroupID uint  `gorm:"not null;index;uniqueIndex:idx_join_request_pending,where:status = 'pending'"`
+	Group   Group `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+
+	UserID uint `gorm:"not null;index;uniqueIndex:idx_join_request_pending,where:status = 'pending'"`
+	User   User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+
+	Message    string
+	Status     string `gorm:"not null;default:pending"`
+	ReviewedBy *uint
+	ReviewedAt *time.Time
+	CreatedAt  time.Time
+}
+
+// CREATE TABLE group_join_requests (
+//     id SERIAL PRIMARY KEY,
+//     group_id INTEGER NOT NULL,
+//     user_id INTEGER NOT NULL,
+//     message TEXT,
+//     status VARCHAR(16) NOT NULL DEFAULT 'pending',
+//     reviewed_by INTEGER,
+//     reviewed_at TIMESTAMP,
+//     created_at TIMESTAMP,
+//     FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+//     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+// );
+// CREATE UNIQUE INDEX idx_join_request_pending ON group_join_requests(group_id, user_id) WHERE status = 'pending';
@@ -0,0 +1,92 @@
+package models
+
+import "time"
+
+// Membership history actions
+const (
+	MembershipAdded             = "added"
+	MembershipJoinedByInvite    = "joined_by_invite" // Actor is the user who created the invite
+	MembershipJoinApproved      = "join_request_approved"
+	MembershipInviteAccepted    = "invitation_accepted" // Actor is the user who sent the invitation
+	MembershipJoinedPublic      = "joined_public"       // Joined a public group from the directory
+	MembershipJoinedCommunity   = "joined_community"    // Added to a public channel along with the rest of its community
+	MembershipJoinedChannel     = "joined_channel"      // Joined a public channel of their community
+	MembershipRemoved           = "removed"
+	MembershipBanned            = "banned" // Removed if a member, and kept from rejoining
+	MembershipUnbanned          = "unbanned"
+	MembershipMuted             = "muted"
+	MembershipUnmuted           = "unmuted"
+	MembershipLeft              = "left"
+	MembershipPromoted          = "promoted"
+	MembershipDemoted           = "demoted"
+	MembershipRoleChanged       = "role_changed" // Any other role change, e.g. to moderator or read-only
+	MembershipBecameOwner       = "became_owner" // Ownership passed on because the owner left
+	MembershipTransferRequested = "ownership_transfer_requested"
+	MembershipTransferAccepted  = "ownership_transfer_accepted"
+	MembershipTransferDeclined  = "ownership_transfer_declined"
+	MembershipTransferCancelled = "ownership_transfer_cancelled"
+)
+
+// GroupMembershipEvent records one change to who is in a group and what
+// they may do there.
+type GroupMembershipEvent struct {
+	ID uint `gorm:"primaryKey"`
+
+	GroupID uint  `gorm:"not null;index:idx_membership_group_created,priority:1"` // History of a group
+	Group   Group `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+
+	UserID  uint   `gorm:"not null;index"` // Member the change applies to
+	ActorID uint   `gorm:"not null"`       // User who made the change
+	Action  string `gorm:"not null"`
+
+	CreatedAt time.Time `gorm:"index:idx_membership_group_created,priority:2"`
+}
+
+// CREATE TABLE group_membership_events (
+//     id SERIAL PRIMARY KEY,
+//     group_id INTEGER NOT NULL,
+//     user_id INTEGER NOT NULL,
+//     actor_id INTEGER NOT NULL,
+//     action VARCHAR(64) NOT NULL,
+//     created_at TIMESTAMP,
+//     FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+// );
+
+// CREATE INDEX idx_membership_group_created ON group_membership_events(group_id, created_at);
+
+// Ownership transfer statuses
+const (
+	TransferPending   = "pending"
+	TransferAccepted  = "accepted"
+	TransferDeclined  = "declined"
+	TransferCancelled = "cancelled"
+)
+
+// OwnershipTransfer is an offer from a group's owner to hand the group over
+// to another member. It only takes effect once the recipient accepts.
+type OwnershipTransfer struct {
+	ID uint `gorm:"primaryKey"`
+
+	GroupID uint  `gorm:"not null;index"`
+	Group   Group `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+
+	FromUserID uint `gorm:"not null"`
+	ToUserID   uint `gorm:"not null;index"`
+	ToUser     User `gorm:"foreignKey:ToUserID;constraint:OnDelete:CASCADE"`
+
+	Status      string `gorm:"not null;index"` // "pending", "accepted", "declined" or "cancelled"
+	CreatedAt   time.Time
+	RespondedAt *time.Time
+}
+
+// CREATE TABLE ownership_transfers (
+//     id SERIAL PRIMARY KEY,
+//     group_id INTEGER NOT NULL,
+//     from_user_id INTEGER NOT NULL,
+//     to_user_id INTEGER NOT NULL,
+//     status VARCHAR(16) NOT NULL,
+//     created_at TIMESTAMP,
+//     responded_at TIMESTAMP,
+//     FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+//     FOREIGN KEY (to_user_id) REFERENCES users(id) ON DELETE CASCADE
+// );
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// MessageEdit keeps the previous content of a message each time it is edited.
+type MessageEdit struct {
+	ID uint `gorm:"primaryKey"`
+
+	MessageID uint    `gorm:"not null;index"` // Edit history of a message
+	Message   Message `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE"`
+
+	EditorID uint `gorm:"not null"`
+	Editor   User `gorm:"foreignKey:EditorID;constraint:OnDelete:CASCADE"`
+
+	PreviousContent string    `gorm:"not null"`
+	EditedAt        time.Time `gorm:"index"`
+}
+
+// CREATE TABLE message_edits (
+//     id SERIAL PRIMARY KEY,
+//     message_id INTEGER NOT NULL,
+//     editor_id INTEGER NOT NULL,
+//     previous_content TEXT NOT NULL,
+//     edited_at TIMESTAMP,
+//     CONSTRAINT fk_message_edits_message FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+//     CONSTRAINT fk_message_edit_editor FOREIGN KEY (editor_id) REFERENCES users(id) ON DELETE CASCADE
+// );
+
+// CREATE INDEX idx_message_edits_message_id ON message_edits(message_id);
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// GroupMute keeps a member from posting in a group until MutedUntil. They
+// can still read the group. Expired mutes are ignored and replaced by the next
+// mute of the same member.
+type GroupMute struct {
+	ID uint `gorm:"primaryKey"`
+
+	GroupID uint  `gorm:"not null;uniqueIndex:idx_group_mute_user"`
+	Group   Group `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+
+	UserID uint `gorm:"not null;uniqueIndex:idx_group_mute_user"`
+	User   User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+
+	MutedBy    uint `gorm:"not null"`
+	Reason     string
+	MutedUntil time.Time `gorm:"not null"`
+	CreatedAt  time.Time
+}
+
+// CREATE TABLE group_mutes (
+//     id SERIAL PRIMARY KEY,
+//     group_id INTEGER NOT NULL,
+//     user_id INTEGER NOT NULL,
+//     muted_by INTEGER NOT NULL,
+//     reason TEXT,
+//     muted_until TIMESTAMP NOT NULL,
+//     created_at TIMESTAMP,
+//     FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+//     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+//     UNIQUE (group_id, user_id)
+// );
+
+// GroupBan keeps a user out of a group: they are removed if they were a
+// member and cannot be added again, by any route, until the ban is lifted.
+type GroupBan struct {
+	ID uint `gorm:"primaryKey"`
+
+	GroupID uint  `gorm:"not null;uniqueIndex:idx_group_ban_user"`
+	Group   Group `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+
+	UserID uint `gorm:"not null;uniqueIndex:idx_group_ban_user"`
+	User   User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+
+	BannedBy  uint `gorm:"not null"`
+	Reason    string
+	CreatedAt time.Time
+}
+
+// CREATE TABLE group_bans (
+//     id SERIAL PRIMARY KEY,
+//     group_id INTEGER NOT NULL,
+//     user_id INTEGER NOT NULL,
+//     banned_by INTEGER NOT NULL,
+//     reason TEXT,
+//     created_at TIMESTAMP,
+//     FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+//     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+//     UNIQUE (group_id, user_id)
+// );
@@ -0,0 +1,125 @@
+package models
+
+import "time"
+
+type Poll struct {
+	ID uint `gorm:"primaryKey"`
+
+	GroupID uint  `gorm:"not null;index"` // Listing polls of a group
+	Group   Group `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+
+	MessageID uint    `gorm:"not null;uniqueIndex"` // The group message that carries the poll
+	Message   Message `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE"`
+
+	CreatedBy uint `gorm:"not null;index"`
+	Creator   User `gorm:"foreignKey:CreatedBy;constraint:OnDelete:CASCADE"`
+
+	Question       string     `gorm:"not null"`
+	MultipleChoice bool       `gorm:"not null;default:false"`
+	Anonymous      bool       `gorm:"not null;default:false"`
+	ClosesAt       *time.Time `gorm:"index"` // Optional automatic close time
+	ClosedAt       *time.Time // Set when an admin closes the poll early
+	ClosedBy       *uint
+	CreatedAt      time.Time
+
+	Options []PollOption `gorm:"foreignKey:PollID"`
+}
+
+// IsClosed reports whether the poll no longer accepts votes at the given time.
+func (p Poll) IsClosed(now time.Time) bool {
+	if p.ClosedAt != nil {
+		return true
+	}
+	return p.ClosesAt != nil && !now.Before(*p.ClosesAt)
+}
+
+// CREATE TABLE polls (
+//     id SERIAL PRIMARY KEY,
+//     group_id INTEGER NOT NULL,
+//     message_id INTEGER NOT NULL UNIQUE,
+//     created_by INTEGER NOT NULL,
+//     question TEXT NOT NULL,
+//     multiple_choice BOOLEAN DEFAULT false NOT NULL,
+//     anonymous BOOLEAN DEFAULT false NOT NULL,
+//     closes_at TIMESTAMP,
+//     closed_at TIMESTAMP,
+//     closed_by INTEGER,
+//     created_at TIMESTAMP,
+//     CONSTRAINT fk_poll_group FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+//     CONSTRAINT fk_poll_message FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+//     CONSTRAINT fk_poll_creator FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+// );
+
+type PollOption struct {
+	ID uint `gorm:"primaryKey"`
+
+	PollID uint `gorm:"not null;index"`
+	Poll   Poll `gorm:"foreignKey:PollID;constraint:OnDelete:CASCADE"`
+
+	Position int    `gorm:"not null"` // Display order of the option
+	Text     string `gorm:"not null"`
+}
+
+// CREATE TABLE poll_options (
+//     id SERIAL PRIMARY KEY,
+//     poll_id INTEGER NOT NULL,
+//     position INTEGER NOT NULL,
+//     text TEXT NOT NULL,
+//     CONSTRAINT fk_poll_option_poll FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+// );
+
+type PollVote struct {
+	ID uint `gorm:"primaryKey"`
+
+	PollID uint `gorm:"not null;index;uniqueIndex:idx_poll_option_user"`
+	Poll   Poll `gorm:"foreignKey:PollID;constraint:OnDelete:CASCADE"`
+
+	OptionID uint       `gorm:"not null;uniqueIndex:idx_poll_option_user"`
+	Option   PollOption `gorm:"foreignKey:OptionID;constraint:OnDelete:CASCADE"`
+
+	UserID uint `gorm:"not null;index;uniqueIndex:idx_poll_option_user"`
+	User   User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+
+	CreatedAt time.Time
+}
+
+// CREATE TABLE poll_votes (
+//     id SERIAL PRIMARY KEY,
+//     poll_id INTEGER NOT NULL,
+//     option_id INTEGER NOT NULL,
+//     user_id INTEGER NOT NULL,
+//     created_at TIMESTAMP,
+//     FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE,
+//     FOREIGN KEY (option_id) REFERENCES poll_options(id) ON DELETE CASCADE,
+//     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+//     UNIQUE (poll_id, option_id, user_id)  -- One vote per option per user
+// );
+
+// PollVoteHistory keeps every vote and retraction so results can be replayed.
+type PollVoteHistory struct {
+	ID uint `gorm:"primaryKey"`
+
+	PollID uint `gorm:"not null;index"`
+	Poll   Poll `gorm:"foreignKey:PollID;constraint:OnDelete:CASCADE"`
+
+	OptionID uint       `gorm:"not null"`
+	Option   PollOption `gorm:"foreignKey:OptionID;constraint:OnDelete:CASCADE"`
+
+	UserID uint `gorm:"not null"`
+	User   User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+
+	Action    string    `gorm:"not null"` // "voted" or "retracted"
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// CREATE TABLE poll_vote_histories (
+//     id SERIAL PRIMARY KEY,
+//     poll_id INTEGER NOT NULL,
+//     option_id INTEGER NOT NULL,
+//     user_id INTEGER NOT NULL,
+//     action VARCHAR(16) NOT NULL,
+//     created_at TIMESTAMP,
+//     FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE,
+//     FOREIGN KEY (option_id) REFERENCES poll_options(id) ON DELETE CASCADE,
+//     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+// );
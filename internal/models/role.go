@@ -0,0 +1,98 @@
+package models
+
+// Group member roles, from most to least privileged
+const (
+	RoleOwner     = "owner"
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleMember    = "member"
+	RoleReadOnly  = "read_only"
+)
+
+// Roles lists every role, most privileged first.
+var Roles = []string{RoleOwner, RoleAdmin, RoleModerator, RoleMember, RoleReadOnly}
+
+// Group capabilities
+const (
+	CapPost           = "post"
+	CapAddMembers     = "add_members"
+	CapRemoveMembers  = "remove_members"
+	CapPin            = "pin"
+	CapEditSettings   = "edit_settings"
+	CapDeleteMessages = "delete_messages" // Delete or moderate other members' messages
+	CapVote           = "vote"            // Vote in polls
+
+	// Not configurable per group
+	CapManageRoles       = "manage_roles"
+	CapTransferOwnership = "transfer_ownership"
+	CapViewAuditLog      = "view_audit_log"
+	CapDeleteGroup       = "delete_group" // Delete and restore the group
+)
+
+// Capabilities lists the capabilities a group can override per role.
+var Capabilities = []string{CapPost, CapAddMembers, CapRemoveMembers, CapPin, CapEditSettings, CapDeleteMessages, CapVote}
+
+// DefaultCapabilities is what each role may do in a group without overrides.
+var DefaultCapabilities = map[string]map[string]bool{
+	RoleOwner: {
+		CapPost: true, CapAddMembers: true, CapRemoveMembers: true, CapPin: true, CapEditSettings: true,
+		CapDeleteMessages: true, CapVote: true, CapManageRoles: true, CapTransferOwnership: true, CapViewAuditLog: true,
+		CapDeleteGroup: true,
+	},
+	RoleAdmin: {
+		CapPost: true, CapAddMembers: true, CapRemoveMembers: true, CapPin: true, CapEditSettings: true,
+		CapDeleteMessages: true, CapVote: true, CapManageRoles: true, CapViewAuditLog: true,
+	},
+	RoleModerator: {CapPost: true, CapPin: true, CapDeleteMessages: true, CapVote: true},
+	RoleMember:    {CapPost: true, CapVote: true},
+	RoleReadOnly:  {},
+}
+
+// RoleRank orders roles so that a higher rank outranks a lower one.
+func RoleRank(role string) int {
+	for i, r := range Roles {
+		if r == role {
+			return len(Roles) - i
+		}
+	}
+	return 0
+}
+
+// IsValidRole reports whether role is a known role.
+func IsValidRole(role string) bool {
+	return RoleRank(role) > 0
+}
+
+// IsConfigurableCapability reports whether a group may override capability.
+func IsConfigurableCapability(capability string) bool {
+	for _, c := range Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupPermissionOverride grants or revokes one capability for one role in
+// one group, replacing the default. The owner's capabilities cannot be
+// overridden.
+type GroupPermissionOverride struct {
+	ID uint `gorm:"primaryKey"`
+
+	GroupID uint  `gorm:"not null;uniqueIndex:idx_group_role_capability"`
+	Group   Group `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+
+	Role       string `gorm:"not null;uniqueIndex:idx_group_role_capability"`
+	Capability string `gorm:"not null;uniqueIndex:idx_group_role_capability"`
+	Allowed    bool   `gorm:"not null"`
+}
+
+// CREATE TABLE group_permission_overrides (
+//     id SERIAL PRIMARY KEY,
+//     group_id INTEGER NOT NULL,
+//     role VARCHAR(16) NOT NULL,
+//     capability VARCHAR(32) NOT NULL,
+//     allowed BOOLEAN NOT NULL,
+//     FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+//     UNIQUE (group_id, role, capability)
+// );
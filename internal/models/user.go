@@ -2,16 +2,64 @@ package models
 
 import "time"
 
+// Who may add a user to a group directly; anyone else has to send an invitation
+const (
+	GroupAddAnyone     = "anyone"
+	GroupAddContacts   = "contacts"   // Users they have messaged one-to-one
+	GroupAddInvitation = "invitation" // Nobody; every group needs their consent
+)
+
+// GroupAddPolicies lists the valid group add preferences.
+var GroupAddPolicies = []string{GroupAddAnyone, GroupAddContacts, GroupAddInvitation}
+
+// Who may send a user messages through a broadcast list
+const (
+	BroadcastAnyone   = "anyone"
+	BroadcastContacts = "contacts" // Users they have messaged one-to-one
+	BroadcastNobody   = "nobody"
+)
+
+// BroadcastPolicies lists the valid broadcast preferences.
+var BroadcastPolicies = []string{BroadcastAnyone, BroadcastContacts, BroadcastNobody}
+
 type User struct {
 	Id        uint   `gorm:"primaryKey"`
 	Username  string `gorm:"uniqueIndex;not null"` // Index for lookup
 	Password  string `gorm:"not null"`
 	CreatedAt time.Time
+
+	Placeholder bool `gorm:"not null;default:false"` // Created by the importer for a person without an account
+
+	GroupAddPolicy  string `gorm:"not null;default:anyone"` // Who may add them to groups without an invitation
+	BroadcastPolicy string `gorm:"not null;default:anyone"` // Who may reach them through broadcast lists
 }
 
 // CREATE TABLE users (
 //     id SERIAL PRIMARY KEY,
 //     username VARCHAR(255) NOT NULL UNIQUE,
 //     password VARCHAR(255) NOT NULL,
-//     created_at TIMESTAMP
+//     created_at TIMESTAMP,
+//     placeholder BOOLEAN DEFAULT false NOT NULL,
+//     group_add_policy VARCHAR(16) DEFAULT 'anyone' NOT NULL,
+//     broadcast_policy VARCHAR(16) DEFAULT 'anyone' NOT NULL
 // );
+
+// IsValidGroupAddPolicy reports whether p is one of GroupAddPolicies.
+func IsValidGroupAddPolicy(p string) bool {
+	for _, policy := range GroupAddPolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidBroadcastPolicy reports whether p is one of BroadcastPolicies.
+func IsValidBroadcastPolicy(p string) bool {
+	for _, policy := range BroadcastPolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package realtime
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Event is a single notification pushed to the subscribers of a topic.
+type Event struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+var (
+	mu     sync.RWMutex
+	topics = map[string]map[chan Event]uint{} // Each subscriber's channel and user
+)
+
+// GroupTopic returns the topic name used for events of a group.
+func GroupTopic(groupID uint) string {
+	return fmt.Sprintf("group:%d", groupID)
+}
+
+// UserTopic returns the topic name used for events addressed to one user,
+// wherever they happen.
+func UserTopic(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// Subscribe registers a listener for a user on a topic. The returned function
+// must be called once the listener goes away so the channel can be released.
+// The channel is closed early if the user is kicked from the topic.
+func Subscribe(topic string, userID uint) (<-chan Event, func()) {
+	ch := make(chan Event, 16)
+
+	mu.Lock()
+	if topics[topic] == nil {
+		topics[topic] = map[chan Event]uint{}
+	}
+	topics[topic][ch] = userID
+	mu.Unlock()
+
+	return ch, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := topics[topic][ch]; ok {
+			unsubscribe(topic, ch)
+		}
+	}
+}
+
+// Kick closes every subscription of a user to a topic, for example once they
+// leave a group. Events already published to them are still delivered first.
+func Kick(topic string, userID uint) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for ch, subscriber := range topics[topic] {
+		if subscriber == userID {
+			unsubscribe(topic, ch)
+		}
+	}
+}
+
+// unsubscribe removes and closes a channel. mu must be held.
+func unsubscribe(topic string, ch chan Event) {
+	delete(topics[topic], ch)
+	if len(topics[topic]) == 0 {
+		delete(topics, topic)
+	}
+	close(ch)
+}
+
+// Publish delivers an event to every subscriber of a topic.
+// Subscribers whose buffer is full miss the event instead of blocking the sender.
+func Publish(topic string, event Event) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for ch := range topics[topic] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
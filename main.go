@@ -3,6 +3,7 @@ package main
 import (
 	"MessagingSystemBackend/internal/controllers"
 	"MessagingSystemBackend/internal/initializers"
+	"MessagingSystemBackend/internal/jobs"
 	"MessagingSystemBackend/internal/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -10,12 +11,15 @@ import (
 
 // Initialize environment variables, database connection, and perform DB migrations
 func init() {
-	initializers.LoadEnv()      // Load environment variables from .env file
-	initializers.ConnectToDb()  // Connect to the database using GORM
-	initializers.SyncDatabase() // Auto-migrate all models to the database
+	initializers.LoadEnv()         // Load environment variables from .env file
+	initializers.LoadGroupLimits() // Read default and maximum group limits
+	initializers.ConnectToDb()     // Connect to the database using GORM
+	initializers.SyncDatabase()    // Auto-migrate all models to the database
 }
 
 func main() {
+	jobs.StartGroupPurge() // Erase deleted groups once their grace period ends
+
 	r := gin.Default() // Initialize a Gin router with default middleware (logger and recovery)
 
 	// Authentication routes
@@ -24,21 +28,151 @@ func main() {
 	r.GET("/validate", middleware.RequireAuth, controllers.Validate) // Authenticated route to verify user session
 	r.GET("/logout", middleware.RequireAuth, controllers.Logout)     // Logout user by clearing auth token
 
+	// User preference routes
+	r.GET("/preferences", middleware.RequireAuth, controllers.GetPreferences)    // Your preferences
+	r.PUT("/preferences", middleware.RequireAuth, controllers.UpdatePreferences) // Who may add you to groups without an invitation
+
 	// Direct message (DM) routes
 	dmRoutes := r.Group("/dm")
 	dmRoutes.Use(middleware.RequireAuth)                // Require authentication for all DM routes
 	dmRoutes.POST(":id", controllers.SendDirectMessage) // Send a direct message to a user by ID
 	dmRoutes.GET(":id", controllers.GetDirectMessage)   // Get direct messages with a specific user
 
+	// Conversation routes (direct and group chats alike)
+	conversationRoutes := r.Group("/conversations")
+	conversationRoutes.Use(middleware.RequireAuth)                                 // Require authentication for all conversation routes
+	conversationRoutes.GET("", controllers.ListConversations)                      // List the user's conversations with their last message
+	conversationRoutes.POST("/direct", controllers.StartDirectConversation)        // Start (or reuse) an unnamed conversation with several users
+	conversationRoutes.POST("/direct/:userId", controllers.OpenDirectConversation) // Open (or start) the direct conversation with a user
+	conversationRoutes.GET("/:id/messages", controllers.GetConversationMessages)   // Message history, paginated with before
+	conversationRoutes.POST("/:id/messages", controllers.SendConversationMessage)  // Send a message to a conversation
+	conversationRoutes.GET("/:id/export", controllers.ExportConversation)          // Export the full history of a conversation
+	conversationRoutes.PUT("/messages/:id", controllers.EditMessage)               // Edit a message by ID
+	conversationRoutes.DELETE("/messages/:id", controllers.DeleteMessage)          // Delete a message (own, or others' with delete_messages)
+
+	// Broadcast list routes (one-to-many direct messages)
+	broadcastRoutes := r.Group("/broadcasts")
+	broadcastRoutes.Use(middleware.RequireAuth)                                       // Require authentication for all broadcast routes
+	broadcastRoutes.POST("", controllers.CreateBroadcastList)                         // Create a broadcast list
+	broadcastRoutes.GET("", controllers.ListBroadcastLists)                           // List the user's broadcast lists
+	broadcastRoutes.GET("/:id", controllers.GetBroadcastList)                         // Get a broadcast list with its members
+	broadcastRoutes.DELETE("/:id", controllers.DeleteBroadcastList)                   // Delete a broadcast list
+	broadcastRoutes.POST("/:id/members", controllers.AddBroadcastMembers)             // Add users to a broadcast list
+	broadcastRoutes.DELETE("/:id/members/:userId", controllers.RemoveBroadcastMember) // Remove a user from a broadcast list
+	broadcastRoutes.POST("/:id/send", controllers.SendBroadcast)                      // Send a message privately to every member
+
 	// Group-related routes
 	groupRoutes := r.Group("/groups")
-	groupRoutes.Use(middleware.RequireAuth)                             // Require authentication for all group routes
-	groupRoutes.POST("/create", controllers.CreateGroup)                // Create a new group
-	groupRoutes.POST("/:id/message", controllers.SendGroupMessage)      // Send a message to a group
-	groupRoutes.POST("/:id/add-member", controllers.AddGroupMember)     // Add a new member to a group
-	groupRoutes.POST("/:id/add-admin", controllers.AddAdmin)            // Promote a member to group admin
-	groupRoutes.GET("/:id/summary", controllers.SummarizeGroupMessages) // Summarize group chat using NLP
-	groupRoutes.GET("/:id", controllers.GetGroupMessage)                // Retrieve a message from a group
+	groupRoutes.Use(middleware.RequireAuth)                                   // Require authentication for all group routes
+	groupRoutes.POST("/create", controllers.CreateGroup)                      // Create a new group
+	groupRoutes.POST("/:id/message", controllers.SendGroupMessage)            // Send a message to a group
+	groupRoutes.POST("/:id/add-member", controllers.AddGroupMember)           // Add a new member to a group
+	groupRoutes.POST("/:id/add-admin", controllers.AddAdmin)                  // Promote a member to group admin
+	groupRoutes.DELETE("/:id/members/:userId", controllers.RemoveGroupMember) // Remove a member from a group (admins)
+	groupRoutes.POST("/:id/members/batch", controllers.BatchUpdateMembers)    // Add, remove or change the role of many members (JSON or CSV)
+	groupRoutes.POST("/:id/leave", controllers.LeaveGroup)                    // Leave a group
+	groupRoutes.POST("/:id/demote", controllers.DemoteAdmin)                  // Demote an admin to member (not the owner)
+	groupRoutes.GET("/:id/history", controllers.GetMembershipHistory)         // Membership history of a group
+	groupRoutes.GET("/:id/audit-log", controllers.GetAuditLog)                // Administrative actions in a group (admins)
+
+	// Group profile routes
+	groupRoutes.GET("/:id/info", controllers.GetGroupInfo)           // Profile of a group with its members and their roles
+	groupRoutes.PUT("/:id/profile", controllers.UpdateGroupProfile)  // Rename a group or change its description, topic, visibility or color
+	groupRoutes.POST("/:id/avatar", controllers.UploadGroupAvatar)   // Upload a group picture
+	groupRoutes.DELETE("/:id/avatar", controllers.DeleteGroupAvatar) // Remove the group picture
+	groupRoutes.GET("/:id/avatar", controllers.GetGroupAvatar)       // Serve the group picture
+
+	// Public group directory routes
+	groupRoutes.GET("/directory", controllers.ListGroupDirectory) // Search public groups, by member count or activity
+	groupRoutes.POST("/:id/join", controllers.JoinPublicGroup)    // Join a public group
+
+	// Group moderation routes
+	groupRoutes.GET("/:id/mutes", controllers.ListMutes)               // Members muted right now, with reasons
+	groupRoutes.POST("/:id/mutes/:userId", controllers.MuteMember)     // Keep a member from posting for a while
+	groupRoutes.DELETE("/:id/mutes/:userId", controllers.UnmuteMember) // Lift a mute early
+	groupRoutes.GET("/:id/bans", controllers.ListBans)                 // Banned users, with reasons
+	groupRoutes.POST("/:id/bans/:userId", controllers.BanMember)       // Remove a user and keep them from rejoining
+	groupRoutes.DELETE("/:id/bans/:userId", controllers.UnbanMember)   // Lift a ban
+
+	// Group archiving and deletion routes
+	groupRoutes.POST("/:id/archive", controllers.ArchiveGroup)     // Make a group read-only
+	groupRoutes.POST("/:id/unarchive", controllers.UnarchiveGroup) // Make an archived group writable again
+	groupRoutes.DELETE("/:id", controllers.DeleteGroup)            // Owner deletes a group (restorable during the grace period)
+	groupRoutes.POST("/:id/restore", controllers.RestoreGroup)     // Owner restores a deleted group
+
+	// Group role, permission and settings routes
+	groupRoutes.PUT("/:id/members/:userId/role", controllers.SetMemberRole)      // Change a member's role
+	groupRoutes.GET("/:id/settings", controllers.GetGroupSettings)               // Member, admin and edit-window limits of a group
+	groupRoutes.PUT("/:id/settings", controllers.UpdateGroupSettings)            // Override (or reset) a group's limits
+	groupRoutes.GET("/:id/permissions", controllers.GetGroupPermissions)         // What each role may do in a group
+	groupRoutes.PUT("/:id/permissions", controllers.SetGroupPermission)          // Override (or reset) a capability for a role
+	groupRoutes.POST("/:id/messages/:messageId/pin", controllers.PinMessage)     // Pin a group message
+	groupRoutes.DELETE("/:id/messages/:messageId/pin", controllers.UnpinMessage) // Unpin a group message
+	groupRoutes.GET("/:id/pins", controllers.ListPinnedMessages)                 // Pinned messages of a group
+
+	// Group invite link routes
+	groupRoutes.POST("/:id/invites", controllers.CreateGroupInvite)                // Create an invite link (expiry, max uses, role)
+	groupRoutes.GET("/:id/invites", controllers.ListGroupInvites)                  // List a group's active invite links
+	groupRoutes.DELETE("/:id/invites/:inviteId", controllers.RevokeGroupInvite)    // Revoke an invite link
+	groupRoutes.GET("/:id/invites/:inviteId/uses", controllers.GetGroupInviteUses) // Who joined through an invite link
+
+	// Invite redemption routes
+	inviteRoutes := r.Group("/invites")
+	inviteRoutes.Use(middleware.RequireAuth)                         // Require authentication to redeem invites
+	inviteRoutes.POST("/:token/join", controllers.JoinGroupByInvite) // Join a group through an invite link
+
+	// Group join request routes
+	groupRoutes.POST("/:id/join-requests", controllers.RequestToJoinGroup)                    // Ask to join a group
+	groupRoutes.DELETE("/:id/join-requests", controllers.CancelJoinRequest)                   // Withdraw your pending request
+	groupRoutes.GET("/:id/join-requests", controllers.ListJoinRequests)                       // Pending requests of a group
+	groupRoutes.POST("/:id/join-requests/:requestId/approve", controllers.ApproveJoinRequest) // Approve a request and add the requester
+	groupRoutes.POST("/:id/join-requests/:requestId/reject", controllers.RejectJoinRequest)   // Reject a request
+
+	// Group invitation routes
+	groupRoutes.POST("/:id/invitations", controllers.InviteToGroup)                         // Invite a user, who joins only on accepting
+	groupRoutes.GET("/:id/invitations", controllers.ListGroupInvitations)                   // Pending invitations of a group
+	groupRoutes.DELETE("/:id/invitations/:invitationId", controllers.CancelGroupInvitation) // Withdraw an invitation
+
+	// Invitations received by the current user
+	invitationRoutes := r.Group("/invitations")
+	invitationRoutes.Use(middleware.RequireAuth)                         // Require authentication for all invitation routes
+	invitationRoutes.GET("", controllers.ListMyInvitations)              // Your pending group invitations
+	invitationRoutes.POST("/:id/accept", controllers.AcceptInvitation)   // Accept and join the group
+	invitationRoutes.POST("/:id/decline", controllers.DeclineInvitation) // Decline an invitation
+
+	// Join requests and live events of the current user
+	r.GET("/join-requests", middleware.RequireAuth, controllers.ListMyJoinRequests) // Your join requests and their outcome
+	r.GET("/events", middleware.RequireAuth, controllers.StreamUserEvents)          // Stream your own live events (SSE)
+
+	// Group ownership transfer routes
+	groupRoutes.POST("/:id/ownership-transfer", controllers.RequestOwnershipTransfer)         // Owner offers the group to a member
+	groupRoutes.DELETE("/:id/ownership-transfer", controllers.CancelOwnershipTransfer)        // Owner withdraws the offer
+	groupRoutes.POST("/:id/ownership-transfer/accept", controllers.AcceptOwnershipTransfer)   // Recipient accepts and becomes owner
+	groupRoutes.POST("/:id/ownership-transfer/decline", controllers.DeclineOwnershipTransfer) // Recipient declines the offer
+	groupRoutes.GET("/:id/summary", controllers.SummarizeGroupMessages)                       // Summarize group chat using NLP
+	groupRoutes.GET("/:id", controllers.GetGroupMessage)                                      // Retrieve a message from a group
+	groupRoutes.GET("/:id/events", controllers.StreamGroupEvents)                             // Stream live group events (SSE)
+
+	// Group poll routes
+	groupRoutes.POST("/:id/polls", controllers.CreatePoll)              // Create a poll in a group
+	groupRoutes.GET("/:id/polls", controllers.ListPolls)                // List polls of a group
+	groupRoutes.GET("/:id/polls/:pollId", controllers.GetPoll)          // Poll results with vote history
+	groupRoutes.POST("/:id/polls/:pollId/vote", controllers.VotePoll)   // Vote or change vote in a poll
+	groupRoutes.POST("/:id/polls/:pollId/close", controllers.ClosePoll) // Close a poll early
+
+	// Community routes; channels are groups inside a community and use the group routes otherwise
+	communityRoutes := r.Group("/communities")
+	communityRoutes.Use(middleware.RequireAuth)                                       // Require authentication for all community routes
+	communityRoutes.POST("/create", controllers.CreateCommunity)                      // Create a community you own
+	communityRoutes.GET("", controllers.ListCommunities)                              // Communities you belong to
+	communityRoutes.GET("/:id", controllers.GetCommunity)                             // A community and the channels you can see
+	communityRoutes.GET("/:id/members", controllers.ListCommunityMembers)             // Members of a community with their roles
+	communityRoutes.POST("/:id/members", controllers.AddCommunityMember)              // Add a member, who joins every public channel
+	communityRoutes.PUT("/:id/members/:userId/role", controllers.SetCommunityRole)    // Owner makes a member admin or member
+	communityRoutes.DELETE("/:id/members/:userId", controllers.RemoveCommunityMember) // Remove a member from the community and its channels
+	communityRoutes.POST("/:id/leave", controllers.LeaveCommunity)                    // Leave a community and its channels
+	communityRoutes.POST("/:id/channels", controllers.CreateChannel)                  // Create a public or private channel
+	communityRoutes.POST("/:id/channels/:channelId/join", controllers.JoinChannel)    // Join a public channel of your community
 
 	// Routes for viewing message previews and chat history
 	viewRoutes := r.Group("/view")
@@ -47,6 +181,18 @@ func main() {
 	viewRoutes.GET("/groups", controllers.ViewGroupPreviews)       // View group conversation previews
 	viewRoutes.GET("/chat/:type/:id", controllers.ViewChatHistory) // View full chat history (DM/group)
 
+	// Export full chat history (DM/group) as JSON Lines, HTML or plain text
+	r.GET("/export/:type/:id", middleware.RequireAuth, controllers.ExportChat)
+
+	// Full-text search across the user's DMs and groups
+	r.GET("/search", middleware.RequireAuth, controllers.SearchMessages)
+
+	// Platform admin routes
+	adminRoutes := r.Group("/admin")
+	adminRoutes.Use(middleware.RequireAuth, middleware.RequireAdmin) // Require a platform admin (ADMIN_USERNAMES)
+	adminRoutes.POST("/import", controllers.ImportChatHistory)       // Import a Slack or WhatsApp export
+	adminRoutes.GET("/imports", controllers.ListImportJobs)          // List recent import runs
+
 	// Edit message routes
 	groupRoutes.PUT("/message/:id", controllers.EditGroupMessage) // Edit a group message by ID
 	dmRoutes.PUT("/message/:id", controllers.EditDirectMessage)   // Edit a direct message by ID